@@ -0,0 +1,65 @@
+package ctxwire
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Accumulator collects the headers of the responses of concurrent downstream
+// calls, such as the ones of an errgroup, and merges their values into a
+// single context once they are done. It is safe for concurrent use.
+type Accumulator struct {
+	ctx        context.Context
+	aggregator *Aggregator
+	mu         sync.Mutex
+	headers    []http.Header
+}
+
+type accumulatorKey struct{}
+
+// NewAccumulator returns a new Accumulator merging the values of the default
+// registry into the given context.
+func NewAccumulator(ctx context.Context, opts ...AggregatorOption) *Accumulator {
+	return defaultRegistry.NewAccumulator(ctx, opts...)
+}
+
+// NewAccumulator returns a new Accumulator merging the values of the registry
+// into the given context. The options configure the merge as for an
+// Aggregator.
+func (r *Registry) NewAccumulator(ctx context.Context, opts ...AggregatorOption) *Accumulator {
+	a := &Accumulator{aggregator: r.NewAggregator(opts...)}
+	a.ctx = context.WithValue(ctx, accumulatorKey{}, a)
+	return a
+}
+
+// Context returns the context of the accumulator. The Transport adds the
+// headers of the responses of the requests sent with this context, or a
+// context derived from it, to the accumulator.
+func (a *Accumulator) Context() context.Context { return a.ctx }
+
+// Add adds a copy of the given response headers to the accumulator.
+func (a *Accumulator) Add(h http.Header) {
+	h = h.Clone()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.headers = append(a.headers, h)
+}
+
+// Merge returns a copy of the context of the accumulator holding the values
+// of the response headers added so far, merged as described by
+// Aggregator.Aggregate in the order they were added. The headers of
+// concurrent calls are added in the order the calls complete, so the conflict
+// policies and mergers depending on the order of the responses don't give a
+// deterministic result: aggregate the responses with an Aggregator instead.
+func (a *Accumulator) Merge() (context.Context, error) {
+	a.mu.Lock()
+	headers := append([]http.Header(nil), a.headers...)
+	a.mu.Unlock()
+	return a.aggregator.Aggregate(a.ctx, headers...)
+}
+
+func accumulatorFrom(ctx context.Context) *Accumulator {
+	a, _ := ctx.Value(accumulatorKey{}).(*Accumulator)
+	return a
+}
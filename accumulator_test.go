@@ -0,0 +1,101 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+var sumInts = ctxwire.MergerFunc(func(existing, incoming any) (any, error) {
+	return existing.(int) + incoming.(int), nil
+})
+
+func TestAccumulator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("total", keyInt, ctxwire.WithMerger(sumInts))))
+
+	acc := r.NewAccumulator(context.WithValue(context.Background(), keyStr, "parent"))
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			acc.Add(http.Header{"X-Ctxwire-Total": {strconv.Itoa(i)}})
+		}()
+	}
+	wg.Wait()
+	ctx, err := acc.Merge()
+	require.NoError(t, err)
+	require.Equal(t, 55, ctx.Value(keyInt))
+	require.Equal(t, "parent", ctx.Value(keyStr))
+
+	// Later additions are merged by the next calls.
+	acc.Add(http.Header{"X-Ctxwire-Total": {"45"}})
+	ctx, err = acc.Merge()
+	require.NoError(t, err)
+	require.Equal(t, 100, ctx.Value(keyInt))
+}
+
+func TestAccumulatorTransport(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("total", keyInt, ctxwire.WithMerger(sumInts))))
+	srv := httptest.NewServer(r.Handler(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		n, _ := strconv.Atoi(req.URL.Query().Get("n"))
+		ctxwire.Put(req.Context(), keyInt, n)
+	})))
+	defer srv.Close()
+
+	client := ctxwire.NewClient(nil, ctxwire.WithTransportRegistry(r))
+	acc := r.NewAccumulator(context.Background())
+	var wg sync.WaitGroup
+	for i := 1; i <= 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(acc.Context(), http.MethodGet, srv.URL+"?n="+strconv.Itoa(i), nil)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	ctx, err := acc.Merge()
+	require.NoError(t, err)
+	require.Equal(t, 15, ctx.Value(keyInt))
+}
+
+func TestDefaultAccumulator(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewStringPropagator("str", keyStr))
+	acc := ctxwire.NewAccumulator(context.Background(), ctxwire.WithConflictPolicy(ctxwire.FirstResponseWins))
+	acc.Add(http.Header{"X-Ctxwire-Str": {"a"}})
+	acc.Add(http.Header{"X-Ctxwire-Str": {"b"}})
+	ctx, err := acc.Merge()
+	require.NoError(t, err)
+	require.Equal(t, "a", ctx.Value(keyStr))
+}
+
+func TestAccumulatorCopiesHeaders(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("str", keyStr)))
+	acc := r.NewAccumulator(context.Background())
+	h := http.Header{"X-Ctxwire-Str": {"a"}}
+	acc.Add(h)
+	h.Set("X-Ctxwire-Str", "b")
+	ctx, err := acc.Merge()
+	require.NoError(t, err)
+	require.Equal(t, "a", ctx.Value(keyStr))
+}
@@ -0,0 +1,182 @@
+package ctxwire
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// ConflictPolicy defines which value an Aggregator keeps when several
+// responses carry a value for a propagator without merger.
+type ConflictPolicy int
+
+const (
+	// LastResponseWins keeps the value of the last response carrying one.
+	LastResponseWins ConflictPolicy = iota
+	// FirstResponseWins keeps the value of the first response carrying one.
+	FirstResponseWins
+)
+
+// AggregatorOption configures an Aggregator.
+type AggregatorOption func(a *Aggregator)
+
+// WithAggregateMerger sets the merger used by the aggregator to merge the
+// values of the propagator with the given name, taking precedence over the
+// merger of the propagator.
+func WithAggregateMerger(name string, merger Merger) AggregatorOption {
+	return func(a *Aggregator) { a.mergers[name] = merger }
+}
+
+// WithConflictPolicy sets the policy applied to the values of the
+// propagators without merger. Defaults to LastResponseWins.
+func WithConflictPolicy(policy ConflictPolicy) AggregatorOption {
+	return func(a *Aggregator) { a.policy = policy }
+}
+
+// Aggregator merges the context values back-propagated by several responses,
+// such as the responses of the backends called by an API gateway for a single
+// request.
+type Aggregator struct {
+	registry *Registry
+	mergers  map[string]Merger
+	policy   ConflictPolicy
+}
+
+// NewAggregator returns a new Aggregator using the propagators of the default
+// registry.
+func NewAggregator(opts ...AggregatorOption) *Aggregator {
+	return defaultRegistry.NewAggregator(opts...)
+}
+
+// NewAggregator returns a new Aggregator using the propagators of the
+// registry.
+func (r *Registry) NewAggregator(opts ...AggregatorOption) *Aggregator {
+	a := &Aggregator{registry: r, mergers: map[string]Merger{}}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Aggregate extracts the context values from the given response headers into
+// a copy of the given context, skipping the RequestOnly propagators, and
+// returns it. The context can then be injected into the response of the
+// gateway.
+// The responses are resolved in the given order, whatever the order in which
+// they were received, so that the result is deterministic. The values of a
+// propagator are merged into the value of the context with the merger set
+// with WithAggregateMerger, or else the merger of the propagator. Without
+// merger, the value is chosen according to the ConflictPolicy of the
+// aggregator.
+// Propagators other than ValuePropagator don't expose their values, which are
+// extracted from the responses in order, the last one winning, or in reverse
+// order with FirstResponseWins.
+// Each response is extracted once. Errors are handled according to the
+// ErrorPolicy of the registry: under CollectErrors, the values of the failing
+// propagators are left out and the context is returned along with the errors.
+func (a *Aggregator) Aggregate(ctx context.Context, responses ...http.Header) (context.Context, error) {
+	var propagators []*ValuePropagator
+	for _, p := range a.registry.load() {
+		if vp, ok := p.(*ValuePropagator); ok && propagates(p, ResponseOnly) {
+			propagators = append(propagators, vp)
+		}
+	}
+
+	order := make([]int, len(responses))
+	for i := range order {
+		order[i] = i
+	}
+	if a.policy == FirstResponseWins {
+		slices.Reverse(order)
+	}
+	// Extract each response on top of the previous ones, so that the values of
+	// the other propagators chain, with the values of the ValuePropagators
+	// reset, so that each response yields its own values, which are merged
+	// once resolved.
+	var errs []error
+	extracted := make([]context.Context, len(responses))
+	result := ctx
+	for _, i := range order {
+		base := result
+		for _, p := range propagators {
+			base = context.WithValue(base, p.contextKey, nil)
+		}
+		respCtx, err := a.registry.ExtractResponse(base, responses[i])
+		if err != nil {
+			if a.registry.errorPolicy == FailFast {
+				return nil, err
+			}
+			errs = append(errs, err)
+		}
+		extracted[i], result = respCtx, respCtx
+	}
+	for _, p := range propagators {
+		v, err := a.resolve(p, ctx.Value(p.contextKey), extracted, responses)
+		if err != nil {
+			if a.registry.errorPolicy == FailFast {
+				return nil, err
+			}
+			errs = append(errs, withPropagatorName(p, err))
+			v = ctx.Value(p.contextKey)
+		}
+		result = context.WithValue(result, p.contextKey, v)
+	}
+	return result, a.registry.joinErrors(errs)
+}
+
+// resolve returns the value of the propagator resulting from merging the
+// values extracted from the responses into the existing value. Responses
+// carrying a tombstone clear the value; the value is cleared with Clear if no
+// later response sets it back.
+func (a *Aggregator) resolve(p *ValuePropagator, existing any, extracted []context.Context, responses []http.Header) (any, error) {
+	_, cleared := existing.(tombstone)
+	if cleared {
+		existing = nil
+	}
+	if p.noOverwrite && existing != nil {
+		return existing, nil
+	}
+	merger := a.mergers[p.name]
+	if merger == nil {
+		merger = p.merger
+	}
+	if merger == nil {
+		merger, _ = p.decoder.(Merger)
+	}
+	v, fromResponse := existing, false
+	for i, respCtx := range extracted {
+		incoming := respCtx.Value(p.contextKey)
+		if incoming == nil {
+			if !clears(p, responses[i]) || (a.policy == FirstResponseWins && fromResponse && merger == nil) {
+				continue
+			}
+			v, cleared, fromResponse = nil, true, true
+			continue
+		}
+		switch {
+		case a.policy == FirstResponseWins && fromResponse && merger == nil:
+			continue
+		case v == nil:
+			v, cleared = incoming, false
+		case merger != nil:
+			merged, err := merger.Merge(v, incoming)
+			if err != nil {
+				return nil, newError(OpMerge, p.headerKey(), "merge context value", err)
+			}
+			v = merged
+		default:
+			v = incoming
+		}
+		fromResponse = true
+	}
+	if v == nil && cleared {
+		return tombstone{}, nil
+	}
+	return v, nil
+}
+
+// clears reports whether the given response headers carry a tombstone for the
+// propagator.
+func clears(p *ValuePropagator, h http.Header) bool {
+	return slices.Contains(values(HeaderCarrier(h), p.headerKey()), TombstoneValue)
+}
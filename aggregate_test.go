@@ -0,0 +1,150 @@
+package ctxwire_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type logsKey struct{}
+
+var mergeLogs = ctxwire.MergerFunc(func(existing, incoming any) (any, error) {
+	return existing.(string) + "," + incoming.(string), nil
+})
+
+func newAggregateRegistry(t *testing.T) *ctxwire.Registry {
+	t.Helper()
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("logs", logsKey{}, ctxwire.WithMerger(mergeLogs)),
+		ctxwire.NewStringPropagator("str", keyStr),
+		ctxwire.NewIntPropagator("int", keyInt, ctxwire.WithDirection(ctxwire.RequestOnly)),
+	))
+	return r
+}
+
+func TestAggregator(t *testing.T) {
+	r := newAggregateRegistry(t)
+	responses := []http.Header{
+		{"X-Ctxwire-Logs": {"a"}, "X-Ctxwire-Str": {"first"}, "X-Ctxwire-Int": {"1"}},
+		{},
+		{"X-Ctxwire-Logs": {"b"}, "X-Ctxwire-Str": {"last"}},
+	}
+	ctx := context.WithValue(context.Background(), logsKey{}, "gw")
+
+	got, err := r.NewAggregator().Aggregate(ctx, responses...)
+	require.NoError(t, err)
+	require.Equal(t, "gw,a,b", got.Value(logsKey{}))
+	require.Equal(t, "last", got.Value(keyStr))
+	require.Nil(t, got.Value(keyInt))
+
+	got, err = r.NewAggregator(ctxwire.WithConflictPolicy(ctxwire.FirstResponseWins)).Aggregate(ctx, responses...)
+	require.NoError(t, err)
+	require.Equal(t, "gw,a,b", got.Value(logsKey{}))
+	require.Equal(t, "first", got.Value(keyStr))
+
+	// Values of the context are replaced by the responses without merger.
+	got, err = r.NewAggregator(ctxwire.WithConflictPolicy(ctxwire.FirstResponseWins)).Aggregate(
+		context.WithValue(ctx, keyStr, "local"), responses...)
+	require.NoError(t, err)
+	require.Equal(t, "first", got.Value(keyStr))
+
+	got, err = r.NewAggregator(
+		ctxwire.WithAggregateMerger("str", ctxwire.MergerFunc(func(existing, incoming any) (any, error) {
+			return existing.(string) + "+" + incoming.(string), nil
+		})),
+	).Aggregate(context.Background(), responses...)
+	require.NoError(t, err)
+	require.Equal(t, "first+last", got.Value(keyStr))
+	require.Equal(t, "a,b", got.Value(logsKey{}))
+
+	h := http.Header{}
+	require.NoError(t, r.InjectResponse(got, h))
+	require.Equal(t, "a,b", h.Get("x-ctxwire-logs"))
+}
+
+func TestAggregatorErrors(t *testing.T) {
+	r := newAggregateRegistry(t)
+	_, err := r.NewAggregator(
+		ctxwire.WithAggregateMerger("str", ctxwire.MergerFunc(func(_, _ any) (any, error) {
+			return nil, errors.New("failed!")
+		})),
+	).Aggregate(context.Background(), http.Header{"X-Ctxwire-Str": {"a"}}, http.Header{"X-Ctxwire-Str": {"b"}})
+	var e *ctxwire.Error
+	require.ErrorAs(t, err, &e)
+	require.Equal(t, ctxwire.OpMerge, e.Op())
+
+	r = ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("json", keyStr)))
+	_, err = r.NewAggregator().Aggregate(context.Background(), http.Header{"X-Ctxwire-Json": {"%%%"}})
+	require.Error(t, err)
+}
+
+func TestAggregatorDecodesOnce(t *testing.T) {
+	codec := ctxwire.JSONCodec.Wrap(ctxwire.TimestampMiddleware(time.Minute, ctxwire.WithNonces(ctxwire.NewMemoryNonceStore())))
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithCodec(codec))))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "v"), h))
+	got, err := r.NewAggregator().Aggregate(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "v", got.Value(keyStr))
+}
+
+func TestAggregatorCollectErrors(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithErrorPolicy(ctxwire.CollectErrors))
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("json", keyInt),
+		ctxwire.NewStringPropagator("logs", logsKey{}, ctxwire.WithMerger(mergeLogs)),
+	))
+	got, err := r.NewAggregator().Aggregate(context.Background(),
+		http.Header{"X-Ctxwire-Json": {"%%%"}, "X-Ctxwire-Logs": {"a"}},
+		http.Header{"X-Ctxwire-Logs": {"b"}},
+	)
+	require.Error(t, err)
+	require.NotNil(t, got)
+	require.Equal(t, "a,b", got.Value(logsKey{}))
+	require.Nil(t, got.Value(keyInt))
+}
+
+func TestAggregatorTombstones(t *testing.T) {
+	r := newAggregateRegistry(t)
+	cleared := http.Header{"X-Ctxwire-Str": {ctxwire.TombstoneValue}, "X-Ctxwire-Logs": {ctxwire.TombstoneValue}}
+	responses := []http.Header{
+		{"X-Ctxwire-Str": {"first"}, "X-Ctxwire-Logs": {"a"}},
+		cleared,
+		{"X-Ctxwire-Logs": {"b"}},
+	}
+
+	got, err := r.NewAggregator().Aggregate(context.WithValue(context.Background(), keyStr, "local"), responses...)
+	require.NoError(t, err)
+	require.True(t, ctxwire.Cleared(got, keyStr))
+	require.Equal(t, "b", got.Value(logsKey{}))
+	h := http.Header{}
+	require.NoError(t, r.InjectResponse(got, h))
+	require.Equal(t, ctxwire.TombstoneValue, h.Get("x-ctxwire-str"))
+
+	got, err = r.NewAggregator(ctxwire.WithConflictPolicy(ctxwire.FirstResponseWins)).Aggregate(context.Background(), cleared, responses[0])
+	require.NoError(t, err)
+	require.True(t, ctxwire.Cleared(got, keyStr))
+
+	// Values cleared by the gateway are set back by the responses.
+	got, err = r.NewAggregator().Aggregate(ctxwire.Clear(context.Background(), logsKey{}), responses[2])
+	require.NoError(t, err)
+	require.Equal(t, "b", got.Value(logsKey{}))
+}
+
+func TestDefaultAggregator(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewStringPropagator("str", keyStr))
+	got, err := ctxwire.NewAggregator().Aggregate(context.Background(),
+		http.Header{"X-Ctxwire-Str": {"a"}}, http.Header{"X-Ctxwire-Str": {"b"}})
+	require.NoError(t, err)
+	require.Equal(t, "b", got.Value(keyStr))
+}
@@ -0,0 +1,124 @@
+package ctxwire
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+)
+
+// B3Propagator propagates the trace identifiers set with
+// ContextWithTraceParent in the B3 headers of Zipkin, so that ctxwire
+// deployments can coexist with fleets standardized on B3.
+// It extracts both the single "b3" header and the multiple "x-b3-*" headers,
+// the single header taking precedence. It injects the format it was created
+// for. Parent span IDs are not propagated, and the debug flag is extracted as
+// the sampled flag. Invalid incoming values are ignored.
+// It only propagates values from clients to servers.
+// It implements the Propagator interface.
+type B3Propagator struct {
+	single bool
+}
+
+var _ Propagator = (*B3Propagator)(nil)
+
+// NewB3Propagator returns a new B3Propagator injecting the multiple "x-b3-*"
+// headers.
+func NewB3Propagator() *B3Propagator { return &B3Propagator{} }
+
+// NewB3SinglePropagator returns a new B3Propagator injecting the single "b3"
+// header.
+func NewB3SinglePropagator() *B3Propagator { return &B3Propagator{single: true} }
+
+const (
+	b3Key        = "b3"
+	b3TraceIDKey = "x-b3-traceid"
+	b3SpanIDKey  = "x-b3-spanid"
+	b3SampledKey = "x-b3-sampled"
+	b3FlagsKey   = "x-b3-flags"
+	b3ParentKey  = "x-b3-parentspanid"
+)
+
+// Name returns the name of the propagator.
+func (p *B3Propagator) Name() string { return "b3" }
+
+// Direction returns RequestOnly.
+func (p *B3Propagator) Direction() Direction { return RequestOnly }
+
+// HeaderKeys returns the header keys used by the propagator.
+func (p *B3Propagator) HeaderKeys() []string {
+	return []string{b3Key, b3TraceIDKey, b3SpanIDKey, b3SampledKey, b3FlagsKey, b3ParentKey}
+}
+
+// Inject implements the Propagator interface.
+func (p *B3Propagator) Inject(ctx context.Context, c Carrier) error {
+	tp, ok := TraceParentFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	sampled := "0"
+	if tp.Sampled() {
+		sampled = "1"
+	}
+	traceID, spanID := hex.EncodeToString(tp.TraceID[:]), hex.EncodeToString(tp.SpanID[:])
+	if p.single {
+		c.Set(b3Key, traceID+"-"+spanID+"-"+sampled)
+		return nil
+	}
+	c.Set(b3TraceIDKey, traceID)
+	c.Set(b3SpanIDKey, spanID)
+	c.Set(b3SampledKey, sampled)
+	return nil
+}
+
+// Extract implements the Propagator interface.
+func (p *B3Propagator) Extract(ctx context.Context, c Carrier) (context.Context, error) {
+	var traceID, spanID, sampled string
+	if v := c.Get(b3Key); v != "" {
+		parts := strings.Split(v, "-")
+		if len(parts) < 2 {
+			// Sampling decision only.
+			return ctx, nil
+		}
+		traceID, spanID = parts[0], parts[1]
+		if len(parts) > 2 {
+			sampled = parts[2]
+		}
+	} else {
+		traceID, spanID, sampled = c.Get(b3TraceIDKey), c.Get(b3SpanIDKey), c.Get(b3SampledKey)
+		if c.Get(b3FlagsKey) == "1" {
+			sampled = "d"
+		}
+	}
+	tp, ok := parseB3(traceID, spanID, sampled)
+	if !ok {
+		return ctx, nil
+	}
+	return ContextWithTraceParent(ctx, tp), nil
+}
+
+// parseB3 parses the given B3 identifiers. 64-bit trace IDs are left-padded
+// with zeros.
+func parseB3(traceID, spanID, sampled string) (TraceParent, bool) {
+	var tp TraceParent
+	if len(traceID) == 16 {
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	tid, ok := decodeLowerHex(traceID)
+	if !ok || len(tid) != len(tp.TraceID) || isZero(tid) {
+		return tp, false
+	}
+	sid, ok := decodeLowerHex(spanID)
+	if !ok || len(sid) != len(tp.SpanID) || isZero(sid) {
+		return tp, false
+	}
+	copy(tp.TraceID[:], tid)
+	copy(tp.SpanID[:], sid)
+	switch sampled {
+	case "1", "d", "true":
+		tp.Flags = 1
+	case "", "0", "false":
+	default:
+		return tp, false
+	}
+	return tp, true
+}
@@ -0,0 +1,76 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestB3Propagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewB3Propagator()))
+
+	h := http.Header{}
+	h.Set("X-B3-TraceId", "80f198ee56343ba864fe8b2a57d3eff7")
+	h.Set("X-B3-SpanId", "e457b5a2e4d86bd1")
+	h.Set("X-B3-ParentSpanId", "05e3ac9a4f6e3b90")
+	h.Set("X-B3-Sampled", "1")
+	ctx, err := r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	tp, ok := ctxwire.TraceParentFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-01", tp.String())
+
+	out := http.Header{}
+	require.NoError(t, r.InjectRequest(ctx, out))
+	require.Equal(t, http.Header{
+		"X-B3-Traceid": {"80f198ee56343ba864fe8b2a57d3eff7"},
+		"X-B3-Spanid":  {"e457b5a2e4d86bd1"},
+		"X-B3-Sampled": {"1"},
+	}, out)
+
+	// The single header takes precedence, and 64-bit trace IDs are padded.
+	h.Set("b3", "64fe8b2a57d3eff7-e457b5a2e4d86bd1-0-05e3ac9a4f6e3b90")
+	ctx, err = r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	tp, _ = ctxwire.TraceParentFromContext(ctx)
+	require.Equal(t, "00-000000000000000064fe8b2a57d3eff7-e457b5a2e4d86bd1-00", tp.String())
+
+	// The debug flag implies sampling.
+	h = http.Header{}
+	h.Set("X-B3-TraceId", "64fe8b2a57d3eff7")
+	h.Set("X-B3-SpanId", "e457b5a2e4d86bd1")
+	h.Set("X-B3-Flags", "1")
+	ctx, err = r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	tp, _ = ctxwire.TraceParentFromContext(ctx)
+	require.True(t, tp.Sampled())
+
+	// Sampling-only and invalid headers are ignored.
+	for _, v := range []string{"0", "zz-e457b5a2e4d86bd1", "64fe8b2a57d3eff7-0000000000000000", "64fe8b2a57d3eff7-e457b5a2e4d86bd1-x"} {
+		h := http.Header{}
+		h.Set("b3", v)
+		ctx, err := r.ExtractRequest(context.Background(), h)
+		require.NoError(t, err)
+		_, ok := ctxwire.TraceParentFromContext(ctx)
+		require.False(t, ok, v)
+	}
+}
+
+func TestB3SinglePropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewB3SinglePropagator(), ctxwire.NewTraceParentPropagator()))
+
+	// Identifiers extracted from traceparent are re-injected as B3.
+	h := http.Header{}
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, err := r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	out := http.Header{}
+	require.NoError(t, r.InjectRequest(ctx, out))
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1", out.Get("b3"))
+	require.Equal(t, h.Get("traceparent"), out.Get("traceparent"))
+}
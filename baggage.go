@@ -0,0 +1,159 @@
+package ctxwire
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+type baggageKey struct{}
+
+// NewBaggagePropagator returns a new ValuePropagator propagating the baggage
+// set with SetBaggage in a single "baggage" header.
+// Extracted baggage is merged into the baggage already present in the context,
+// incoming entries taking precedence.
+func NewBaggagePropagator(opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{WithMerger(MergerFunc(mergeBaggage))}, opts...)
+	return NewTypedJSONPropagator[map[string]string]("baggage", baggageKey{}, opts...)
+}
+
+func mergeBaggage(existing, incoming any) (any, error) {
+	merged := maps.Clone(existing.(map[string]string))
+	maps.Copy(merged, incoming.(map[string]string))
+	return merged, nil
+}
+
+// SetBaggage returns a copy of the given context with the baggage entry k set
+// to v.
+func SetBaggage(ctx context.Context, k, v string) context.Context {
+	b, _ := ctx.Value(baggageKey{}).(map[string]string)
+	b = maps.Clone(b)
+	if b == nil {
+		b = make(map[string]string, 1)
+	}
+	b[k] = v
+	return context.WithValue(ctx, baggageKey{}, b)
+}
+
+// Baggage returns a copy of the baggage of the given context.
+// It returns nil if the context has no baggage.
+func Baggage(ctx context.Context) map[string]string {
+	b, _ := ctx.Value(baggageKey{}).(map[string]string)
+	return maps.Clone(b)
+}
+
+const (
+	// maxBaggageMembers is the maximum number of list-members of a W3C
+	// baggage header.
+	maxBaggageMembers = 64
+	// maxBaggageSize is the maximum size in bytes of a W3C baggage header.
+	maxBaggageSize = 8192
+)
+
+// NewW3CBaggagePropagator returns a new ValuePropagator propagating the
+// baggage set with SetBaggage in the standard "baggage" header of the W3C
+// Baggage specification, interoperating with non-Go services.
+// Entries are sorted by key, and those exceeding the limits of 64 entries and
+// 8192 bytes of the specification are not propagated. The properties of
+// incoming entries are ignored, as well as malformed entries. Keys that are not
+// valid tokens are rejected on Inject.
+// Extracted baggage is merged into the baggage already present in the context,
+// incoming entries taking precedence.
+func NewW3CBaggagePropagator(opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{
+		WithEncoder(EncoderFunc(encodeW3CBaggage)),
+		WithDecoder(DecoderFunc(decodeW3CBaggage)),
+		WithMerger(MergerFunc(mergeBaggage)),
+		WithNamer(func(string) string { return "baggage" }),
+		WithByteEncoding(Raw),
+	}, opts...)
+	return NewPropagator("w3c-baggage", baggageKey{}, opts...)
+}
+
+func encodeW3CBaggage(ctx context.Context, key any) ([]byte, error) {
+	b, _ := ctx.Value(key).(map[string]string)
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var buf []byte
+	n := 0
+	for _, k := range slices.Sorted(maps.Keys(b)) {
+		if !isToken(k) {
+			return nil, fmt.Errorf("invalid baggage key %q", k)
+		}
+		member := k + "=" + escapeBaggageValue(b[k])
+		size := len(buf) + len(member)
+		if n > 0 {
+			size++
+		}
+		if n == maxBaggageMembers || size > maxBaggageSize {
+			break
+		}
+		if n > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, member...)
+		n++
+	}
+	return buf, nil
+}
+
+func decodeW3CBaggage(ctx context.Context, key any, data []byte) (context.Context, error) {
+	b := map[string]string{}
+	for _, member := range strings.Split(string(data), ",") {
+		member, _, _ = strings.Cut(member, ";")
+		k, v, ok := strings.Cut(member, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || !isToken(k) {
+			continue
+		}
+		v, err := url.PathUnescape(v)
+		if err != nil {
+			continue
+		}
+		b[k] = v
+		if len(b) == maxBaggageMembers {
+			break
+		}
+	}
+	return context.WithValue(ctx, key, b), nil
+}
+
+// isBaggageOctet reports whether c can appear unescaped in a baggage value.
+func isBaggageOctet(c byte) bool {
+	return c >= 0x21 && c <= 0x7e && c != '"' && c != ',' && c != ';' && c != '\\' && c != '%'
+}
+
+// escapeBaggageValue percent-encodes the bytes of v which are not baggage
+// octets.
+func escapeBaggageValue(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; isBaggageOctet(c) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// isToken reports whether s is a token as defined by RFC 7230.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
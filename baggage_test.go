@@ -0,0 +1,77 @@
+package ctxwire_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestBaggage(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewBaggagePropagator()))
+
+	require.Nil(t, ctxwire.Baggage(context.Background()))
+
+	ctx := ctxwire.SetBaggage(context.Background(), "tenant", "acme")
+	ctx = ctxwire.SetBaggage(ctx, "region", "eu")
+	require.Equal(t, map[string]string{"tenant": "acme", "region": "eu"}, ctxwire.Baggage(ctx))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Len(t, h, 1)
+
+	ctx = ctxwire.SetBaggage(context.Background(), "tenant", "other")
+	ctx = ctxwire.SetBaggage(ctx, "local", "yes")
+	ctx, err := r.Extract(ctx, h)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"tenant": "acme", "region": "eu", "local": "yes"}, ctxwire.Baggage(ctx))
+}
+
+func TestW3CBaggage(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewW3CBaggagePropagator()))
+
+	ctx := ctxwire.SetBaggage(context.Background(), "tenant", "acme")
+	ctx = ctxwire.SetBaggage(ctx, "user", "Jöhn Doe;1,%")
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Equal(t, http.Header{"Baggage": {"tenant=acme,user=J%C3%B6hn%20Doe%3B1%2C%25"}}, h)
+
+	ctx, err := r.Extract(ctxwire.SetBaggage(context.Background(), "local", "yes"), h)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"tenant": "acme", "user": "Jöhn Doe;1,%", "local": "yes"}, ctxwire.Baggage(ctx))
+
+	// Properties and malformed members are ignored.
+	h.Set("baggage", " a = 1 ;prop=x, b, c=%zz ,d=2;p")
+	ctx, err = r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"a": "1", "d": "2"}, ctxwire.Baggage(ctx))
+
+	err = r.Inject(ctxwire.SetBaggage(context.Background(), "bad key", "v"), http.Header{})
+	require.ErrorContains(t, err, `invalid baggage key "bad key"`)
+}
+
+func TestW3CBaggageLimits(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewW3CBaggagePropagator()))
+
+	ctx := context.Background()
+	for i := range 100 {
+		ctx = ctxwire.SetBaggage(ctx, fmt.Sprintf("k%03d", i), "v")
+	}
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Len(t, strings.Split(h.Get("baggage"), ","), 64)
+
+	ctx = ctxwire.SetBaggage(context.Background(), "a", strings.Repeat("x", 8000))
+	ctx = ctxwire.SetBaggage(ctx, "b", strings.Repeat("x", 500))
+	ctx = ctxwire.SetBaggage(ctx, "c", "small")
+	h = http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Equal(t, "a="+strings.Repeat("x", 8000), h.Get("baggage"))
+}
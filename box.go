@@ -0,0 +1,71 @@
+package ctxwire
+
+import (
+	"context"
+	"sync"
+)
+
+type boxKey struct{}
+
+// box is a mutable container of context values.
+type box struct {
+	mu     sync.Mutex
+	values map[any]any
+}
+
+func (b *box) get(key any) (any, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.values[key]
+	return v, ok
+}
+
+// WithBox returns a copy of the given context containing a mutable box, in
+// which values can be recorded with Put.
+// Values recorded in the box are visible to the propagators when injecting the
+// context with a registry, taking precedence over the values of the context.
+// This allows HTTP handlers to record values during the request processing and
+// have them injected into the response by a middleware which cannot see the
+// contexts derived by the handler.
+func WithBox(ctx context.Context) context.Context {
+	return context.WithValue(ctx, boxKey{}, &box{values: make(map[any]any)})
+}
+
+// Put records the value v associated with the given key in the box of the
+// given context. It is safe for concurrent use.
+// It reports whether the context contains a box, in which case the value is
+// recorded.
+func Put(ctx context.Context, key, v any) bool {
+	b, ok := ctx.Value(boxKey{}).(*box)
+	if !ok {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[key] = v
+	return true
+}
+
+// boxedContext is a context whose values recorded in a box take precedence
+// over the values of the context.
+type boxedContext struct {
+	context.Context
+	box *box
+}
+
+func (c boxedContext) Value(key any) any {
+	if v, ok := c.box.get(key); ok {
+		return v
+	}
+	return c.Context.Value(key)
+}
+
+// unbox returns a context exposing the values of the box of the given context,
+// or the context itself if it has no box.
+func unbox(ctx context.Context) context.Context {
+	b, ok := ctx.Value(boxKey{}).(*box)
+	if !ok {
+		return ctx
+	}
+	return boxedContext{Context: ctx, box: b}
+}
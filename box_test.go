@@ -0,0 +1,42 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestBox(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("int", keyInt),
+	))
+
+	require.False(t, ctxwire.Put(context.Background(), keyStr, "lost"))
+
+	// A middleware boxes the request context and injects it in the response
+	// once the handler returns.
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), keyStr, "derived")
+		require.True(t, ctxwire.Put(ctx, keyInt, 42))
+		require.True(t, ctxwire.Put(ctx, keyStr, "boxed"))
+	}
+	middleware := func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), keyStr, "original")
+		ctx = ctxwire.WithBox(ctx)
+		handler(w, req.WithContext(ctx))
+		require.NoError(t, r.Inject(ctx, w.Header()))
+	}
+
+	rec := httptest.NewRecorder()
+	middleware(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	ctx, err := r.Extract(context.Background(), rec.Header())
+	require.NoError(t, err)
+	require.Equal(t, "boxed", ctx.Value(keyStr))
+	require.Equal(t, float64(42), ctx.Value(keyInt))
+}
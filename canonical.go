@@ -0,0 +1,75 @@
+package ctxwire
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// CanonicalJSONCodec is a JSONCodec producing canonical JSON.
+var CanonicalJSONCodec = JSONCodec.Wrap(CanonicalJSONMiddleware())
+
+// CanonicalJSONMiddleware returns a middleware rewriting the JSON payloads of
+// the wrapped codec in a canonical form: object keys are sorted at any depth,
+// insignificant whitespace is removed, HTML characters are not escaped and
+// numbers with a fraction or an exponent are written in the shortest form of
+// their float64 value, so that 1.0, 1e0 and 1 produce the same bytes. Integers
+// written without a fraction or an exponent are kept as is, so that they don't
+// lose precision beyond 2^53.
+// The same context value then always produces byte-identical headers, which
+// is a prerequisite for signing, caching and golden-file testing.
+// Payloads are decoded unchanged.
+func CanonicalJSONMiddleware() CodecMiddleware {
+	return TransformMiddleware(canonicalJSON, func(data []byte) ([]byte, error) { return data, nil })
+}
+
+// canonicalJSON returns the canonical form of the given JSON document.
+func canonicalJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(canonicalNumbers(v)); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// canonicalNumbers rewrites the numbers of the given decoded JSON document in
+// their canonical form.
+func canonicalNumbers(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = canonicalNumbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = canonicalNumbers(e)
+		}
+	case json.Number:
+		if !strings.ContainsAny(string(v), ".eE") {
+			if v == "-0" {
+				return json.Number("0")
+			}
+			return v
+		}
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return v
+		}
+		if f == 0 {
+			return json.Number("0")
+		}
+		// float64 values are marshaled in the shortest form reading back
+		// to the same value.
+		return f
+	}
+	return v
+}
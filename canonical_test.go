@@ -0,0 +1,47 @@
+package ctxwire_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type rawKey struct{}
+
+func TestCanonicalJSON(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("raw", rawKey{},
+		ctxwire.WithCodec(ctxwire.CanonicalJSONCodec),
+		ctxwire.WithByteEncoding(ctxwire.Raw),
+	)))
+
+	for _, v := range []json.RawMessage{
+		json.RawMessage(`{"b": 1, "a": {"d": [1.50, "<x>"], "c": null}}`),
+		json.RawMessage("{\n  \"a\": {\"c\": null, \"d\": [1.50, \"<x>\"]},\n  \"b\": 1\n}"),
+	} {
+		h, err := r.Preview(context.WithValue(context.Background(), rawKey{}, v))
+		require.NoError(t, err)
+		require.Equal(t, `{"a":{"c":null,"d":[1.5,"<x>"]},"b":1}`, h.Get("x-ctxwire-raw"))
+	}
+}
+
+func TestCanonicalJSONNumbers(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("raw", rawKey{},
+		ctxwire.WithCodec(ctxwire.CanonicalJSONCodec),
+		ctxwire.WithByteEncoding(ctxwire.Raw),
+	)))
+
+	for in, want := range map[string]string{
+		`[1, 1.0, 1e0, 10E-1, -0, -0.0]`: `[1,1,1,1,0,0]`,
+		`[0.000001, 1e-7, 1.5e21]`:       `[0.000001,1e-7,1.5e+21]`,
+		`[12345678901234567890, 1e400]`:  `[12345678901234567890,1e400]`,
+	} {
+		h, err := r.Preview(context.WithValue(context.Background(), rawKey{}, json.RawMessage(in)))
+		require.NoError(t, err)
+		require.Equal(t, want, h.Get("x-ctxwire-raw"), in)
+	}
+}
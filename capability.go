@@ -0,0 +1,126 @@
+package ctxwire
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// Capabilities describes what a client can decode, as advertised in the
+// headers of its requests.
+type Capabilities struct {
+	// Propagators are the names of the propagators of the client.
+	Propagators []string
+	// Tags are the content tags accepted by the client, by decreasing order of
+	// preference.
+	Tags []string
+}
+
+type capabilitiesKey struct{}
+
+// WithCapabilities makes the registry negotiate the propagated values with its
+// peers:
+//   - InjectRequest advertises the names of the propagators of the registry in
+//     the accept header, named after the header prefix of the registry
+//     ("x-ctxwire-accept" by default), and the given content tags, by
+//     decreasing order of preference, in the accept-tags header.
+//   - Extract records the capabilities advertised by clients in the context,
+//     which can be read with CapabilitiesFromContext.
+//   - Inject and InjectResponse skip the named propagators the client can't
+//     decode, if the context holds its capabilities.
+//
+// Use NegotiatedEncoder to encode values in the preferred encoding of the
+// client.
+func WithCapabilities(tags ...string) RegistryOption {
+	return func(r *Registry) {
+		r.capabilities = true
+		r.acceptedTags = tags
+	}
+}
+
+// CapabilitiesFromContext returns the capabilities of the client recorded in
+// the given context, and whether they were found.
+func CapabilitiesFromContext(ctx context.Context) (Capabilities, bool) {
+	c, ok := ctx.Value(capabilitiesKey{}).(Capabilities)
+	return c, ok
+}
+
+func (r *Registry) acceptKey() string     { return r.naming.headerPrefix() + "accept" }
+func (r *Registry) acceptTagsKey() string { return r.naming.headerPrefix() + "accept-tags" }
+
+// advertise sets the capabilities of the registry in the given carrier.
+func (r *Registry) advertise(c Carrier) {
+	var names []string
+	for _, p := range r.load() {
+		if n, ok := p.(named); ok && propagates(p, ResponseOnly) {
+			names = append(names, n.Name())
+		}
+	}
+	if len(names) > 0 {
+		c.Set(r.acceptKey(), strings.Join(names, ", "))
+	}
+	if len(r.acceptedTags) > 0 {
+		c.Set(r.acceptTagsKey(), strings.Join(r.acceptedTags, ", "))
+	}
+}
+
+// recordCapabilities returns a copy of the given context recording the
+// capabilities advertised in the given carrier, if any.
+func (r *Registry) recordCapabilities(ctx context.Context, c Carrier) context.Context {
+	accept, tags := c.Get(r.acceptKey()), c.Get(r.acceptTagsKey())
+	if accept == "" && tags == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, capabilitiesKey{}, Capabilities{
+		Propagators: splitList(accept),
+		Tags:        splitList(tags),
+	})
+}
+
+// accepted returns a function reporting whether the client accepts the values
+// of a propagator, or nil if all the propagators are accepted.
+func (r *Registry) accepted(ctx context.Context, dir Direction) func(p Propagator) bool {
+	if !r.capabilities || dir == RequestOnly {
+		return nil
+	}
+	caps, ok := CapabilitiesFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return func(p Propagator) bool {
+		n, ok := p.(named)
+		return !ok || slices.Contains(caps.Propagators, n.Name())
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// NegotiatedEncoder returns an encoder tagging the payloads, as TagEncoder
+// does, with the encoder of the content tag preferred by the client whose
+// capabilities are recorded in the context. The fallback tag is used when the
+// client accepts none of the tags of the given encoders, or didn't advertise
+// its capabilities.
+// Clients decode the payloads with a TagDecoder.
+func NegotiatedEncoder(encoders map[string]Encoder, fallback string) Encoder {
+	tagged := make(map[string]Encoder, len(encoders))
+	for tag, enc := range encoders {
+		tagged[tag] = TagEncoder(tag, enc)
+	}
+	return EncoderFunc(func(ctx context.Context, key any) ([]byte, error) {
+		tag := fallback
+		if caps, ok := CapabilitiesFromContext(ctx); ok {
+			if i := slices.IndexFunc(caps.Tags, func(tag string) bool { return tagged[tag] != nil }); i >= 0 {
+				tag = caps.Tags[i]
+			}
+		}
+		return tagged[tag].Encode(ctx, key)
+	})
+}
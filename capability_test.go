@@ -0,0 +1,70 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestCapabilities(t *testing.T) {
+	jsonEnc, jsonDec := ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON)
+	gzEnc, gzDec := ctxwire.Compress(jsonEnc, jsonDec, ctxwire.WithCompressionThreshold(0))
+
+	client := ctxwire.NewRegistry(ctxwire.WithCapabilities("gz+j", "j"))
+	require.NoError(t, client.Configure(
+		ctxwire.NewPropagator("str", keyStr, ctxwire.WithDecoder(ctxwire.TagDecoder(map[string]ctxwire.Decoder{
+			"j":    jsonDec,
+			"gz+j": gzDec,
+		}))),
+	))
+	server := ctxwire.NewRegistry(ctxwire.WithCapabilities())
+	require.NoError(t, server.Configure(
+		ctxwire.NewPropagator("str", keyStr, ctxwire.WithEncoder(ctxwire.NegotiatedEncoder(map[string]ctxwire.Encoder{
+			"j":    jsonEnc,
+			"gz+j": gzEnc,
+		}, "j"))),
+		ctxwire.NewJSONPropagator("int", keyInt),
+	))
+
+	req := http.Header{}
+	require.NoError(t, client.InjectRequest(context.Background(), req))
+	require.Equal(t, http.Header{
+		"X-Ctxwire-Accept":      {"str"},
+		"X-Ctxwire-Accept-Tags": {"gz+j, j"},
+	}, req)
+
+	ctx, err := server.ExtractRequest(context.Background(), req)
+	require.NoError(t, err)
+	caps, ok := ctxwire.CapabilitiesFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, ctxwire.Capabilities{Propagators: []string{"str"}, Tags: []string{"gz+j", "j"}}, caps)
+
+	// The server only injects the values the client can decode, in its
+	// preferred encoding.
+	ctx = context.WithValue(ctx, keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	resp := http.Header{}
+	require.NoError(t, server.InjectResponse(ctx, resp))
+	require.Len(t, resp, 1)
+	data := resp.Get("x-ctxwire-str")
+	require.NotEmpty(t, data)
+
+	got, err := client.ExtractResponse(context.Background(), resp)
+	require.NoError(t, err)
+	require.Equal(t, "foo", got.Value(keyStr))
+
+	// Without capabilities, all the values are injected with the fallback
+	// encoding.
+	ctx = context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	resp = http.Header{}
+	require.NoError(t, server.InjectResponse(ctx, resp))
+	require.Len(t, resp, 2)
+	require.NotEqual(t, data, resp.Get("x-ctxwire-str"))
+	got, err = client.ExtractResponse(context.Background(), resp)
+	require.NoError(t, err)
+	require.Equal(t, "foo", got.Value(keyStr))
+}
@@ -0,0 +1,125 @@
+package ctxwire
+
+import (
+	"maps"
+	"net/http"
+	"net/textproto"
+	"slices"
+	"strings"
+)
+
+// Carrier carries the propagated values over the wire as string key/value
+// pairs. It allows using the same propagators with HTTP headers, gRPC
+// metadata, message queue headers or any other key/value transport.
+type Carrier interface {
+	// Get returns the value associated with the given key, or an empty string.
+	Get(key string) string
+	// Set sets the value associated with the given key.
+	Set(key, value string)
+	// Keys returns the keys of the carrier.
+	Keys() []string
+}
+
+// MultiValueCarrier is a Carrier supporting several values for the same key.
+type MultiValueCarrier interface {
+	Carrier
+	// Values returns all the values associated with the given key.
+	Values(key string) []string
+	// Add adds the value to the values associated with the given key.
+	Add(key, value string)
+}
+
+// HeaderCarrier adapts http.Header to the MultiValueCarrier interface.
+type HeaderCarrier http.Header
+
+var _ MultiValueCarrier = HeaderCarrier(nil)
+
+// Get implements the Carrier interface.
+func (c HeaderCarrier) Get(key string) string {
+	return http.Header(c).Get(key)
+}
+
+// Set implements the Carrier interface.
+func (c HeaderCarrier) Set(key, value string) {
+	http.Header(c).Set(key, value)
+}
+
+// Keys implements the Carrier interface.
+func (c HeaderCarrier) Keys() []string {
+	return slices.Collect(maps.Keys(c))
+}
+
+// Values implements the MultiValueCarrier interface.
+func (c HeaderCarrier) Values(key string) []string {
+	return http.Header(c).Values(key)
+}
+
+// Add implements the MultiValueCarrier interface.
+func (c HeaderCarrier) Add(key, value string) {
+	http.Header(c).Add(key, value)
+}
+
+// MapCarrier adapts map[string]string to the Carrier interface, so that any
+// system storing string key/value pairs can carry the values. Keys are
+// lowercase.
+type MapCarrier map[string]string
+
+var _ Carrier = MapCarrier(nil)
+
+// Get implements the Carrier interface.
+func (c MapCarrier) Get(key string) string {
+	return c[strings.ToLower(key)]
+}
+
+// Set implements the Carrier interface.
+func (c MapCarrier) Set(key, value string) {
+	c[strings.ToLower(key)] = value
+}
+
+// Keys implements the Carrier interface.
+func (c MapCarrier) Keys() []string {
+	return slices.Collect(maps.Keys(c))
+}
+
+// MIMEHeaderCarrier adapts textproto.MIMEHeader to the MultiValueCarrier
+// interface, so that values can be carried in the headers of MIME parts and
+// mail messages. A mail.Header can be converted to a textproto.MIMEHeader.
+type MIMEHeaderCarrier textproto.MIMEHeader
+
+var _ MultiValueCarrier = MIMEHeaderCarrier(nil)
+
+// Get implements the Carrier interface.
+func (c MIMEHeaderCarrier) Get(key string) string {
+	return textproto.MIMEHeader(c).Get(key)
+}
+
+// Set implements the Carrier interface.
+func (c MIMEHeaderCarrier) Set(key, value string) {
+	textproto.MIMEHeader(c).Set(key, value)
+}
+
+// Keys implements the Carrier interface.
+func (c MIMEHeaderCarrier) Keys() []string {
+	return slices.Collect(maps.Keys(c))
+}
+
+// Values implements the MultiValueCarrier interface.
+func (c MIMEHeaderCarrier) Values(key string) []string {
+	return textproto.MIMEHeader(c).Values(key)
+}
+
+// Add implements the MultiValueCarrier interface.
+func (c MIMEHeaderCarrier) Add(key, value string) {
+	textproto.MIMEHeader(c).Add(key, value)
+}
+
+// values returns all the values associated with the given key in the carrier.
+func values(c Carrier, key string) []string {
+	if mc, ok := c.(MultiValueCarrier); ok {
+		return mc.Values(key)
+	}
+	if v := c.Get(key); v != "" {
+		return []string{v}
+	}
+	return nil
+}
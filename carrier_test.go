@@ -0,0 +1,85 @@
+package ctxwire_test
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+// mapCarrier is a minimal Carrier implementation, as used by message queues.
+type mapCarrier map[string]string
+
+func (c mapCarrier) Get(key string) string { return c[key] }
+func (c mapCarrier) Set(key, value string) { c[key] = value }
+func (c mapCarrier) Keys() []string        { return slices.Collect(maps.Keys(c)) }
+
+func TestCarrier(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("int", keyInt, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	c := mapCarrier{}
+	require.NoError(t, r.InjectCarrier(ctx, c, ctxwire.RequestOnly))
+	require.Equal(t, mapCarrier{"x-ctxwire-str": "ImZvbyI="}, c)
+
+	ctx, err := r.ExtractCarrier(context.Background(), c, ctxwire.Both)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+}
+
+func TestMIMEHeaderCarrier(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithMultiValue())))
+
+	// Values are carried in the headers of multipart parts.
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	h := textproto.MIMEHeader{"Content-Type": {"text/plain"}}
+	require.NoError(t, r.InjectCarrier(context.WithValue(context.Background(), keyStr, "foo"), ctxwire.MIMEHeaderCarrier(h), ctxwire.Both))
+	require.NoError(t, r.InjectCarrier(context.WithValue(context.Background(), keyStr, "bar"), ctxwire.MIMEHeaderCarrier(h), ctxwire.Both))
+	require.Equal(t, []string{"ImZvbyI=", "ImJhciI="}, h.Values("X-Ctxwire-Str"))
+	_, err := mw.CreatePart(h)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	part, err := multipart.NewReader(&buf, mw.Boundary()).NextPart()
+	require.NoError(t, err)
+	ctx, err := r.ExtractCarrier(context.Background(), ctxwire.MIMEHeaderCarrier(part.Header), ctxwire.Both)
+	require.NoError(t, err)
+	require.Equal(t, "bar", ctx.Value(keyStr))
+
+	// And in the headers of mail messages.
+	msg, err := mail.ReadMessage(strings.NewReader("X-Ctxwire-Str: ImZvbyI=\r\n\r\nbody"))
+	require.NoError(t, err)
+	ctx, err = r.ExtractCarrier(context.Background(), ctxwire.MIMEHeaderCarrier(msg.Header), ctxwire.Both)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+}
+
+func TestMapCarrier(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithHeaderPrefix("X-App-"))
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr)))
+
+	c := ctxwire.MapCarrier{}
+	require.NoError(t, r.InjectCarrier(context.WithValue(context.Background(), keyStr, "foo"), c, ctxwire.Both))
+	require.Equal(t, ctxwire.MapCarrier{"x-app-str": "ImZvbyI="}, c)
+	require.Equal(t, "ImZvbyI=", c.Get("X-App-Str"))
+	require.Equal(t, []string{"x-app-str"}, c.Keys())
+
+	ctx, err := r.ExtractCarrier(context.Background(), c, ctxwire.Both)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+}
@@ -0,0 +1,84 @@
+package ctxwire
+
+import "context"
+
+// Codec pairs an encoder with the decoder of its payloads.
+type Codec struct {
+	Encoder Encoder
+	Decoder Decoder
+}
+
+// JSONCodec encodes and decodes context values as JSON, as NewPropagator does
+// by default.
+var JSONCodec = Codec{Encoder: EncoderFunc(encodeJSON), Decoder: DecoderFunc(decodeJSON)}
+
+// CodecMiddleware wraps a codec to layer cross-cutting transforms, such as
+// compression, signing or size limiting, on any base codec.
+type CodecMiddleware func(c Codec) Codec
+
+// Wrap returns the codec wrapped with the given middlewares, in order: the
+// first middleware is the closest to the codec, so payloads go through the
+// middlewares in order when encoding and in reverse order when decoding.
+// Mergers implemented by the decoder of the codec must be set explicitly with
+// WithMerger.
+func (c Codec) Wrap(middlewares ...CodecMiddleware) Codec {
+	for _, m := range middlewares {
+		c = m(c)
+	}
+	return c
+}
+
+// WithCodec sets the encoder and the decoder used to encode and decode the
+// context value.
+func WithCodec(c Codec) PropagatorOption {
+	return func(p *ValuePropagator) {
+		p.encoder = c.Encoder
+		p.decoder = c.Decoder
+	}
+}
+
+// TransformMiddleware returns a middleware transforming the encoded payloads
+// with the given functions. The encode function is called with the payloads
+// produced by the wrapped encoder, and the decode function must revert it.
+// Empty payloads are not transformed.
+func TransformMiddleware(encode, decode func(data []byte) ([]byte, error)) CodecMiddleware {
+	return func(c Codec) Codec {
+		return Codec{
+			Encoder: EncoderFunc(func(ctx context.Context, key any) ([]byte, error) {
+				data, err := c.Encoder.Encode(ctx, key)
+				if err != nil || len(data) == 0 {
+					return data, err
+				}
+				return encode(data)
+			}),
+			Decoder: DecoderFunc(func(ctx context.Context, key any, data []byte) (context.Context, error) {
+				data, err := decode(data)
+				if err != nil {
+					return nil, err
+				}
+				return c.Decoder.Decode(ctx, key, data)
+			}),
+		}
+	}
+}
+
+// CompressMiddleware returns a middleware compressing the payloads as
+// Compress does.
+func CompressMiddleware(opts ...CompressOption) CodecMiddleware {
+	return func(c Codec) Codec {
+		enc, dec := Compress(c.Encoder, c.Decoder, opts...)
+		return Codec{Encoder: enc, Decoder: dec}
+	}
+}
+
+// TagMiddleware returns a middleware tagging the payloads with the given
+// content tag, as TagEncoder does. The decoder only accepts the payloads
+// tagged with the given tag; use TagDecoder to accept several tags.
+func TagMiddleware(tag string) CodecMiddleware {
+	return func(c Codec) Codec {
+		return Codec{
+			Encoder: TagEncoder(tag, c.Encoder),
+			Decoder: TagDecoder(map[string]Decoder{tag: c.Decoder}),
+		}
+	}
+}
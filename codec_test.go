@@ -0,0 +1,63 @@
+package ctxwire_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestCodecWrap(t *testing.T) {
+	var calls []string
+	trace := func(name string) ctxwire.CodecMiddleware {
+		return ctxwire.TransformMiddleware(
+			func(data []byte) ([]byte, error) {
+				calls = append(calls, "encode "+name)
+				return append([]byte(name), data...), nil
+			},
+			func(data []byte) ([]byte, error) {
+				calls = append(calls, "decode "+name)
+				if !bytes.HasPrefix(data, []byte(name)) {
+					return nil, errors.New("bad prefix")
+				}
+				return data[len(name):], nil
+			},
+		)
+	}
+	codec := ctxwire.JSONCodec.Wrap(trace("a"), trace("b"))
+
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithCodec(codec))))
+
+	h, err := r.Preview(context.WithValue(context.Background(), keyStr, "foo"))
+	require.NoError(t, err)
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+	require.Equal(t, []string{"encode a", "encode b", "decode b", "decode a"}, calls)
+
+	_, err = r.Extract(context.Background(), http.Header{"X-Ctxwire-Str": {"YWIiZm9vIg=="}}) // ab"foo"
+	require.EqualError(t, err, "decode context value: bad prefix")
+}
+
+func TestCodecMiddlewares(t *testing.T) {
+	codec := ctxwire.JSONCodec.Wrap(
+		ctxwire.CompressMiddleware(ctxwire.WithCompressionThreshold(0)),
+		ctxwire.TagMiddleware("gz+j"),
+	)
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithCodec(codec))))
+
+	h, err := r.Preview(context.WithValue(context.Background(), keyStr, "foo"))
+	require.NoError(t, err)
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+
+	_, err = r.Extract(context.Background(), http.Header{"X-Ctxwire-Str": {"ImZvbyI="}})
+	require.EqualError(t, err, "decode context value: missing content tag")
+}
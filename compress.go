@@ -0,0 +1,99 @@
+package ctxwire
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Compression flags prefixing compressed payloads.
+const (
+	flagUncompressed byte = iota
+	flagGzip
+)
+
+// CompressOption configures the compression of Compress.
+type CompressOption func(c *compressor)
+
+// WithCompressionThreshold sets the size in bytes from which payloads are
+// compressed. Smaller payloads are sent uncompressed, since compression would
+// likely make them larger. Defaults to 256 bytes.
+func WithCompressionThreshold(threshold int) CompressOption {
+	return func(c *compressor) { c.threshold = threshold }
+}
+
+// WithMaxDecompressedSize sets the maximum size in bytes of decompressed
+// payloads, protecting receivers from decompression bombs. Defaults to 1 MiB.
+func WithMaxDecompressedSize(size int64) CompressOption {
+	return func(c *compressor) { c.maxSize = size }
+}
+
+type compressor struct {
+	enc       Encoder
+	dec       Decoder
+	threshold int
+	maxSize   int64
+}
+
+// Compress returns an encoder and a decoder wrapping the given ones to gzip the
+// encoded payloads larger than a threshold before they are sent on the wire.
+// Compressed payloads are prefixed with a flag byte, so the returned encoder
+// and decoder must be used on both sides.
+// Mergers implemented by the given decoder must be set explicitly with
+// WithMerger.
+func Compress(enc Encoder, dec Decoder, opts ...CompressOption) (Encoder, Decoder) {
+	c := &compressor{enc: enc, dec: dec, threshold: 256, maxSize: 1 << 20}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return EncoderFunc(c.encode), DecoderFunc(c.decode)
+}
+
+func (c *compressor) encode(ctx context.Context, key any) ([]byte, error) {
+	data, err := c.enc.Encode(ctx, key)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	if len(data) < c.threshold {
+		return append([]byte{flagUncompressed}, data...), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(flagGzip)
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *compressor) decode(ctx context.Context, key any, data []byte) (context.Context, error) {
+	if len(data) == 0 {
+		return nil, errors.New("missing compression flag")
+	}
+	switch data[0] {
+	case flagUncompressed:
+		return c.dec.Decode(ctx, key, data[1:])
+	case flagGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		decompressed, err := io.ReadAll(io.LimitReader(r, c.maxSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(decompressed)) > c.maxSize {
+			return nil, fmt.Errorf("decompressed payload exceeds %d bytes", c.maxSize)
+		}
+		return c.dec.Decode(ctx, key, decompressed)
+	default:
+		return nil, fmt.Errorf("unknown compression flag %#x", data[0])
+	}
+}
@@ -0,0 +1,37 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestCompress(t *testing.T) {
+	enc, dec := ctxwire.Compress(
+		ctxwire.EncoderFunc(ctxwire.EncodeJSON),
+		ctxwire.DecoderFunc(ctxwire.DecodeJSON),
+		ctxwire.WithCompressionThreshold(64),
+		ctxwire.WithMaxDecompressedSize(2048),
+	)
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithEncoder(enc), ctxwire.WithDecoder(dec))))
+
+	for _, v := range []string{"small", strings.Repeat("large", 100)} {
+		h := http.Header{}
+		require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, v), h))
+		require.Less(t, len(h.Get("x-ctxwire-str")), 100)
+
+		ctx, err := r.Extract(context.Background(), h)
+		require.NoError(t, err)
+		require.Equal(t, v, ctx.Value(keyStr))
+	}
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, strings.Repeat("x", 4096)), h))
+	_, err := r.Extract(context.Background(), h)
+	require.EqualError(t, err, "decode context value: decompressed payload exceeds 2048 bytes")
+}
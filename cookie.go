@@ -0,0 +1,81 @@
+package ctxwire
+
+import "net/http"
+
+// CookieCarrier carries the propagated values in cookies, enabling propagation
+// to and from browsers, which persist cookies across requests.
+// It reads the cookies of a request and writes cookies to a response writer,
+// or to the request if the response writer is nil, for clients.
+// Cookie values can't contain spaces, double quotes, commas, semicolons or
+// backslashes, so the propagators must use a byte encoding such as Base64 or
+// Base64URL.
+// It implements the Carrier interface.
+type CookieCarrier struct {
+	req      *http.Request
+	w        http.ResponseWriter
+	template http.Cookie
+}
+
+var _ Carrier = (*CookieCarrier)(nil)
+
+// CookieOption configures a CookieCarrier.
+type CookieOption func(c *CookieCarrier)
+
+// WithCookieAttributes sets the attributes of the cookies written by the
+// carrier, such as their path, domain, expiration or security attributes.
+// The name and value of the given cookie are ignored. Defaults to a cookie
+// with the "/" path, HttpOnly and a Lax SameSite mode.
+func WithCookieAttributes(template http.Cookie) CookieOption {
+	return func(c *CookieCarrier) { c.template = template }
+}
+
+// NewCookieCarrier returns a new CookieCarrier reading the cookies of the given
+// request and writing cookies to the given response writer. A nil response
+// writer makes the carrier add the cookies to the request instead.
+func NewCookieCarrier(req *http.Request, w http.ResponseWriter, opts ...CookieOption) *CookieCarrier {
+	c := &CookieCarrier{
+		req:      req,
+		w:        w,
+		template: http.Cookie{Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Get implements the Carrier interface.
+func (c *CookieCarrier) Get(key string) string {
+	if c.req == nil {
+		return ""
+	}
+	cookie, err := c.req.Cookie(key)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// Set implements the Carrier interface.
+func (c *CookieCarrier) Set(key, value string) {
+	cookie := c.template
+	cookie.Name, cookie.Value = key, value
+	if c.w == nil {
+		c.req.AddCookie(&cookie)
+		return
+	}
+	http.SetCookie(c.w, &cookie)
+}
+
+// Keys implements the Carrier interface.
+func (c *CookieCarrier) Keys() []string {
+	if c.req == nil {
+		return nil
+	}
+	cookies := c.req.Cookies()
+	keys := make([]string, len(cookies))
+	for i, cookie := range cookies {
+		keys[i] = cookie.Name
+	}
+	return keys
+}
@@ -0,0 +1,48 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestCookieCarrier(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr)))
+
+	// The server sets the cookie in its response.
+	w := httptest.NewRecorder()
+	c := ctxwire.NewCookieCarrier(httptest.NewRequest(http.MethodGet, "/", nil), w,
+		ctxwire.WithCookieAttributes(http.Cookie{Path: "/app", Secure: true, MaxAge: 60}))
+	require.NoError(t, r.InjectCarrier(context.WithValue(context.Background(), keyStr, "foo"), c, ctxwire.Both))
+	require.Equal(t, []string{"x-ctxwire-str=ImZvbyI=; Path=/app; Max-Age=60; Secure"}, w.Header().Values("Set-Cookie"))
+
+	// The browser sends it back.
+	req := httptest.NewRequest(http.MethodGet, "/app", nil)
+	for _, cookie := range w.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	c = ctxwire.NewCookieCarrier(req, httptest.NewRecorder())
+	require.Equal(t, []string{"x-ctxwire-str"}, c.Keys())
+	ctx, err := r.ExtractCarrier(context.Background(), c, ctxwire.Both)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+}
+
+func TestCookieCarrierClient(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr)))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	c := ctxwire.NewCookieCarrier(req, nil)
+	require.NoError(t, r.InjectCarrier(context.WithValue(context.Background(), keyStr, "foo"), c, ctxwire.Both))
+	require.Equal(t, "x-ctxwire-str=ImZvbyI=", req.Header.Get("Cookie"))
+
+	ctx, err := r.ExtractCarrier(context.Background(), c, ctxwire.Both)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+}
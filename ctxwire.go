@@ -1,70 +1,30 @@
 // Package ctxwire propagate context values between HTTP requests and responses
 // over the wire using HTTP headers.
+// Propagators operate on carriers, so that the same propagators can be used
+// with other transports than HTTP.
 package ctxwire
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"net/http"
-	"sync"
 )
 
-// Error is the error type used by the package.
-// It wraps the original error and adds a message.
-type Error struct {
-	message string
-	err     error
-}
-
-var _ error = (*Error)(nil)
-
-// Error implements the error interface.
-func (e *Error) Error() string {
-	return fmt.Sprintf("%s: %s", e.message, e.err.Error())
-}
-
-// Unwrap implements the errors.Wrapper interface.
-func (e *Error) Unwrap() error {
-	return e.err
-}
-
-func newError(message string, err error) error {
-	var ctxwireErr *Error
-	if errors.As(err, &ctxwireErr) {
-		return err
-	}
-	return &Error{message: message, err: err}
-}
-
-// propagator propagates context values between requests and responses.
+// Propagator propagates context values between requests and responses.
 type Propagator interface {
-	// Inject injects the context values into the given headers.
-	Inject(ctx context.Context, h http.Header) error
-	// Extract extracts the context values from the given headers into a copy of
+	// Inject injects the context values into the given carrier.
+	Inject(ctx context.Context, c Carrier) error
+	// Extract extracts the context values from the given carrier into a copy of
 	// the given context.
-	Extract(ctx context.Context, h http.Header) (context.Context, error)
-}
-
-// NewValuePropagator returns a new ValuePropagator with the given name.
-// The context key is used to store the context value in the context.
-// The encoder and decoder are used to encode and decode the context value.
-func NewValuePropagator(name string, contextKey any, encoder Encoder, decoder Decoder) *ValuePropagator {
-	return &ValuePropagator{
-		name:       name,
-		contextKey: contextKey,
-		encoder:    encoder,
-		decoder:    decoder,
-	}
+	Extract(ctx context.Context, c Carrier) (context.Context, error)
 }
 
 // NewJSONPropagator returns a new ValuePropagator with the given name configured
 // to encode and decode the context value as JSON.
 // The context key is used to store the context value in the context.
-func NewJSONPropagator(name string, contextKey any) *ValuePropagator {
-	return NewValuePropagator(name, contextKey, EncoderFunc(encodeJSON), DecoderFunc(decodeJSON))
+// It is equivalent to NewPropagator.
+func NewJSONPropagator(name string, contextKey any, opts ...PropagatorOption) *ValuePropagator {
+	return NewPropagator(name, contextKey, opts...)
 }
 
 func encodeJSON(ctx context.Context, key any) ([]byte, error) {
@@ -83,64 +43,46 @@ func decodeJSON(ctx context.Context, key any, data []byte) (context.Context, err
 	return context.WithValue(ctx, key, v), nil
 }
 
-// ValuePropagator propagates a single context value between requests and responses.
-// It implements the Propagator interface.
-type ValuePropagator struct {
-	name       string
-	contextKey any
-	encoder    Encoder
-	decoder    Decoder
+// NewTypedJSONPropagator returns a new ValuePropagator with the given name
+// configured to encode and decode the context value as JSON.
+// Unlike NewJSONPropagator, the value is decoded into a T, so that extracted
+// values can be type-asserted to T by the receiver.
+func NewTypedJSONPropagator[T any](name string, contextKey any, opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{WithDecoder(DecoderFunc(decodeTypedJSON[T]))}, opts...)
+	return NewPropagator(name, contextKey, opts...)
 }
 
-var _ Propagator = (*ValuePropagator)(nil)
-
-// Inject implements the Propagator interface.
-func (p *ValuePropagator) Inject(ctx context.Context, h http.Header) error {
-	data, err := p.encoder.Encode(ctx, p.contextKey)
-	if err != nil {
-		return newError("encode context value", err)
-	}
-	if len(data) == 0 {
-		return nil
-	}
-	h.Set(headerKey(p.name), base64.StdEncoding.EncodeToString(data))
-	return nil
-}
-
-// Extract implements the Propagator interface.
-func (p *ValuePropagator) Extract(ctx context.Context, h http.Header) (context.Context, error) {
-	vStr := h.Get(headerKey(p.name))
-	if vStr == "" {
-		return ctx, nil
-	}
-	v, err := base64.StdEncoding.DecodeString(vStr)
-	if err != nil {
-		return nil, newError("base64 decode context value", err)
-	}
-	newCtx, err := p.decoder.Decode(ctx, p.contextKey, v)
-	if err != nil {
-		return nil, newError("decode context value", err)
+func decodeTypedJSON[T any](ctx context.Context, key any, data []byte) (context.Context, error) {
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
 	}
-	return newCtx, nil
+	return context.WithValue(ctx, key, v), nil
 }
 
-func headerKey(name string) string { return "x-ctxwire-" + name }
-
 // Encoder is an interface for encoding context values into bytes.
-// Errors returned by the encoder should be wrapped with ctxwire.NewError.
+// Errors returned by the encoder are wrapped into an *Error by the propagator.
 type Encoder interface {
 	// Encode encodes the context value associated with the given key into bytes.
 	Encode(ctx context.Context, key any) (data []byte, err error)
 }
 
 // Decoder is an interface for decoding bytes into context values.
-// Errors returned by the encoder should be wrapped with ctxwire.NewError.
+// Errors returned by the decoder are wrapped into an *Error by the propagator.
 type Decoder interface {
 	// Decode decodes the given data into a context value associated with the
 	// given key and returns a new context with the value set.
 	Decode(ctx context.Context, key any, data []byte) (context.Context, error)
 }
 
+// Merger is an interface for merging a context value extracted from the wire
+// into the value already present in the context.
+type Merger interface {
+	// Merge returns the value resulting from merging the incoming value into the
+	// existing one. Both values are non-nil.
+	Merge(existing, incoming any) (any, error)
+}
+
 // EncoderFunc is an adapter type to allow the use of ordinary functions as encoders.
 type EncoderFunc func(ctx context.Context, key any) ([]byte, error)
 
@@ -157,67 +99,110 @@ func (f DecoderFunc) Decode(ctx context.Context, key any, data []byte) (context.
 	return f(ctx, key, data)
 }
 
+// MergerFunc is an adapter type to allow the use of ordinary functions as mergers.
+type MergerFunc func(existing, incoming any) (any, error)
+
+// Merge implements the Merger interface.
+func (f MergerFunc) Merge(existing, incoming any) (any, error) {
+	return f(existing, incoming)
+}
+
 // Configure configures the propagators to be used to propagate context values
 // between requests and responses.
+// The propagators are added to the default registry, replacing the
+// propagators previously configured with the same name.
+// It panics if the propagators cannot be configured.
 func Configure(propagators ...Propagator) {
-	register.add(propagators...)
+	if err := defaultRegistry.Configure(propagators...); err != nil {
+		panic(err)
+	}
+}
+
+// Unregister removes the propagators with the given name from the default
+// registry.
+func Unregister(name string) {
+	defaultRegistry.Unregister(name)
 }
 
-// Inject injects the context values into the given headers.
+// Propagators returns a description of the propagators of the default
+// registry, in the order they run.
+func Propagators() []PropagatorInfo {
+	return defaultRegistry.Propagators()
+}
+
+// Reset removes all the propagators from the default registry.
+func Reset() {
+	defaultRegistry.Reset()
+}
+
+// Inject injects the context values into the given headers using the
+// propagators of the default registry.
 func Inject(ctx context.Context, h http.Header) error {
-	if err := register.Inject(ctx, h); err != nil {
-		return newError("inject context values", err)
-	}
-	return nil
+	return defaultRegistry.Inject(ctx, h)
+}
+
+// InjectTrailer injects the context values of the trailer propagators of the
+// default registry into the trailers of the given response writer.
+func InjectTrailer(ctx context.Context, w http.ResponseWriter) error {
+	return defaultRegistry.InjectTrailer(ctx, w)
+}
+
+// InjectCarrier injects the context values into the given carrier using the
+// propagators of the default registry propagating values in the given
+// direction.
+func InjectCarrier(ctx context.Context, c Carrier, dir Direction) error {
+	return defaultRegistry.InjectCarrier(ctx, c, dir)
+}
+
+// ExtractCarrier extracts the context values from the given carrier into a copy
+// of the given context using the propagators of the default registry
+// propagating values in the given direction.
+func ExtractCarrier(ctx context.Context, c Carrier, dir Direction) (context.Context, error) {
+	return defaultRegistry.ExtractCarrier(ctx, c, dir)
+}
+
+// Preview returns the headers that Inject would inject for the given context
+// using the propagators of the default registry. It is useful for logging,
+// size estimation and debugging.
+func Preview(ctx context.Context) (http.Header, error) {
+	return defaultRegistry.Preview(ctx)
 }
 
 // Extract extracts the context values from the given headers into a copy of
-// the given context.
+// the given context using the propagators of the default registry.
 func Extract(ctx context.Context, h http.Header) (context.Context, error) {
-	newCtx, err := register.Extract(ctx, h)
-	if err != nil {
-		return nil, newError("extract context values", err)
-	}
-	return newCtx, nil
+	return defaultRegistry.Extract(ctx, h)
 }
 
-var register propagatorRegister
+// ExtractWithReport extracts the context values from the given headers into a
+// copy of the given context using the propagators of the default registry.
+// It also returns a report describing which propagators found their headers.
+func ExtractWithReport(ctx context.Context, h http.Header) (context.Context, *ExtractReport, error) {
+	return defaultRegistry.ExtractWithReport(ctx, h)
+}
 
-type propagatorRegister struct {
-	mu          sync.Mutex
-	propagators []Propagator
+// InjectRequest injects the context values into the given request headers
+// using the propagators of the default registry, except the ResponseOnly ones.
+func InjectRequest(ctx context.Context, h http.Header) error {
+	return defaultRegistry.InjectRequest(ctx, h)
 }
 
-var _ Propagator = (*propagatorRegister)(nil)
+// ExtractRequest extracts the context values from the given request headers
+// into a copy of the given context using the propagators of the default
+// registry, except the ResponseOnly ones.
+func ExtractRequest(ctx context.Context, h http.Header) (context.Context, error) {
+	return defaultRegistry.ExtractRequest(ctx, h)
+}
 
-func (r *propagatorRegister) add(propagators ...Propagator) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.propagators = append(r.propagators, propagators...)
+// InjectResponse injects the context values into the given response headers
+// using the propagators of the default registry, except the RequestOnly ones.
+func InjectResponse(ctx context.Context, h http.Header) error {
+	return defaultRegistry.InjectResponse(ctx, h)
 }
 
-// Inject implements the Propagator interface.
-func (r *propagatorRegister) Inject(ctx context.Context, h http.Header) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	for _, p := range r.propagators {
-		if err := p.Inject(ctx, h); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// Extract implements the Propagator interface.
-func (r *propagatorRegister) Extract(ctx context.Context, h http.Header) (context.Context, error) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	for _, p := range r.propagators {
-		var err error
-		ctx, err = p.Extract(ctx, h)
-		if err != nil {
-			return nil, err
-		}
-	}
-	return ctx, nil
+// ExtractResponse extracts the context values from the given response headers
+// into a copy of the given context using the propagators of the default
+// registry, except the RequestOnly ones.
+func ExtractResponse(ctx context.Context, h http.Header) (context.Context, error) {
+	return defaultRegistry.ExtractResponse(ctx, h)
 }
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -28,10 +29,12 @@ var (
 func TestBackPropagation(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(logProducerHandler))
 	t.Cleanup(server.Close)
+	t.Cleanup(ctxwire.Reset)
 
 	ctxwire.Configure(
 		ctxwire.NewJSONPropagator("str", keyStr),
 		ctxwire.NewJSONPropagator("int", keyInt),
+		//nolint:staticcheck // The deprecated constructor must keep working.
 		ctxwire.NewValuePropagator("log", keyLog,
 			ctxwire.EncoderFunc(logEncoder),
 			ctxwire.DecoderFunc(logDecoder),
@@ -149,10 +152,13 @@ var (
 )
 
 func TestError(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
 	ctxwire.Configure(
+		//nolint:staticcheck // The deprecated constructor must keep working.
 		ctxwire.NewValuePropagator("encode", keyEncode,
 			ctxwire.EncoderFunc(errEncoder),
 			ctxwire.DecoderFunc(ctxwire.DecodeJSON)),
+		//nolint:staticcheck // The deprecated constructor must keep working.
 		ctxwire.NewValuePropagator("decode", keyDecode,
 			ctxwire.EncoderFunc(ctxwire.EncodeJSON),
 			ctxwire.DecoderFunc(errDecoder)),
@@ -169,6 +175,47 @@ func TestError(t *testing.T) {
 	require.EqualError(t, err, "decode context value: failed!")
 }
 
+func TestNewPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("log", keyLog,
+		ctxwire.WithEncoder(ctxwire.EncoderFunc(logEncoder)),
+		ctxwire.WithDecoder(ctxwire.DecoderFunc(logDecoder)),
+	)))
+
+	want := logState{attrs: []logAttr{logWithService("search")}}
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyLog, want), h))
+	require.Equal(t, "eyJzZXJ2aWNlIjoic2VhcmNoIn0=", h.Get("x-ctxwire-log"))
+	// The decoder appends the received entries to the ones of the context.
+	ctx, err := r.Extract(context.WithValue(context.Background(), keyLog, logState{}), h)
+	require.NoError(t, err)
+	require.Len(t, ctx.Value(keyLog).(logState).attrs, 1)
+
+	// Propagators without codec options encode their values as JSON.
+	p := ctxwire.NewPropagator("str", keyStr)
+	h = http.Header{}
+	require.NoError(t, p.Inject(context.WithValue(context.Background(), keyStr, "foo"), ctxwire.HeaderCarrier(h)))
+	ctx, err = p.Extract(context.Background(), ctxwire.HeaderCarrier(h))
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+}
+
+func TestNewPropagatorError(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewPropagator("encode", keyEncode, ctxwire.WithEncoder(ctxwire.EncoderFunc(errEncoder))),
+		ctxwire.NewPropagator("decode", keyDecode, ctxwire.WithDecoder(ctxwire.DecoderFunc(errDecoder))),
+	))
+
+	h := http.Header{}
+	err := r.Inject(context.WithValue(context.Background(), keyEncode, "foo"), h)
+	require.EqualError(t, err, "encode context value: failed!")
+
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyDecode, "bar"), h))
+	_, err = r.Extract(context.Background(), h)
+	require.EqualError(t, err, "decode context value: failed!")
+}
+
 func errEncoder(ctx context.Context, key any) ([]byte, error) {
 	v := ctx.Value(key)
 	if v == nil {
@@ -180,3 +227,169 @@ func errEncoder(ctx context.Context, key any) ([]byte, error) {
 func errDecoder(_ context.Context, _ any, _ []byte) (context.Context, error) {
 	return nil, errors.New("failed!")
 }
+
+type (
+	userKey struct{}
+	user    struct {
+		ID    int      `json:"id"`
+		Name  string   `json:"name"`
+		Roles []string `json:"roles"`
+	}
+)
+
+func TestTypedJSONPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewTypedJSONPropagator[user]("user", userKey{})))
+
+	want := user{ID: 1, Name: "alice", Roles: []string{"admin"}}
+	ctx := context.WithValue(context.Background(), userKey{}, want)
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	got, ok := ctx.Value(userKey{}).(user)
+	require.True(t, ok)
+	require.Equal(t, want, got)
+
+	h.Set("x-ctxwire-user", "WzFd") // base64 of "[1]"
+	_, err = r.Extract(context.Background(), h)
+	require.Error(t, err)
+}
+
+type tagsKey struct{}
+
+func TestMerger(t *testing.T) {
+	mergeTags := ctxwire.MergerFunc(func(existing, incoming any) (any, error) {
+		return append(slices.Clone(existing.([]string)), incoming.([]string)...), nil
+	})
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewTypedJSONPropagator[[]string]("tags", tagsKey{}, ctxwire.WithMerger(mergeTags)),
+	))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), tagsKey{}, []string{"b", "c"}), h))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "c"}, ctx.Value(tagsKey{}))
+
+	ctx, err = r.Extract(context.WithValue(context.Background(), tagsKey{}, []string{"a"}), h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, ctx.Value(tagsKey{}))
+
+	failing := ctxwire.MergerFunc(func(_, _ any) (any, error) { return nil, errors.New("failed!") })
+	require.NoError(t, r.Configure(
+		ctxwire.NewTypedJSONPropagator[[]string]("tags", tagsKey{}, ctxwire.WithMerger(failing)),
+	))
+	_, err = r.Extract(context.WithValue(context.Background(), tagsKey{}, []string{"a"}), h)
+	require.EqualError(t, err, "merge context value: failed!")
+}
+
+type hopsKey struct{}
+
+func TestMultiValue(t *testing.T) {
+	appendHops := ctxwire.MergerFunc(func(existing, incoming any) (any, error) {
+		return append(slices.Clone(existing.([]string)), incoming.([]string)...), nil
+	})
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewTypedJSONPropagator[[]string]("hops", hopsKey{},
+		ctxwire.WithMultiValue(), ctxwire.WithMerger(appendHops))))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), hopsKey{}, []string{"gateway"}), h))
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), hopsKey{}, []string{"search"}), h))
+	require.Len(t, h.Values("x-ctxwire-hops"), 2)
+
+	ctx, err := r.Extract(context.WithValue(context.Background(), hopsKey{}, []string{"client"}), h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"client", "gateway", "search"}, ctx.Value(hopsKey{}))
+
+	// Carriers without multi-value support only keep the last value.
+	c := mapCarrier{}
+	require.NoError(t, r.InjectCarrier(context.WithValue(context.Background(), hopsKey{}, []string{"gateway"}), c, ctxwire.Both))
+	require.NoError(t, r.InjectCarrier(context.WithValue(context.Background(), hopsKey{}, []string{"search"}), c, ctxwire.Both))
+	ctx, err = r.ExtractCarrier(context.Background(), c, ctxwire.Both)
+	require.NoError(t, err)
+	require.Equal(t, []string{"search"}, ctx.Value(hopsKey{}))
+}
+
+func TestNoOverwrite(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithNoOverwrite())))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "incoming"), h))
+
+	ctx, err := r.Extract(context.WithValue(context.Background(), keyStr, "local"), h)
+	require.NoError(t, err)
+	require.Equal(t, "local", ctx.Value(keyStr))
+
+	ctx, err = r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "incoming", ctx.Value(keyStr))
+}
+
+func TestNewValuePropagator(t *testing.T) {
+	//nolint:staticcheck // The deprecated constructor must keep working.
+	p := ctxwire.NewValuePropagator("log", keyLog,
+		ctxwire.EncoderFunc(logEncoder),
+		ctxwire.DecoderFunc(logDecoder),
+		ctxwire.WithPriority(1),
+	)
+	require.Equal(t, "log", p.Name())
+	require.Equal(t, 1, p.Priority())
+
+	ctx := context.WithValue(context.Background(), keyLog, logState{attrs: []logAttr{logWithService("search")}})
+	h := http.Header{}
+	require.NoError(t, p.Inject(ctx, ctxwire.HeaderCarrier(h)))
+	require.Equal(t, "eyJzZXJ2aWNlIjoic2VhcmNoIn0=", h.Get("x-ctxwire-log"))
+}
+
+func TestChunkSize(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("str", keyStr, ctxwire.WithChunkSize(4))))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "abcdefghij"), h))
+	require.Equal(t, http.Header{
+		"X-Ctxwire-Str-1": {"abcd"},
+		"X-Ctxwire-Str-2": {"efgh"},
+		"X-Ctxwire-Str-3": {"ij"},
+	}, h)
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "abcdefghij", ctx.Value(keyStr))
+
+	// Values fitting in a single header are not chunked.
+	h = http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "abc"), h))
+	require.Equal(t, http.Header{"X-Ctxwire-Str": {"abc"}}, h)
+	ctx, err = r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "abc", ctx.Value(keyStr))
+
+	err = r.Inject(context.WithValue(context.Background(), keyStr, strings.Repeat("a", 4*32+1)), http.Header{})
+	require.EqualError(t, err, "encode context value: value needs 33 chunks, exceeding the maximum of 32")
+}
+
+func TestMaxEncodedSize(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithMaxEncodedSize(8))))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "foo"), h))
+	require.Equal(t, "ImZvbyI=", h.Get("x-ctxwire-str"))
+
+	err := r.Inject(context.WithValue(context.Background(), keyStr, "foobar"), http.Header{})
+	require.ErrorIs(t, err, ctxwire.ErrValueTooLarge)
+	require.EqualError(t, err, "encode context value: value too large: exceeds the limit of 8 bytes")
+
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr,
+		ctxwire.WithMaxEncodedSize(8), ctxwire.WithSizePolicy(ctxwire.DropOversized))))
+	h = http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "foobar"), h))
+	require.Empty(t, h)
+}
@@ -0,0 +1,82 @@
+// Package ctxwireamqp propagates ctxwire context values through AMQP 0-9-1
+// brokers such as RabbitMQ. Publishers inject the values into the headers of
+// their messages, and consumers extract them from the deliveries, so that the
+// values survive the hop through queues.
+// Messages only flow from publishers to consumers, so the ResponseOnly
+// propagators don't run.
+package ctxwireamqp
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/trezz/ctxwire"
+)
+
+// TableCarrier adapts amqp.Table headers to the ctxwire.Carrier interface.
+// Keys are lowercase. Values are set as strings, and byte slice values are
+// read as strings too.
+type TableCarrier amqp.Table
+
+var _ ctxwire.Carrier = TableCarrier(nil)
+
+// Get implements the ctxwire.Carrier interface.
+func (c TableCarrier) Get(key string) string {
+	switch v := c[strings.ToLower(key)].(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return ""
+	}
+}
+
+// Set implements the ctxwire.Carrier interface.
+func (c TableCarrier) Set(key, value string) { c[strings.ToLower(key)] = value }
+
+// Keys implements the ctxwire.Carrier interface.
+func (c TableCarrier) Keys() []string { return slices.Collect(maps.Keys(c)) }
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+}
+
+// WithRegistry makes the helpers propagate the values of the given registry
+// in the message headers. Defaults to ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// InjectPublishing injects the context values into the headers of the given
+// message before it is published, allocating the headers if needed.
+func InjectPublishing(ctx context.Context, msg *amqp.Publishing, opts ...Option) error {
+	if msg.Headers == nil {
+		msg.Headers = amqp.Table{}
+	}
+	return newConfig(opts).registry.InjectCarrier(ctx, TableCarrier(msg.Headers), ctxwire.RequestOnly)
+}
+
+// ExtractDelivery extracts the context values from the headers of the given
+// delivery into a copy of the given context.
+func ExtractDelivery(ctx context.Context, d *amqp.Delivery, opts ...Option) (context.Context, error) {
+	c := TableCarrier(d.Headers)
+	if c == nil {
+		c = TableCarrier{}
+	}
+	return newConfig(opts).registry.ExtractCarrier(ctx, c, ctxwire.RequestOnly)
+}
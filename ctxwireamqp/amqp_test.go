@@ -0,0 +1,47 @@
+package ctxwireamqp_test
+
+import (
+	"context"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwireamqp"
+)
+
+type userKey struct{}
+
+type sessionKey struct{}
+
+func TestPublishingDelivery(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", userKey{}),
+		ctxwire.NewStringPropagator("session", sessionKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	ctx = context.WithValue(ctx, sessionKey{}, "s1")
+	msg := amqp.Publishing{Body: []byte("hello")}
+	require.NoError(t, ctxwireamqp.InjectPublishing(ctx, &msg, ctxwireamqp.WithRegistry(r)))
+	require.Equal(t, amqp.Table{"x-ctxwire-user": "alice"}, msg.Headers)
+	require.NoError(t, msg.Headers.Validate())
+
+	d := amqp.Delivery{Headers: amqp.Table{"x-ctxwire-user": []byte("bob")}}
+	got, err := ctxwireamqp.ExtractDelivery(context.Background(), &d, ctxwireamqp.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "bob", got.Value(userKey{}))
+
+	got, err = ctxwireamqp.ExtractDelivery(context.Background(), &amqp.Delivery{}, ctxwireamqp.WithRegistry(r))
+	require.NoError(t, err)
+	require.Nil(t, got.Value(userKey{}))
+}
+
+func TestTableCarrier(t *testing.T) {
+	c := ctxwireamqp.TableCarrier{"x-ctxwire-n": int32(1)}
+	require.Empty(t, c.Get("x-ctxwire-n"))
+	c.Set("X-Ctxwire-A", "1")
+	require.Equal(t, "1", c.Get("x-ctxwire-a"))
+	require.ElementsMatch(t, []string{"x-ctxwire-a", "x-ctxwire-n"}, c.Keys())
+}
@@ -0,0 +1,116 @@
+// Package ctxwireasynq propagates ctxwire context values into Asynq
+// background tasks, so that jobs inherit the context of the requests which
+// enqueued them, such as tenants, request IDs or logging fields.
+//
+// Since Asynq tasks have no metadata besides their payload, Wrap wraps the
+// payload into an envelope holding the values when enqueuing tasks, and the
+// middleware returned by Middleware extracts them into the context of the
+// handlers, which read the original payload with Payload.
+// Tasks only flow from clients to handlers, so the ResponseOnly propagators
+// don't run.
+package ctxwireasynq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/trezz/ctxwire"
+)
+
+// magic prefixes the payloads wrapped by Wrap, followed by the values as a
+// JSON object on a single line.
+var magic = []byte("ctxwire:")
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+}
+
+// WithRegistry makes Wrap and the middleware carry the values of the given
+// registry in the task envelopes, instead of the values of
+// ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Wrap returns the given task payload wrapped into an envelope holding the
+// context values.
+//
+//	payload, err := ctxwireasynq.Wrap(ctx, data)
+//	task := asynq.NewTask("email:send", payload)
+func Wrap(ctx context.Context, payload []byte, opts ...Option) ([]byte, error) {
+	c := ctxwire.MapCarrier{}
+	if err := newConfig(opts).registry.InjectCarrier(ctx, c, ctxwire.RequestOnly); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(map[string]string(c))
+	if err != nil {
+		return nil, fmt.Errorf("marshal task envelope: %w", err)
+	}
+	wrapped := make([]byte, 0, len(magic)+len(data)+1+len(payload))
+	wrapped = append(wrapped, magic...)
+	wrapped = append(wrapped, data...)
+	wrapped = append(wrapped, '\n')
+	return append(wrapped, payload...), nil
+}
+
+// unwrap returns the values and the original payload of the given payload.
+// Payloads which are not wrapped are returned as is.
+func unwrap(payload []byte) (ctxwire.MapCarrier, []byte, error) {
+	rest, ok := bytes.CutPrefix(payload, magic)
+	if !ok {
+		return nil, payload, nil
+	}
+	data, payload, ok := bytes.Cut(rest, []byte{'\n'})
+	if !ok {
+		return nil, nil, errors.New("unterminated task envelope")
+	}
+	c := ctxwire.MapCarrier{}
+	if err := json.Unmarshal(data, (*map[string]string)(&c)); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal task envelope: %w", err)
+	}
+	return c, payload, nil
+}
+
+// Payload returns the original payload of the given task, unwrapping it if it
+// was wrapped with Wrap. Invalid envelopes are rejected by the middleware
+// before the handlers run.
+func Payload(t *asynq.Task) []byte {
+	_, payload, _ := unwrap(t.Payload())
+	return payload
+}
+
+// Middleware returns an asynq.MiddlewareFunc extracting the context values of
+// the task payloads wrapped with Wrap into the context of the handlers. Tasks
+// with invalid envelopes or values fail without being retried.
+func Middleware(opts ...Option) asynq.MiddlewareFunc {
+	cfg := newConfig(opts)
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			c, _, err := unwrap(t.Payload())
+			if err != nil {
+				return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+			}
+			if c != nil {
+				if ctx, err = cfg.registry.ExtractCarrier(ctx, c, ctxwire.RequestOnly); err != nil {
+					return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+				}
+			}
+			return next.ProcessTask(ctx, t)
+		})
+	}
+}
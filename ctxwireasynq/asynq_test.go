@@ -0,0 +1,44 @@
+package ctxwireasynq_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hibiken/asynq"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwireasynq"
+)
+
+type tenantKey struct{}
+
+func TestMiddleware(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("tenant", tenantKey{})))
+
+	payload, err := ctxwireasynq.Wrap(context.WithValue(context.Background(), tenantKey{}, "acme"), []byte(`{"to":"a@b.c"}`),
+		ctxwireasynq.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "ctxwire:{\"x-ctxwire-tenant\":\"acme\"}\n{\"to\":\"a@b.c\"}", string(payload))
+
+	var tenant any
+	var data []byte
+	h := ctxwireasynq.Middleware(ctxwireasynq.WithRegistry(r))(asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+		tenant, data = ctx.Value(tenantKey{}), ctxwireasynq.Payload(t)
+		return nil
+	}))
+	require.NoError(t, h.ProcessTask(context.Background(), asynq.NewTask("email:send", payload)))
+	require.Equal(t, "acme", tenant)
+	require.Equal(t, `{"to":"a@b.c"}`, string(data))
+
+	// Tasks enqueued without values are processed as is.
+	require.NoError(t, h.ProcessTask(context.Background(), asynq.NewTask("email:send", []byte("raw"))))
+	require.Nil(t, tenant)
+	require.Equal(t, "raw", string(data))
+
+	err = h.ProcessTask(context.Background(), asynq.NewTask("email:send", []byte("ctxwire:{")))
+	require.ErrorIs(t, err, asynq.SkipRetry)
+	require.ErrorContains(t, err, "unterminated task envelope")
+	err = h.ProcessTask(context.Background(), asynq.NewTask("email:send", []byte("ctxwire:{\n")))
+	require.ErrorContains(t, err, "unmarshal task envelope")
+}
@@ -0,0 +1,205 @@
+// Package ctxwireaws propagates ctxwire context values through AWS SQS queues
+// and SNS topics in message attributes, so that workers and Lambda functions
+// consuming the messages can reconstruct the context of the originating
+// request.
+// Values are set as attributes of the String data type, holding the header
+// values of the propagators, base64-encoded by default. Since SQS and SNS
+// limit messages to 10 attributes, registries propagating many values should
+// be configured with ctxwire.WithEnvelope.
+// Messages only flow from producers to consumers, so the ResponseOnly
+// propagators don't run.
+package ctxwireaws
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/trezz/ctxwire"
+)
+
+// stringType is the data type of the attributes set by the carriers.
+const stringType = "String"
+
+// isString reports whether the given data type is String or one of its custom
+// types.
+func isString(dataType string) bool {
+	return dataType == stringType || strings.HasPrefix(dataType, stringType+".")
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// SQSCarrier adapts the message attributes of the SQS API to the
+// ctxwire.Carrier interface. Keys are lowercase.
+type SQSCarrier map[string]sqstypes.MessageAttributeValue
+
+var _ ctxwire.Carrier = SQSCarrier(nil)
+
+// Get implements the ctxwire.Carrier interface.
+func (c SQSCarrier) Get(key string) string {
+	v, ok := c[strings.ToLower(key)]
+	if !ok || !isString(deref(v.DataType)) {
+		return ""
+	}
+	return deref(v.StringValue)
+}
+
+// Set implements the ctxwire.Carrier interface.
+func (c SQSCarrier) Set(key, value string) {
+	dataType := stringType
+	c[strings.ToLower(key)] = sqstypes.MessageAttributeValue{DataType: &dataType, StringValue: &value}
+}
+
+// Keys implements the ctxwire.Carrier interface.
+func (c SQSCarrier) Keys() []string { return slices.Collect(maps.Keys(c)) }
+
+// SNSCarrier adapts the message attributes of the SNS API to the
+// ctxwire.Carrier interface. Keys are lowercase.
+type SNSCarrier map[string]snstypes.MessageAttributeValue
+
+var _ ctxwire.Carrier = SNSCarrier(nil)
+
+// Get implements the ctxwire.Carrier interface.
+func (c SNSCarrier) Get(key string) string {
+	v, ok := c[strings.ToLower(key)]
+	if !ok || !isString(deref(v.DataType)) {
+		return ""
+	}
+	return deref(v.StringValue)
+}
+
+// Set implements the ctxwire.Carrier interface.
+func (c SNSCarrier) Set(key, value string) {
+	dataType := stringType
+	c[strings.ToLower(key)] = snstypes.MessageAttributeValue{DataType: &dataType, StringValue: &value}
+}
+
+// Keys implements the ctxwire.Carrier interface.
+func (c SNSCarrier) Keys() []string { return slices.Collect(maps.Keys(c)) }
+
+// SQSEventCarrier adapts the message attributes of the SQS events received
+// by Lambda functions to the ctxwire.Carrier interface. Keys are lowercase.
+type SQSEventCarrier map[string]events.SQSMessageAttribute
+
+var _ ctxwire.Carrier = SQSEventCarrier(nil)
+
+// Get implements the ctxwire.Carrier interface.
+func (c SQSEventCarrier) Get(key string) string {
+	v, ok := c[strings.ToLower(key)]
+	if !ok || !isString(v.DataType) {
+		return ""
+	}
+	return deref(v.StringValue)
+}
+
+// Set implements the ctxwire.Carrier interface.
+func (c SQSEventCarrier) Set(key, value string) {
+	c[strings.ToLower(key)] = events.SQSMessageAttribute{DataType: stringType, StringValue: &value}
+}
+
+// Keys implements the ctxwire.Carrier interface.
+func (c SQSEventCarrier) Keys() []string { return slices.Collect(maps.Keys(c)) }
+
+// SNSEventCarrier adapts the message attributes of the SNS events received by
+// Lambda functions to the ctxwire.Carrier interface. Attributes are objects
+// holding a "Type" and a "Value". Keys are lowercase.
+type SNSEventCarrier map[string]any
+
+var _ ctxwire.Carrier = SNSEventCarrier(nil)
+
+// Get implements the ctxwire.Carrier interface.
+func (c SNSEventCarrier) Get(key string) string {
+	attr, _ := c[strings.ToLower(key)].(map[string]any)
+	if dataType, _ := attr["Type"].(string); !isString(dataType) {
+		return ""
+	}
+	v, _ := attr["Value"].(string)
+	return v
+}
+
+// Set implements the ctxwire.Carrier interface.
+func (c SNSEventCarrier) Set(key, value string) {
+	c[strings.ToLower(key)] = map[string]any{"Type": stringType, "Value": value}
+}
+
+// Keys implements the ctxwire.Carrier interface.
+func (c SNSEventCarrier) Keys() []string { return slices.Collect(maps.Keys(c)) }
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+}
+
+// WithRegistry makes the helpers propagate the values of the given registry
+// in the message attributes, instead of the values of
+// ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func inject(ctx context.Context, c ctxwire.Carrier, opts []Option) error {
+	return newConfig(opts).registry.InjectCarrier(ctx, c, ctxwire.RequestOnly)
+}
+
+func extract(ctx context.Context, c ctxwire.Carrier, opts []Option) (context.Context, error) {
+	return newConfig(opts).registry.ExtractCarrier(ctx, c, ctxwire.RequestOnly)
+}
+
+// InjectSendMessage injects the context values into the message attributes of
+// the given SQS input, allocating them if needed.
+func InjectSendMessage(ctx context.Context, in *sqs.SendMessageInput, opts ...Option) error {
+	if in.MessageAttributes == nil {
+		in.MessageAttributes = map[string]sqstypes.MessageAttributeValue{}
+	}
+	return inject(ctx, SQSCarrier(in.MessageAttributes), opts)
+}
+
+// InjectPublish injects the context values into the message attributes of the
+// given SNS input, allocating them if needed. The attributes are delivered to
+// SQS subscribers with the message.
+func InjectPublish(ctx context.Context, in *sns.PublishInput, opts ...Option) error {
+	if in.MessageAttributes == nil {
+		in.MessageAttributes = map[string]snstypes.MessageAttributeValue{}
+	}
+	return inject(ctx, SNSCarrier(in.MessageAttributes), opts)
+}
+
+// ExtractMessage extracts the context values from the message attributes of
+// the given SQS message into a copy of the given context. Messages must be
+// received with their message attributes.
+func ExtractMessage(ctx context.Context, m *sqstypes.Message, opts ...Option) (context.Context, error) {
+	return extract(ctx, SQSCarrier(m.MessageAttributes), opts)
+}
+
+// ExtractSQSEvent extracts the context values from the message attributes of
+// the given SQS event message into a copy of the given context.
+func ExtractSQSEvent(ctx context.Context, m *events.SQSMessage, opts ...Option) (context.Context, error) {
+	return extract(ctx, SQSEventCarrier(m.MessageAttributes), opts)
+}
+
+// ExtractSNSEvent extracts the context values from the message attributes of
+// the given SNS event message into a copy of the given context.
+func ExtractSNSEvent(ctx context.Context, m *events.SNSEntity, opts ...Option) (context.Context, error) {
+	return extract(ctx, SNSEventCarrier(m.MessageAttributes), opts)
+}
@@ -0,0 +1,99 @@
+package ctxwireaws_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwireaws"
+)
+
+type (
+	userKey    struct{}
+	requestKey struct{}
+	tenantKey  struct{}
+)
+
+func TestSQS(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("user", userKey{}),
+		ctxwire.NewStringPropagator("request", requestKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	ctx = context.WithValue(ctx, requestKey{}, "r1")
+
+	// Messages only flow to the consumers, so the response-only values are
+	// not sent, and the attributes of the producer are kept.
+	dataType, origin := "String", "checkout"
+	in := sqs.SendMessageInput{MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+		"origin": {DataType: &dataType, StringValue: &origin},
+	}}
+	require.NoError(t, ctxwireaws.InjectSendMessage(ctx, &in, ctxwireaws.WithRegistry(r)))
+	require.Len(t, in.MessageAttributes, 2)
+	attr := in.MessageAttributes["x-ctxwire-user"]
+	require.Equal(t, "String", *attr.DataType)
+	require.Equal(t, "ImFsaWNlIg==", *attr.StringValue)
+
+	m := sqstypes.Message{MessageAttributes: in.MessageAttributes}
+	got, err := ctxwireaws.ExtractMessage(context.Background(), &m, ctxwireaws.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "alice", got.Value(userKey{}))
+
+	// Binary attributes are ignored.
+	binary := "Binary"
+	m.MessageAttributes["x-ctxwire-user"] = sqstypes.MessageAttributeValue{DataType: &binary, BinaryValue: []byte("x")}
+	got, err = ctxwireaws.ExtractMessage(context.Background(), &m, ctxwireaws.WithRegistry(r))
+	require.NoError(t, err)
+	require.Nil(t, got.Value(userKey{}))
+}
+
+func TestSNS(t *testing.T) {
+	// Registries propagating many values fit in a single attribute with an
+	// envelope.
+	r := ctxwire.NewRegistry(ctxwire.WithEnvelope())
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("user", userKey{}),
+		ctxwire.NewStringPropagator("request", requestKey{}),
+		ctxwire.NewStringPropagator("tenant", tenantKey{}),
+	))
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	ctx = context.WithValue(ctx, requestKey{}, "r1")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+
+	in := sns.PublishInput{}
+	require.NoError(t, ctxwireaws.InjectPublish(ctx, &in, ctxwireaws.WithRegistry(r)))
+	require.Len(t, in.MessageAttributes, 1)
+	require.Contains(t, in.MessageAttributes, "x-ctxwire")
+	require.NotEmpty(t, ctxwireaws.SNSCarrier(in.MessageAttributes).Get("X-Ctxwire"))
+
+	got, err := r.ExtractCarrier(context.Background(), ctxwireaws.SNSCarrier(in.MessageAttributes), ctxwire.RequestOnly)
+	require.NoError(t, err)
+	require.Equal(t, "alice", got.Value(userKey{}))
+	require.Equal(t, "r1", got.Value(requestKey{}))
+	require.Equal(t, "acme", got.Value(tenantKey{}))
+}
+
+func TestLambdaEvents(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("user", userKey{})))
+	var sqsEvent events.SQSEvent
+	require.NoError(t, json.Unmarshal([]byte(`{"Records":[{"messageAttributes":{
+		"x-ctxwire-user":{"stringValue":"ImFsaWNlIg==","dataType":"String"}}}]}`), &sqsEvent))
+	ctx, err := ctxwireaws.ExtractSQSEvent(context.Background(), &sqsEvent.Records[0], ctxwireaws.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "alice", ctx.Value(userKey{}))
+
+	var snsEvent events.SNSEvent
+	require.NoError(t, json.Unmarshal([]byte(`{"Records":[{"Sns":{"MessageAttributes":{
+		"x-ctxwire-user":{"Type":"String","Value":"ImJvYiI="}}}}]}`), &snsEvent))
+	ctx, err = ctxwireaws.ExtractSNSEvent(context.Background(), &snsEvent.Records[0].SNS, ctxwireaws.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "bob", ctx.Value(userKey{}))
+}
@@ -0,0 +1,183 @@
+// Package ctxwireconnect propagates ctxwire context values over Connect RPCs.
+// Clients inject the values into the request headers of their calls and
+// handlers extract them. Values set by handlers are back-propagated to
+// clients in the response headers of unary calls, in the metadata of the
+// connect.Error values they fail with, and in the response trailers of
+// streaming calls.
+package ctxwireconnect
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/trezz/ctxwire"
+)
+
+// Option configures the interceptor.
+type Option func(i *Interceptor)
+
+// WithRegistry makes the interceptor propagate the values of the given
+// registry in the headers of the RPCs, instead of the values of
+// ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(i *Interceptor) { i.registry = r }
+}
+
+// Interceptor is a connect.Interceptor propagating the context values. The
+// same interceptor can be used by clients and handlers.
+type Interceptor struct {
+	registry *ctxwire.Registry
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor returns a new Interceptor configured with the given options.
+func NewInterceptor(opts ...Option) *Interceptor {
+	i := &Interceptor{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+type responseKey struct{}
+
+// ExtractInto returns a copy of the given context making the client
+// interceptor store into dst a copy of the context of the call holding the
+// values back-propagated by the handler. The extraction happens when unary
+// calls complete, and when the stream is done for streaming calls.
+func ExtractInto(ctx context.Context, dst *context.Context) context.Context {
+	return context.WithValue(ctx, responseKey{}, dst)
+}
+
+func (i *Interceptor) inject(ctx context.Context, h http.Header, dir ctxwire.Direction) error {
+	return i.registry.InjectCarrier(ctx, ctxwire.HeaderCarrier(h), dir)
+}
+
+func (i *Interceptor) extract(ctx context.Context, h http.Header, dir ctxwire.Direction) (context.Context, error) {
+	return i.registry.ExtractCarrier(ctx, ctxwire.HeaderCarrier(h), dir)
+}
+
+// extractRequest returns a copy of the given context holding the values of the
+// given request headers, and a box recording the values to back-propagate.
+func (i *Interceptor) extractRequest(ctx context.Context, h http.Header) (context.Context, error) {
+	ctx, err := i.extract(ctx, h, ctxwire.RequestOnly)
+	if err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	return ctxwire.WithBox(ctx), nil
+}
+
+// extractResponse stores into the destination of ExtractInto, if any, the
+// values back-propagated in the given headers.
+func (i *Interceptor) extractResponse(ctx context.Context, headers ...http.Header) error {
+	dst, ok := ctx.Value(responseKey{}).(*context.Context)
+	if !ok {
+		return nil
+	}
+	for _, h := range headers {
+		var err error
+		if ctx, err = i.extract(ctx, h, ctxwire.ResponseOnly); err != nil {
+			return err
+		}
+	}
+	*dst = ctx
+	return nil
+}
+
+// WrapUnary implements the connect.Interceptor interface.
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			if err := i.inject(ctx, req.Header(), ctxwire.RequestOnly); err != nil {
+				return nil, err
+			}
+			resp, err := next(ctx, req)
+			if err != nil {
+				var connectErr *connect.Error
+				if errors.As(err, &connectErr) {
+					if extractErr := i.extractResponse(ctx, connectErr.Meta()); extractErr != nil {
+						return nil, errors.Join(err, extractErr)
+					}
+				}
+				return nil, err
+			}
+			if err := i.extractResponse(ctx, resp.Header(), resp.Trailer()); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+		ctx, err := i.extractRequest(ctx, req.Header())
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next(ctx, req)
+		if err != nil {
+			var connectErr *connect.Error
+			if errors.As(err, &connectErr) {
+				_ = i.inject(ctx, connectErr.Meta(), ctxwire.ResponseOnly)
+			}
+			return nil, err
+		}
+		if err := i.inject(ctx, resp.Header(), ctxwire.ResponseOnly); err != nil {
+			return nil, connect.NewError(connect.CodeInternal, err)
+		}
+		return resp, nil
+	}
+}
+
+// WrapStreamingClient implements the connect.Interceptor interface.
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+		return &clientConn{StreamingClientConn: conn, i: i, ctx: ctx,
+			injectErr: i.inject(ctx, conn.RequestHeader(), ctxwire.RequestOnly)}
+	}
+}
+
+type clientConn struct {
+	connect.StreamingClientConn
+	i         *Interceptor
+	ctx       context.Context
+	injectErr error
+	done      bool
+}
+
+func (c *clientConn) Send(m any) error {
+	if c.injectErr != nil {
+		return c.injectErr
+	}
+	return c.StreamingClientConn.Send(m)
+}
+
+func (c *clientConn) Receive(m any) error {
+	if c.injectErr != nil {
+		return c.injectErr
+	}
+	err := c.StreamingClientConn.Receive(m)
+	if err == nil || c.done {
+		return err
+	}
+	c.done = true
+	if extractErr := c.i.extractResponse(c.ctx, c.ResponseHeader(), c.ResponseTrailer()); extractErr != nil {
+		return errors.Join(err, extractErr)
+	}
+	return err
+}
+
+// WrapStreamingHandler implements the connect.Interceptor interface.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := i.extractRequest(ctx, conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+		err = next(ctx, conn)
+		if injectErr := i.inject(ctx, conn.ResponseTrailer(), ctxwire.ResponseOnly); injectErr != nil && err == nil {
+			return connect.NewError(connect.CodeInternal, injectErr)
+		}
+		return err
+	}
+}
@@ -0,0 +1,132 @@
+package ctxwireconnect_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwireconnect"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type (
+	tenantKey struct{}
+	quotaKey  struct{}
+)
+
+func TestUnary(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("tenant", tenantKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("quota", quotaKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	// The same interceptor serves the client and the handler.
+	interceptor := connect.WithInterceptors(ctxwireconnect.NewInterceptor(ctxwireconnect.WithRegistry(r)))
+	mux := http.NewServeMux()
+	mux.Handle("/test.Service/Unary", connect.NewUnaryHandler("/test.Service/Unary",
+		func(ctx context.Context, req *connect.Request[structpb.Value]) (*connect.Response[structpb.Value], error) {
+			if ctx.Value(tenantKey{}) != "acme" {
+				return nil, connect.NewError(connect.CodePermissionDenied, nil)
+			}
+			ctxwire.Put(ctx, quotaKey{}, 9)
+			return connect.NewResponse(req.Msg), nil
+		}, interceptor))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := connect.NewClient[structpb.Value, structpb.Value](server.Client(), server.URL+"/test.Service/Unary", interceptor)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	var respCtx context.Context
+	_, err := client.CallUnary(ctxwireconnect.ExtractInto(ctx, &respCtx), connect.NewRequest(structpb.NewStringValue("ping")))
+	require.NoError(t, err)
+	require.Equal(t, 9, respCtx.Value(quotaKey{}))
+	require.Equal(t, "acme", respCtx.Value(tenantKey{}))
+
+	// Calls without ExtractInto still propagate the values to the handler.
+	_, err = client.CallUnary(ctx, connect.NewRequest(structpb.NewStringValue("ping")))
+	require.NoError(t, err)
+}
+
+func TestUnaryError(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("quota", quotaKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly))))
+	interceptor := connect.WithInterceptors(ctxwireconnect.NewInterceptor(ctxwireconnect.WithRegistry(r)))
+	mux := http.NewServeMux()
+	mux.Handle("/test.Service/Unary", connect.NewUnaryHandler("/test.Service/Unary",
+		func(ctx context.Context, _ *connect.Request[structpb.Value]) (*connect.Response[structpb.Value], error) {
+			ctxwire.Put(ctx, quotaKey{}, 0)
+			return nil, connect.NewError(connect.CodeResourceExhausted, nil)
+		}, interceptor))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The values back-propagated along with an error are extracted.
+	client := connect.NewClient[structpb.Value, structpb.Value](server.Client(), server.URL+"/test.Service/Unary", interceptor)
+	respCtx := context.Background()
+	_, err := client.CallUnary(ctxwireconnect.ExtractInto(context.Background(), &respCtx), connect.NewRequest(structpb.NewStringValue("ping")))
+	require.Equal(t, connect.CodeResourceExhausted, connect.CodeOf(err))
+	require.Equal(t, 0, respCtx.Value(quotaKey{}))
+}
+
+func TestServerStream(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("tenant", tenantKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("quota", quotaKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	interceptor := connect.WithInterceptors(ctxwireconnect.NewInterceptor(ctxwireconnect.WithRegistry(r)))
+	mux := http.NewServeMux()
+	// The values recorded while streaming are sent in the trailers, once
+	// the stream is done.
+	mux.Handle("/test.Service/Stream", connect.NewServerStreamHandler("/test.Service/Stream",
+		func(ctx context.Context, req *connect.Request[structpb.Value], stream *connect.ServerStream[structpb.Value]) error {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			for i := range 3 {
+				if err := stream.Send(structpb.NewStringValue(tenant)); err != nil {
+					return err
+				}
+				ctxwire.Put(ctx, quotaKey{}, 2-i)
+			}
+			return nil
+		}, interceptor))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := connect.NewClient[structpb.Value, structpb.Value](server.Client(), server.URL+"/test.Service/Stream", interceptor)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	var respCtx context.Context
+	stream, err := client.CallServerStream(ctxwireconnect.ExtractInto(ctx, &respCtx), connect.NewRequest(structpb.NewStringValue("ping")))
+	require.NoError(t, err)
+	n := 0
+	for stream.Receive() {
+		require.Equal(t, "acme", stream.Msg().GetStringValue())
+		require.Nil(t, respCtx, "the values are extracted once the stream is done")
+		n++
+	}
+	require.NoError(t, stream.Err())
+	require.NoError(t, stream.Close())
+	require.Equal(t, 3, n)
+	require.Equal(t, 0, respCtx.Value(quotaKey{}))
+}
+
+func TestInvalidRequestHeader(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("quota", quotaKey{})))
+	mux := http.NewServeMux()
+	mux.Handle("/test.Service/Unary", connect.NewUnaryHandler("/test.Service/Unary",
+		func(_ context.Context, req *connect.Request[structpb.Value]) (*connect.Response[structpb.Value], error) {
+			return connect.NewResponse(req.Msg), nil
+		}, connect.WithInterceptors(ctxwireconnect.NewInterceptor(ctxwireconnect.WithRegistry(r)))))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := connect.NewClient[structpb.Value, structpb.Value](server.Client(), server.URL+"/test.Service/Unary")
+	req := connect.NewRequest(structpb.NewStringValue("ping"))
+	req.Header().Set("x-ctxwire-quota", "forty-two")
+	_, err := client.CallUnary(context.Background(), req)
+	require.Equal(t, connect.CodeInvalidArgument, connect.CodeOf(err))
+}
@@ -0,0 +1,18 @@
+module github.com/trezz/ctxwire/ctxwireconnect
+
+go 1.23.0
+
+require (
+	connectrpc.com/connect v1.18.1
+	github.com/stretchr/testify v1.10.0
+	github.com/trezz/ctxwire v0.0.0
+	google.golang.org/protobuf v1.36.6
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/trezz/ctxwire => ../
@@ -0,0 +1,175 @@
+// Package ctxwirefasthttp propagates ctxwire context values over fasthttp
+// requests and responses, for stacks built on fasthttp which can't use the
+// net/http types.
+package ctxwirefasthttp
+
+import (
+	"context"
+
+	"github.com/trezz/ctxwire"
+	"github.com/valyala/fasthttp"
+)
+
+// RequestHeaderCarrier adapts fasthttp.RequestHeader to the
+// ctxwire.MultiValueCarrier interface.
+type RequestHeaderCarrier struct {
+	h *fasthttp.RequestHeader
+}
+
+var _ ctxwire.MultiValueCarrier = RequestHeaderCarrier{}
+
+// NewRequestHeaderCarrier returns a new carrier of the given request headers.
+func NewRequestHeaderCarrier(h *fasthttp.RequestHeader) RequestHeaderCarrier {
+	return RequestHeaderCarrier{h: h}
+}
+
+// Get implements the ctxwire.Carrier interface.
+func (c RequestHeaderCarrier) Get(key string) string { return string(c.h.Peek(key)) }
+
+// Set implements the ctxwire.Carrier interface.
+func (c RequestHeaderCarrier) Set(key, value string) { c.h.Set(key, value) }
+
+// Keys implements the ctxwire.Carrier interface.
+func (c RequestHeaderCarrier) Keys() []string { return keys(c.h.VisitAll) }
+
+// Values implements the ctxwire.MultiValueCarrier interface.
+func (c RequestHeaderCarrier) Values(key string) []string { return toStrings(c.h.PeekAll(key)) }
+
+// Add implements the ctxwire.MultiValueCarrier interface.
+func (c RequestHeaderCarrier) Add(key, value string) { c.h.Add(key, value) }
+
+// ResponseHeaderCarrier adapts fasthttp.ResponseHeader to the
+// ctxwire.MultiValueCarrier interface.
+type ResponseHeaderCarrier struct {
+	h *fasthttp.ResponseHeader
+}
+
+var _ ctxwire.MultiValueCarrier = ResponseHeaderCarrier{}
+
+// NewResponseHeaderCarrier returns a new carrier of the given response
+// headers.
+func NewResponseHeaderCarrier(h *fasthttp.ResponseHeader) ResponseHeaderCarrier {
+	return ResponseHeaderCarrier{h: h}
+}
+
+// Get implements the ctxwire.Carrier interface.
+func (c ResponseHeaderCarrier) Get(key string) string { return string(c.h.Peek(key)) }
+
+// Set implements the ctxwire.Carrier interface.
+func (c ResponseHeaderCarrier) Set(key, value string) { c.h.Set(key, value) }
+
+// Keys implements the ctxwire.Carrier interface.
+func (c ResponseHeaderCarrier) Keys() []string { return keys(c.h.VisitAll) }
+
+// Values implements the ctxwire.MultiValueCarrier interface.
+func (c ResponseHeaderCarrier) Values(key string) []string { return toStrings(c.h.PeekAll(key)) }
+
+// Add implements the ctxwire.MultiValueCarrier interface.
+func (c ResponseHeaderCarrier) Add(key, value string) { c.h.Add(key, value) }
+
+func keys(visitAll func(func(key, value []byte))) []string {
+	var keys []string
+	seen := map[string]bool{}
+	visitAll(func(key, _ []byte) {
+		if k := string(key); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	})
+	return keys
+}
+
+func toStrings(values [][]byte) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return s
+}
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+}
+
+// WithRegistry makes the helpers read and write the headers of the
+// propagators of the given registry, rather than the ones of
+// ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) inject(ctx context.Context, carrier ctxwire.Carrier, dir ctxwire.Direction) error {
+	return c.registry.InjectCarrier(ctx, carrier, dir)
+}
+
+func (c *config) extract(ctx context.Context, carrier ctxwire.Carrier, dir ctxwire.Direction) (context.Context, error) {
+	return c.registry.ExtractCarrier(ctx, carrier, dir)
+}
+
+// InjectRequest injects the context values into the headers of the given
+// request, except the ResponseOnly ones.
+func InjectRequest(ctx context.Context, req *fasthttp.Request, opts ...Option) error {
+	return newConfig(opts).inject(ctx, NewRequestHeaderCarrier(&req.Header), ctxwire.RequestOnly)
+}
+
+// ExtractRequest extracts the context values from the headers of the given
+// request into a copy of the given context, except the ResponseOnly ones.
+func ExtractRequest(ctx context.Context, req *fasthttp.Request, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extract(ctx, NewRequestHeaderCarrier(&req.Header), ctxwire.RequestOnly)
+}
+
+// InjectResponse injects the context values into the headers of the given
+// response, except the RequestOnly ones.
+func InjectResponse(ctx context.Context, resp *fasthttp.Response, opts ...Option) error {
+	return newConfig(opts).inject(ctx, NewResponseHeaderCarrier(&resp.Header), ctxwire.ResponseOnly)
+}
+
+// ExtractResponse extracts the context values from the headers of the given
+// response into a copy of the given context, except the RequestOnly ones.
+func ExtractResponse(ctx context.Context, resp *fasthttp.Response, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extract(ctx, NewResponseHeaderCarrier(&resp.Header), ctxwire.ResponseOnly)
+}
+
+// Doer is implemented by the fasthttp clients, such as fasthttp.Client and
+// fasthttp.HostClient.
+type Doer interface {
+	Do(req *fasthttp.Request, resp *fasthttp.Response) error
+}
+
+// Client is a fasthttp client middleware propagating the context values.
+type Client struct {
+	doer Doer
+	cfg  *config
+}
+
+// NewClient returns a new Client sending its requests with the given client.
+func NewClient(doer Doer, opts ...Option) *Client {
+	return &Client{doer: doer, cfg: newConfig(opts)}
+}
+
+// Do injects the context values into the headers of the given request, sends
+// it and returns a copy of the given context holding the values
+// back-propagated in the headers of the response.
+func (c *Client) Do(ctx context.Context, req *fasthttp.Request, resp *fasthttp.Response) (context.Context, error) {
+	if err := c.cfg.inject(ctx, NewRequestHeaderCarrier(&req.Header), ctxwire.RequestOnly); err != nil {
+		return nil, err
+	}
+	if err := c.doer.Do(req, resp); err != nil {
+		return nil, err
+	}
+	return c.cfg.extract(ctx, NewResponseHeaderCarrier(&resp.Header), ctxwire.ResponseOnly)
+}
@@ -0,0 +1,74 @@
+package ctxwirefasthttp_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwirefasthttp"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+type userKey struct{}
+
+type sessionKey struct{}
+
+func TestCarriers(t *testing.T) {
+	var req fasthttp.RequestHeader
+	c := ctxwirefasthttp.NewRequestHeaderCarrier(&req)
+	c.Set("x-ctxwire-a", "1")
+	c.Add("x-ctxwire-a", "2")
+	require.Equal(t, "1", c.Get("X-Ctxwire-A"))
+	require.Equal(t, []string{"1", "2"}, c.Values("x-ctxwire-a"))
+	require.Equal(t, []string{"X-Ctxwire-A"}, c.Keys())
+
+	var resp fasthttp.ResponseHeader
+	rc := ctxwirefasthttp.NewResponseHeaderCarrier(&resp)
+	rc.Set("x-ctxwire-b", "1")
+	require.Equal(t, "1", rc.Get("X-Ctxwire-B"))
+	require.Nil(t, rc.Values("x-ctxwire-c"))
+	require.Contains(t, rc.Keys(), "X-Ctxwire-B")
+}
+
+func TestClient(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", userKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewStringPropagator("session", sessionKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+
+	ln := fasthttputil.NewInmemoryListener()
+	defer ln.Close()
+	go func() {
+		_ = fasthttp.Serve(ln, func(rc *fasthttp.RequestCtx) {
+			ctx, err := ctxwirefasthttp.ExtractRequest(rc, &rc.Request, ctxwirefasthttp.WithRegistry(r))
+			if err != nil {
+				rc.Error(err.Error(), fasthttp.StatusBadRequest)
+				return
+			}
+			rc.SetBodyString(ctx.Value(userKey{}).(string))
+			ctx = context.WithValue(ctx, sessionKey{}, "s1")
+			if err := ctxwirefasthttp.InjectResponse(ctx, &rc.Response, ctxwirefasthttp.WithRegistry(r)); err != nil {
+				rc.Error(err.Error(), fasthttp.StatusInternalServerError)
+			}
+		})
+	}()
+
+	client := ctxwirefasthttp.NewClient(&fasthttp.Client{
+		Dial: func(string) (net.Conn, error) { return ln.Dial() },
+	}, ctxwirefasthttp.WithRegistry(r))
+	req, resp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+	req.SetRequestURI("http://example.com/")
+
+	ctx, err := client.Do(context.WithValue(context.Background(), userKey{}, "alice"), req, resp)
+	require.NoError(t, err)
+	require.Equal(t, fasthttp.StatusOK, resp.StatusCode())
+	require.Equal(t, "alice", string(resp.Body()))
+	require.Equal(t, "s1", ctx.Value(sessionKey{}))
+	require.Equal(t, "alice", ctx.Value(userKey{}))
+}
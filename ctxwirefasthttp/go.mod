@@ -0,0 +1,20 @@
+module github.com/trezz/ctxwire/ctxwirefasthttp
+
+go 1.23.0
+
+require (
+	github.com/stretchr/testify v1.10.0
+	github.com/trezz/ctxwire v0.0.0
+	github.com/valyala/fasthttp v1.58.0
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/valyala/bytebufferpool v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/trezz/ctxwire => ../
@@ -0,0 +1,120 @@
+// Package ctxwiregateway propagates ctxwire context values through the
+// HTTP to gRPC translation layer of grpc-gateway.
+// Its options make the gateway forward the ctxwire request headers to the gRPC
+// servers as metadata with the same keys, where ctxwiregrpc extracts them, and
+// send the values back-propagated by the servers in the header or trailer
+// metadata back to the HTTP clients as response headers with the same names.
+//
+//	mux := runtime.NewServeMux(ctxwiregateway.ServeMuxOptions()...)
+package ctxwiregateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/trezz/ctxwire"
+	"google.golang.org/protobuf/proto"
+)
+
+// Option configures the ServeMux options.
+type Option func(c *config)
+
+type config struct {
+	prefix   string
+	incoming runtime.HeaderMatcherFunc
+	outgoing runtime.HeaderMatcherFunc
+	trailer  runtime.HeaderMatcherFunc
+}
+
+// WithHeaderPrefix sets the prefix of the ctxwire headers, which must match
+// the header prefix of the registries of the clients and servers. Defaults to
+// ctxwire.DefaultHeaderPrefix.
+func WithHeaderPrefix(prefix string) Option {
+	return func(c *config) { c.prefix = strings.ToLower(prefix) }
+}
+
+// WithIncomingHeaderMatcher sets the matcher of the request headers which are
+// not ctxwire headers. Defaults to runtime.DefaultHeaderMatcher.
+func WithIncomingHeaderMatcher(fn runtime.HeaderMatcherFunc) Option {
+	return func(c *config) { c.incoming = fn }
+}
+
+// WithOutgoingHeaderMatcher sets the matcher of the header metadata keys which
+// are not ctxwire keys. Defaults to the matcher of grpc-gateway, prefixing the
+// keys with runtime.MetadataHeaderPrefix.
+func WithOutgoingHeaderMatcher(fn runtime.HeaderMatcherFunc) Option {
+	return func(c *config) { c.outgoing = fn }
+}
+
+// WithOutgoingTrailerMatcher sets the matcher of the trailer metadata keys
+// which are not ctxwire keys. Defaults to the matcher of grpc-gateway,
+// prefixing the keys with runtime.MetadataTrailerPrefix.
+func WithOutgoingTrailerMatcher(fn runtime.HeaderMatcherFunc) Option {
+	return func(c *config) { c.trailer = fn }
+}
+
+// matches reports whether the given key is a ctxwire key, including the key of
+// the envelope.
+func (c *config) matches(key string) bool {
+	key = strings.ToLower(key)
+	return strings.HasPrefix(key, c.prefix) || key == strings.TrimSuffix(c.prefix, "-")
+}
+
+// ServeMuxOptions returns the options of the runtime.ServeMux propagating the
+// ctxwire headers. The values back-propagated by the servers in trailer
+// metadata are sent as response headers, since HTTP clients rarely accept
+// trailers.
+func ServeMuxOptions(opts ...Option) []runtime.ServeMuxOption {
+	c := &config{
+		prefix:   ctxwire.DefaultHeaderPrefix,
+		incoming: runtime.DefaultHeaderMatcher,
+		outgoing: func(key string) (string, bool) {
+			return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+		},
+		trailer: func(key string) (string, bool) {
+			return fmt.Sprintf("%s%s", runtime.MetadataTrailerPrefix, key), true
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return []runtime.ServeMuxOption{
+		runtime.WithIncomingHeaderMatcher(func(key string) (string, bool) {
+			if c.matches(key) {
+				return strings.ToLower(key), true
+			}
+			return c.incoming(key)
+		}),
+		runtime.WithOutgoingHeaderMatcher(func(key string) (string, bool) {
+			if c.matches(key) {
+				return key, true
+			}
+			return c.outgoing(key)
+		}),
+		runtime.WithOutgoingTrailerMatcher(func(key string) (string, bool) {
+			if c.matches(key) {
+				// Sent as headers by the forward response option.
+				return "", false
+			}
+			return c.trailer(key)
+		}),
+		runtime.WithForwardResponseOption(func(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+			md, ok := runtime.ServerMetadataFromContext(ctx)
+			if !ok {
+				return nil
+			}
+			for key, values := range md.TrailerMD {
+				if !c.matches(key) {
+					continue
+				}
+				for _, v := range values {
+					w.Header().Add(key, v)
+				}
+			}
+			return nil
+		}),
+	}
+}
@@ -0,0 +1,67 @@
+package ctxwiregateway_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire/ctxwiregateway"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestServeMuxOptions(t *testing.T) {
+	mux := runtime.NewServeMux(ctxwiregateway.ServeMuxOptions(ctxwiregateway.WithHeaderPrefix("X-App-"))...)
+	var incoming metadata.MD
+	require.NoError(t, mux.HandlePath(http.MethodGet, "/v1/items", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		// Mimic the handlers generated by grpc-gateway.
+		ctx, err := runtime.AnnotateContext(r.Context(), mux, r, "/test.Items/List")
+		require.NoError(t, err)
+		incoming, _ = metadata.FromOutgoingContext(ctx)
+		ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{
+			HeaderMD:  metadata.Pairs("x-app-tenant", "acme", "server", "s"),
+			TrailerMD: metadata.Pairs("x-app-session", "s1", "x-app", "envelope", "other", "o"),
+		})
+		runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, w, r, &emptypb.Empty{}, mux.GetForwardResponseOptions()...)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/items", nil)
+	req.Header.Set("X-App-User", "alice")
+	req.Header.Set("X-Other", "ignored")
+	req.Header.Set("Authorization", "token")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	require.Equal(t, []string{"alice"}, incoming.Get("x-app-user"))
+	require.Empty(t, incoming.Get("x-other"))
+	require.Equal(t, []string{"token"}, incoming.Get("authorization"))
+
+	h := w.Result().Header
+	require.Equal(t, "acme", h.Get("X-App-Tenant"))
+	require.Equal(t, "s", h.Get("Grpc-Metadata-Server"))
+	require.Equal(t, "s1", h.Get("X-App-Session"))
+	require.Equal(t, "envelope", h.Get("X-App"))
+	require.Empty(t, h.Get("Other"))
+}
+
+func TestServeMuxOptionsTrailers(t *testing.T) {
+	mux := runtime.NewServeMux(ctxwiregateway.ServeMuxOptions()...)
+	require.NoError(t, mux.HandlePath(http.MethodGet, "/v1/items", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx := runtime.NewServerMetadataContext(r.Context(), runtime.ServerMetadata{
+			TrailerMD: metadata.Pairs("x-ctxwire-session", "s1", "other", "o"),
+		})
+		runtime.ForwardResponseMessage(ctx, mux, &runtime.JSONPb{}, w, r, &emptypb.Empty{}, mux.GetForwardResponseOptions()...)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/items", nil)
+	req.Header.Set("TE", "trailers")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	resp := w.Result()
+	require.Equal(t, "s1", resp.Header.Get("X-Ctxwire-Session"))
+	require.Empty(t, resp.Trailer.Get("X-Ctxwire-Session"))
+	require.Equal(t, "o", resp.Trailer.Get("Grpc-Trailer-Other"))
+}
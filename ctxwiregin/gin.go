@@ -0,0 +1,153 @@
+// Package ctxwiregin propagates ctxwire context values through Gin handlers,
+// with a middleware extracting the values from the request headers and
+// injecting them back into the response headers.
+package ctxwiregin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/trezz/ctxwire"
+)
+
+// Option configures the middleware.
+type Option func(c *config)
+
+type config struct {
+	registry     *ctxwire.Registry
+	errorHandler func(c *gin.Context, err error)
+}
+
+// WithRegistry sets the registry whose values the middleware extracts from the
+// requests and injects into the responses. Defaults to ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+// WithErrorHandler sets the function handling the errors extracting the
+// context values from the request headers. Defaults to aborting the request
+// with a 400 Bad Request status and the error attached to the Gin context.
+func WithErrorHandler(fn func(c *gin.Context, err error)) Option {
+	return func(c *config) { c.errorHandler = fn }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		registry: ctxwire.DefaultRegistry(),
+		errorHandler: func(c *gin.Context, err error) {
+			_ = c.AbortWithError(http.StatusBadRequest, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) injectResponse(ctx context.Context, h http.Header) error {
+	return c.registry.InjectResponse(ctx, h)
+}
+
+func (c *config) injectTrailer(ctx context.Context, w http.ResponseWriter) error {
+	return c.registry.InjectTrailer(ctx, w)
+}
+
+func (c *config) extractRequest(ctx context.Context, h http.Header) (context.Context, error) {
+	return c.registry.ExtractRequest(ctx, h)
+}
+
+// Middleware returns a Gin middleware extracting the context values from the
+// request headers into the context of the request, except the ResponseOnly
+// ones.
+// The values are injected into the response headers, except the RequestOnly
+// ones, when the handlers write the response, and into the response trailers
+// once they return. The values recorded with Put are injected too.
+// The injection errors are attached to the Gin context.
+func Middleware(opts ...Option) gin.HandlerFunc {
+	cfg := newConfig(opts)
+	return func(c *gin.Context) {
+		ctx, err := cfg.extractRequest(c.Request.Context(), c.Request.Header)
+		if err != nil {
+			cfg.errorHandler(c, err)
+			return
+		}
+		ctx = ctxwire.WithBox(ctx)
+		c.Request = c.Request.WithContext(ctx)
+		w := &responseWriter{ResponseWriter: c.Writer, cfg: cfg, ctx: ctx}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		w.inject()
+		err = w.err
+		if !w.hijacked {
+			err = errors.Join(err, cfg.injectTrailer(ctx, w.ResponseWriter))
+		}
+		if err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
+
+// Put records the given value in the box of the request context of c, so that
+// the middleware injects it into the response. It reports whether the request
+// context holds a box, that is whether the middleware handles the request.
+func Put(c *gin.Context, key, v any) bool {
+	return ctxwire.Put(c.Request.Context(), key, v)
+}
+
+// responseWriter is a gin.ResponseWriter injecting the context values into
+// the response headers right before they are written.
+type responseWriter struct {
+	gin.ResponseWriter
+	cfg      *config
+	ctx      context.Context
+	injected bool
+	hijacked bool
+	err      error
+}
+
+func (w *responseWriter) inject() {
+	if w.injected || w.hijacked {
+		return
+	}
+	w.injected = true
+	w.err = w.cfg.injectResponse(w.ctx, w.ResponseWriter.Header())
+}
+
+// WriteHeaderNow implements the gin.ResponseWriter interface.
+func (w *responseWriter) WriteHeaderNow() {
+	w.inject()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.inject()
+	return w.ResponseWriter.Write(b)
+}
+
+// WriteString implements the gin.ResponseWriter interface.
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.inject()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Flush implements the http.Flusher interface.
+func (w *responseWriter) Flush() {
+	w.inject()
+	w.ResponseWriter.Flush()
+}
+
+// Hijack implements the http.Hijacker interface. The values are not injected
+// into hijacked connections.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := w.ResponseWriter.Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
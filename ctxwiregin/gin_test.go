@@ -0,0 +1,166 @@
+package ctxwiregin_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwiregin"
+)
+
+type (
+	localeKey   struct{}
+	costKey     struct{}
+	durationKey struct{}
+	userKey     struct{}
+	sessionKey  struct{}
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+func TestMiddleware(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("locale", localeKey{}),
+		ctxwire.NewIntPropagator("cost", costKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	engine := gin.New()
+	engine.Use(ctxwiregin.Middleware(ctxwiregin.WithRegistry(r)))
+	engine.GET("/json", func(c *gin.Context) {
+		require.True(t, ctxwiregin.Put(c, costKey{}, 2))
+		c.JSON(http.StatusOK, gin.H{"locale": c.Request.Context().Value(localeKey{})})
+	})
+	// Gin writes the headers of the responses without body once the
+	// handlers return.
+	engine.GET("/status", func(c *gin.Context) {
+		ctxwiregin.Put(c, costKey{}, 1)
+		c.Status(http.StatusNoContent)
+	})
+	// The values recorded before aborting are injected too.
+	authorize := func(c *gin.Context) {
+		ctxwiregin.Put(c, costKey{}, 0)
+		c.AbortWithStatus(http.StatusForbidden)
+	}
+	engine.GET("/admin", authorize, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/json", nil)
+	req.Header.Set("x-ctxwire-locale", "fr-FR")
+	req.Header.Set("x-ctxwire-cost", "100")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"locale":"fr-FR"}`, w.Body.String())
+	require.Equal(t, "fr-FR", w.Header().Get("x-ctxwire-locale"))
+	require.Equal(t, "2", w.Header().Get("x-ctxwire-cost"))
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "1", w.Header().Get("x-ctxwire-cost"))
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	require.Equal(t, http.StatusForbidden, w.Code)
+	require.Equal(t, "0", w.Header().Get("x-ctxwire-cost"))
+}
+
+func TestMiddlewareStream(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewIntPropagator("cost", costKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+		ctxwire.NewIntPropagator("duration", durationKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly), ctxwire.WithTrailer()),
+	))
+	engine := gin.New()
+	engine.Use(ctxwiregin.Middleware(ctxwiregin.WithRegistry(r)))
+	engine.GET("/events", func(c *gin.Context) {
+		ctxwiregin.Put(c, costKey{}, 1)
+		n := 0
+		c.Stream(func(w io.Writer) bool {
+			n++
+			c.SSEvent("tick", n)
+			return n < 3
+		})
+		ctxwiregin.Put(c, durationKey{}, 30)
+	})
+
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "1", resp.Header.Get("x-ctxwire-cost"))
+	require.Empty(t, resp.Header.Get("x-ctxwire-duration"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "event:tick\ndata:3\n")
+	require.Equal(t, "30", resp.Trailer.Get("x-ctxwire-duration"))
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("user", userKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewStringPropagator("session", sessionKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	var errs []*gin.Error
+	engine := gin.New()
+	engine.Use(
+		func(c *gin.Context) {
+			c.Next()
+			errs = c.Errors
+		},
+		ctxwiregin.Middleware(ctxwiregin.WithRegistry(r)),
+	)
+	called := false
+	engine.GET("/", func(c *gin.Context) {
+		called = true
+		ctxwiregin.Put(c, sessionKey{}, 42)
+		c.String(http.StatusOK, "ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-ctxwire-user", "%%%")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	require.False(t, called)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Len(t, errs, 1)
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.True(t, called)
+	require.Equal(t, "ok", w.Body.String())
+	require.Len(t, errs, 1)
+	var e *ctxwire.Error
+	require.ErrorAs(t, errs[0].Err, &e)
+
+	engine = gin.New()
+	engine.Use(ctxwiregin.Middleware(
+		ctxwiregin.WithRegistry(r),
+		ctxwiregin.WithErrorHandler(func(c *gin.Context, _ error) { c.Next() }),
+	))
+	engine.GET("/", func(c *gin.Context) { c.String(http.StatusOK, "ignored") })
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	require.Equal(t, "ignored", w.Body.String())
+}
+
+func TestMiddlewareDefaultRegistry(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewStringPropagator("user", userKey{}))
+	engine := gin.New()
+	engine.Use(ctxwiregin.Middleware())
+	engine.GET("/", func(c *gin.Context) {
+		ctxwiregin.Put(c, userKey{}, "bob")
+		c.String(http.StatusOK, "ok")
+	})
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, "bob", w.Header().Get("x-ctxwire-user"))
+	require.False(t, ctxwiregin.Put(&gin.Context{Request: httptest.NewRequest(http.MethodGet, "/", nil)}, userKey{}, "x"))
+}
@@ -0,0 +1,24 @@
+module github.com/trezz/ctxwire/ctxwiregqlgen
+
+go 1.23.0
+
+require (
+	github.com/99designs/gqlgen v0.17.70
+	github.com/stretchr/testify v1.10.0
+	github.com/trezz/ctxwire v0.0.0
+	github.com/vektah/gqlparser/v2 v2.5.23
+)
+
+require (
+	github.com/agnivade/levenshtein v1.2.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/go-viper/mapstructure/v2 v2.2.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/sosodev/duration v1.3.1 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/trezz/ctxwire => ../
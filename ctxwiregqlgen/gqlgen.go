@@ -0,0 +1,118 @@
+// Package ctxwiregqlgen propagates ctxwire context values through gqlgen
+// GraphQL servers. Its extension extracts the values from the request headers
+// of the operations, so that resolvers see them, and back-propagates the
+// values recorded by resolvers with ctxwire.Put in the "ctxwire" field of the
+// extensions of the responses, since GraphQL responses can be streamed over
+// transports with no response headers.
+package ctxwiregqlgen
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/trezz/ctxwire"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ExtensionKey is the key of the response extensions field holding the
+// back-propagated values.
+const ExtensionKey = "ctxwire"
+
+// Option configures the extension.
+type Option func(e *Extension)
+
+// WithRegistry makes the extension propagate the values of the given
+// registry, rather than the ones of ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(e *Extension) { e.registry = r }
+}
+
+// Extension is a gqlgen handler extension propagating the context values.
+type Extension struct {
+	registry *ctxwire.Registry
+}
+
+var (
+	_ graphql.HandlerExtension     = (*Extension)(nil)
+	_ graphql.OperationInterceptor = (*Extension)(nil)
+	_ graphql.ResponseInterceptor  = (*Extension)(nil)
+)
+
+// NewExtension returns a new Extension configured with the given options.
+//
+//	srv := handler.New(schema)
+//	srv.Use(ctxwiregqlgen.NewExtension())
+func NewExtension(opts ...Option) *Extension {
+	e := &Extension{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ExtensionName implements the graphql.HandlerExtension interface.
+func (e *Extension) ExtensionName() string { return "CtxWire" }
+
+// Validate implements the graphql.HandlerExtension interface.
+func (e *Extension) Validate(graphql.ExecutableSchema) error { return nil }
+
+// InterceptOperation implements the graphql.OperationInterceptor interface.
+// It extracts the context values from the request headers of the operation.
+// Operations with invalid values fail.
+func (e *Extension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	c := ctxwire.HeaderCarrier(graphql.GetOperationContext(ctx).Headers)
+	newCtx, err := e.registry.ExtractCarrier(ctx, c, ctxwire.RequestOnly)
+	if err != nil {
+		return graphql.OneShot(graphql.ErrorResponse(ctx, "%s", err))
+	}
+	return next(ctxwire.WithBox(newCtx))
+}
+
+// InterceptResponse implements the graphql.ResponseInterceptor interface.
+// It injects the values to back-propagate into the extensions of each
+// response of the operation.
+func (e *Extension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if resp == nil {
+		return nil
+	}
+	c := ctxwire.MapCarrier{}
+	if err := e.registry.InjectCarrier(ctx, c, ctxwire.ResponseOnly); err != nil {
+		resp.Errors = append(resp.Errors, gqlerror.Wrap(err))
+		return resp
+	}
+	if len(c) > 0 {
+		if resp.Extensions == nil {
+			resp.Extensions = map[string]any{}
+		}
+		resp.Extensions[ExtensionKey] = map[string]string(c)
+	}
+	return resp
+}
+
+// ExtractResponse extracts the context values back-propagated in the given
+// extensions of a GraphQL response into a copy of the given context. It is
+// intended for Go clients of gqlgen servers.
+func ExtractResponse(ctx context.Context, extensions map[string]any, opts ...Option) (context.Context, error) {
+	e := NewExtension(opts...)
+	c := ctxwire.MapCarrier{}
+	switch v := extensions[ExtensionKey].(type) {
+	case nil:
+	case map[string]string:
+		for key, value := range v {
+			c.Set(key, value)
+		}
+	case map[string]any:
+		for key, value := range v {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s extension value of %q", ExtensionKey, key)
+			}
+			c.Set(key, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid %s extension", ExtensionKey)
+	}
+	return e.registry.ExtractCarrier(ctx, c, ctxwire.ResponseOnly)
+}
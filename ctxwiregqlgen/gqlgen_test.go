@@ -0,0 +1,78 @@
+package ctxwiregqlgen_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwiregqlgen"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+type userKey struct{}
+
+type sessionKey struct{}
+
+func newServer(t *testing.T) (*ctxwire.Registry, http.Handler) {
+	t.Helper()
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("user", userKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewStringPropagator("session", sessionKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: "type Query { user: String! }"})
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			// Resolvers see the extracted values and record the ones to
+			// back-propagate.
+			ctxwire.Put(ctx, sessionKey{}, "s1")
+			return graphql.OneShot(&graphql.Response{Data: []byte(fmt.Sprintf(`{"user":%q}`, ctx.Value(userKey{})))})
+		},
+		SchemaFunc: func() *ast.Schema { return schema },
+	})
+	srv.AddTransport(transport.POST{})
+	srv.Use(ctxwiregqlgen.NewExtension(ctxwiregqlgen.WithRegistry(r)))
+	return r, srv
+}
+
+func TestExtension(t *testing.T) {
+	r, srv := newServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"query":"{ user }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-ctxwire-user", "ImFsaWNlIg==")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	require.JSONEq(t, `{"data":{"user":"alice"},"extensions":{"ctxwire":{"x-ctxwire-session":"s1"}}}`, w.Body.String())
+
+	var resp struct {
+		Extensions map[string]any `json:"extensions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	ctx, err := ctxwiregqlgen.ExtractResponse(context.Background(), resp.Extensions, ctxwiregqlgen.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "s1", ctx.Value(sessionKey{}))
+
+	_, err = ctxwiregqlgen.ExtractResponse(context.Background(), map[string]any{"ctxwire": "s1"}, ctxwiregqlgen.WithRegistry(r))
+	require.ErrorContains(t, err, "invalid ctxwire extension")
+}
+
+func TestExtensionInvalidValue(t *testing.T) {
+	_, srv := newServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"query":"{ user }"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-ctxwire-user", "%%%")
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, req)
+	require.Contains(t, w.Body.String(), "decode header value")
+	require.NotContains(t, w.Body.String(), `"data":{`)
+}
@@ -0,0 +1,22 @@
+module github.com/trezz/ctxwire/ctxwiregrpc
+
+go 1.23.0
+
+require (
+	github.com/stretchr/testify v1.10.0
+	github.com/trezz/ctxwire v0.0.0
+	google.golang.org/grpc v1.72.2
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/net v0.37.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250303144028-a0af3efb3deb // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/trezz/ctxwire => ../
@@ -0,0 +1,275 @@
+// Package ctxwiregrpc propagates ctxwire context values over gRPC metadata.
+// Clients inject the values into the outgoing metadata of their calls and
+// servers extract them from the incoming metadata. Values set by servers are
+// back-propagated to clients in the trailers of the calls.
+package ctxwiregrpc
+
+import (
+	"context"
+
+	"github.com/trezz/ctxwire"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataCarrier adapts metadata.MD to the ctxwire.MultiValueCarrier
+// interface.
+type MetadataCarrier metadata.MD
+
+var _ ctxwire.MultiValueCarrier = MetadataCarrier(nil)
+
+// Get implements the ctxwire.Carrier interface.
+func (c MetadataCarrier) Get(key string) string {
+	if vs := metadata.MD(c).Get(key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+// Set implements the ctxwire.Carrier interface.
+func (c MetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys implements the ctxwire.Carrier interface.
+func (c MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Values implements the ctxwire.MultiValueCarrier interface.
+func (c MetadataCarrier) Values(key string) []string {
+	return metadata.MD(c).Get(key)
+}
+
+// Add implements the ctxwire.MultiValueCarrier interface.
+func (c MetadataCarrier) Add(key, value string) {
+	metadata.MD(c).Append(key, value)
+}
+
+// Option configures the interceptors.
+type Option func(c *config)
+
+type config struct {
+	registry    *ctxwire.Registry
+	injectError func(ctx context.Context, err error)
+}
+
+// WithRegistry makes the interceptors propagate the values of the given
+// registry in the metadata of the calls, rather than the ones of
+// ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+// WithInjectErrorHandler sets the function handling the errors injecting the
+// context values into the trailer of the calls by the server interceptors.
+// The response of the handler is sent anyway, without the values which could
+// not be injected. By default, the errors are ignored.
+func WithInjectErrorHandler(fn func(ctx context.Context, err error)) Option {
+	return func(c *config) { c.injectError = fn }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) inject(ctx context.Context, md metadata.MD, dir ctxwire.Direction) error {
+	return c.registry.InjectCarrier(ctx, MetadataCarrier(md), dir)
+}
+
+func (c *config) extract(ctx context.Context, md metadata.MD, dir ctxwire.Direction) (context.Context, error) {
+	return c.registry.ExtractCarrier(ctx, MetadataCarrier(md), dir)
+}
+
+// outgoingContext returns a copy of the given context whose outgoing metadata
+// holds the context values.
+func (c *config) outgoingContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	if err := c.inject(ctx, md, ctxwire.RequestOnly); err != nil {
+		return nil, err
+	}
+	return metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// incomingContext returns a copy of the given context holding the values of its
+// incoming metadata, and a box recording the values to back-propagate.
+func (c *config) incomingContext(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx, err := c.extract(ctx, md, ctxwire.RequestOnly)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return ctxwire.WithBox(ctx), nil
+}
+
+// trailer returns the metadata back-propagating the values of the given
+// context, reporting the injection errors to the inject error handler.
+func (c *config) trailer(ctx context.Context) metadata.MD {
+	md := metadata.MD{}
+	if err := c.inject(ctx, md, ctxwire.ResponseOnly); err != nil && c.injectError != nil {
+		c.injectError(ctx, err)
+	}
+	return md
+}
+
+// responseOption is a call option receiving the context of a call updated with
+// the values back-propagated by the server.
+type responseOption struct {
+	grpc.EmptyCallOption
+	ctx *context.Context
+}
+
+// ExtractInto returns a call option making the client interceptors store into
+// ctx a copy of it holding the values back-propagated by the server in the
+// headers and trailers of the call. The extraction happens when the call
+// completes for unary calls, and when the stream is done for streaming calls.
+func ExtractInto(ctx *context.Context) grpc.CallOption {
+	return responseOption{ctx: ctx}
+}
+
+func responseContext(callOpts []grpc.CallOption) *context.Context {
+	for _, opt := range callOpts {
+		if r, ok := opt.(responseOption); ok {
+			return r.ctx
+		}
+	}
+	return nil
+}
+
+// extractResponse stores into dst the values back-propagated in the given
+// header and trailer.
+func (c *config) extractResponse(dst *context.Context, header, trailer metadata.MD) error {
+	ctx, err := c.extract(*dst, header, ctxwire.ResponseOnly)
+	if err != nil {
+		return err
+	}
+	if ctx, err = c.extract(ctx, trailer, ctxwire.ResponseOnly); err != nil {
+		return err
+	}
+	*dst = ctx
+	return nil
+}
+
+// UnaryClientInterceptor returns an interceptor injecting the context values
+// into the outgoing metadata of unary calls.
+// Values back-propagated by the server are extracted with the ExtractInto
+// call option, even when the call fails.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		ctx, err := c.outgoingContext(ctx)
+		if err != nil {
+			return err
+		}
+		dst := responseContext(callOpts)
+		if dst == nil {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
+		}
+		var header, trailer metadata.MD
+		callOpts = append(callOpts, grpc.Header(&header), grpc.Trailer(&trailer))
+		err = invoker(ctx, method, req, reply, cc, callOpts...)
+		if extractErr := c.extractResponse(dst, header, trailer); err == nil {
+			return extractErr
+		}
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor injecting the context values
+// into the outgoing metadata of streaming calls.
+// Values back-propagated by the server are extracted with the ExtractInto
+// call option, once RecvMsg returns an error.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := c.outgoingContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			return nil, err
+		}
+		if dst := responseContext(callOpts); dst != nil {
+			cs = &clientStream{ClientStream: cs, c: c, dst: dst}
+		}
+		return cs, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	c   *config
+	dst *context.Context
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil || s.dst == nil {
+		return err
+	}
+	dst := s.dst
+	s.dst = nil
+	header, _ := s.Header()
+	if extractErr := s.c.extractResponse(dst, header, s.Trailer()); extractErr != nil {
+		return extractErr
+	}
+	return err
+}
+
+// UnaryServerInterceptor returns an interceptor extracting the context values
+// from the incoming metadata of unary calls, and back-propagating the values
+// of the context in the trailer of the call. Handlers record the values to
+// back-propagate with ctxwire.Put.
+// Calls whose values fail to be extracted fail with an InvalidArgument
+// status, while the errors injecting the values into the trailer are
+// reported with WithInjectErrorHandler, without failing the call.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	c := newConfig(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := c.incomingContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := handler(ctx, req)
+		if md := c.trailer(ctx); len(md) > 0 {
+			_ = grpc.SetTrailer(ctx, md)
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor extracting the context values
+// from the incoming metadata of streaming calls, and back-propagating the
+// values of the context in the trailer of the call, as
+// UnaryServerInterceptor does.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	c := newConfig(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := c.incomingContext(ss.Context())
+		if err != nil {
+			return err
+		}
+		err = handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		ss.SetTrailer(c.trailer(ctx))
+		return err
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
@@ -0,0 +1,228 @@
+package ctxwiregrpc_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwiregrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type (
+	tenantKey  struct{}
+	latencyKey struct{}
+)
+
+// healthServer reports the tenants of the calls it receives and
+// back-propagates the given latency, before failing with the given error.
+type healthServer struct {
+	grpc_health_v1.UnimplementedHealthServer
+	tenants chan any
+	latency int
+	err     error
+}
+
+func (s *healthServer) Check(ctx context.Context, _ *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	s.tenants <- ctx.Value(tenantKey{})
+	ctxwire.Put(ctx, latencyKey{}, s.latency)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *healthServer) Watch(_ *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	s.tenants <- stream.Context().Value(tenantKey{})
+	for range 2 {
+		if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+			return err
+		}
+	}
+	ctxwire.Put(stream.Context(), latencyKey{}, s.latency)
+	return s.err
+}
+
+func newClient(t *testing.T, r *ctxwire.Registry, srv *healthServer, serverOpts ...ctxwiregrpc.Option) grpc_health_v1.HealthClient {
+	lis := bufconn.Listen(1 << 20)
+	serverOpts = append([]ctxwiregrpc.Option{ctxwiregrpc.WithRegistry(r)}, serverOpts...)
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(ctxwiregrpc.UnaryServerInterceptor(serverOpts...)),
+		grpc.StreamInterceptor(ctxwiregrpc.StreamServerInterceptor(serverOpts...)),
+	)
+	grpc_health_v1.RegisterHealthServer(s, srv)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(ctxwiregrpc.UnaryClientInterceptor(ctxwiregrpc.WithRegistry(r))),
+		grpc.WithStreamInterceptor(ctxwiregrpc.StreamClientInterceptor(ctxwiregrpc.WithRegistry(r))),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func TestUnary(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("tenant", tenantKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("latency", latencyKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	srv := &healthServer{tenants: make(chan any, 1), latency: 42}
+	client := newClient(t, r, srv)
+
+	// The values already in the outgoing metadata are kept.
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "token")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	respCtx := ctx
+	var header metadata.MD
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, ctxwiregrpc.ExtractInto(&respCtx), grpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, "acme", <-srv.tenants)
+	require.Equal(t, 42, respCtx.Value(latencyKey{}))
+	require.Equal(t, "acme", respCtx.Value(tenantKey{}))
+	require.Empty(t, header.Get("x-ctxwire-latency"), "the values are back-propagated in the trailer")
+	md, _ := metadata.FromOutgoingContext(ctx)
+	require.Equal(t, metadata.Pairs("authorization", "token"), md, "the metadata of the caller is not modified")
+
+	// Calls without ExtractInto still propagate the values to the server.
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "acme", <-srv.tenants)
+}
+
+func TestUnaryError(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("latency", latencyKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly))))
+	srv := &healthServer{tenants: make(chan any, 1), latency: 500, err: status.Error(codes.Unavailable, "overloaded")}
+	client := newClient(t, r, srv)
+
+	// The values back-propagated along with an error status are extracted.
+	respCtx := context.Background()
+	_, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{}, ctxwiregrpc.ExtractInto(&respCtx))
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, 500, respCtx.Value(latencyKey{}))
+	<-srv.tenants
+}
+
+func TestStream(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("tenant", tenantKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("latency", latencyKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	srv := &healthServer{tenants: make(chan any, 1), latency: 7}
+	client := newClient(t, r, srv)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	respCtx := ctx
+	stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{}, ctxwiregrpc.ExtractInto(&respCtx))
+	require.NoError(t, err)
+	for range 2 {
+		_, err = stream.Recv()
+		require.NoError(t, err)
+		require.Nil(t, respCtx.Value(latencyKey{}), "the values are extracted once the stream is done")
+	}
+	_, err = stream.Recv()
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, "acme", <-srv.tenants)
+	require.Equal(t, 7, respCtx.Value(latencyKey{}))
+
+	// The values back-propagated by failed streams are extracted too.
+	srv.err = status.Error(codes.Aborted, "conflict")
+	respCtx = ctx
+	stream, err = client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{}, ctxwiregrpc.ExtractInto(&respCtx))
+	require.NoError(t, err)
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	require.Equal(t, codes.Aborted, status.Code(err))
+	require.Equal(t, 7, respCtx.Value(latencyKey{}))
+	<-srv.tenants
+}
+
+func TestInjectError(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("tenant", tenantKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewPropagator("latency", latencyKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly),
+			ctxwire.WithEncoder(ctxwire.EncoderFunc(func(context.Context, any) ([]byte, error) {
+				return nil, errors.New("failed!")
+			})),
+		),
+	))
+	srv := &healthServer{tenants: make(chan any, 1), latency: 42}
+	injectErrs := make(chan error, 1)
+	client := newClient(t, r, srv, ctxwiregrpc.WithInjectErrorHandler(func(_ context.Context, err error) {
+		injectErrs <- err
+	}))
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	// The responses and errors of the handlers are kept.
+	resp, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.GetStatus())
+	require.ErrorContains(t, <-injectErrs, "failed!")
+	<-srv.tenants
+
+	srv.err = status.Error(codes.Unavailable, "overloaded")
+	_, err = client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.ErrorContains(t, <-injectErrs, "failed!")
+	<-srv.tenants
+
+	stream, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	for err == nil {
+		_, err = stream.Recv()
+	}
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.ErrorContains(t, <-injectErrs, "failed!")
+	<-srv.tenants
+}
+
+func TestInvalidMetadata(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("tenant", tenantKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("latency", latencyKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	srv := &healthServer{tenants: make(chan any, 1)}
+	client := newClient(t, r, srv)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-ctxwire-latency", "forty-two")
+	ctx = context.WithValue(ctx, tenantKey{}, "acme")
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err, "response-only values are not extracted from requests")
+	<-srv.tenants
+
+	r = ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("latency", latencyKey{})))
+	interceptor := ctxwiregrpc.UnaryServerInterceptor(ctxwiregrpc.WithRegistry(r))
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-ctxwire-latency", "forty-two"))
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) { return nil, nil })
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	md := metadata.MD{}
+	c := ctxwiregrpc.MetadataCarrier(md)
+	c.Set("X-Ctxwire-Foo", "a")
+	c.Add("x-ctxwire-foo", "b")
+	require.Equal(t, "a", c.Get("x-ctxwire-foo"))
+	require.Equal(t, []string{"a", "b"}, c.Values("X-CTXWIRE-FOO"))
+	require.Equal(t, []string{"x-ctxwire-foo"}, c.Keys())
+}
@@ -0,0 +1,200 @@
+// Package ctxwirehertz propagates ctxwire context values over CloudWeGo Hertz
+// requests and responses, whose header types can't be used with the net/http
+// helpers.
+package ctxwirehertz
+
+import (
+	"context"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/trezz/ctxwire"
+)
+
+// RequestHeaderCarrier adapts protocol.RequestHeader to the
+// ctxwire.MultiValueCarrier interface.
+type RequestHeaderCarrier struct {
+	h *protocol.RequestHeader
+}
+
+var _ ctxwire.MultiValueCarrier = RequestHeaderCarrier{}
+
+// NewRequestHeaderCarrier returns a new carrier of the given request headers.
+func NewRequestHeaderCarrier(h *protocol.RequestHeader) RequestHeaderCarrier {
+	return RequestHeaderCarrier{h: h}
+}
+
+// Get implements the ctxwire.Carrier interface.
+func (c RequestHeaderCarrier) Get(key string) string { return string(c.h.Peek(key)) }
+
+// Set implements the ctxwire.Carrier interface.
+func (c RequestHeaderCarrier) Set(key, value string) { c.h.Set(key, value) }
+
+// Keys implements the ctxwire.Carrier interface.
+func (c RequestHeaderCarrier) Keys() []string { return keys(c.h.VisitAll) }
+
+// Values implements the ctxwire.MultiValueCarrier interface.
+func (c RequestHeaderCarrier) Values(key string) []string { return toStrings(c.h.PeekAll(key)) }
+
+// Add implements the ctxwire.MultiValueCarrier interface.
+func (c RequestHeaderCarrier) Add(key, value string) { c.h.Add(key, value) }
+
+// ResponseHeaderCarrier adapts protocol.ResponseHeader to the
+// ctxwire.MultiValueCarrier interface.
+type ResponseHeaderCarrier struct {
+	h *protocol.ResponseHeader
+}
+
+var _ ctxwire.MultiValueCarrier = ResponseHeaderCarrier{}
+
+// NewResponseHeaderCarrier returns a new carrier of the given response
+// headers.
+func NewResponseHeaderCarrier(h *protocol.ResponseHeader) ResponseHeaderCarrier {
+	return ResponseHeaderCarrier{h: h}
+}
+
+// Get implements the ctxwire.Carrier interface.
+func (c ResponseHeaderCarrier) Get(key string) string { return string(c.h.Peek(key)) }
+
+// Set implements the ctxwire.Carrier interface.
+func (c ResponseHeaderCarrier) Set(key, value string) { c.h.Set(key, value) }
+
+// Keys implements the ctxwire.Carrier interface.
+func (c ResponseHeaderCarrier) Keys() []string { return keys(c.h.VisitAll) }
+
+// Values implements the ctxwire.MultiValueCarrier interface.
+func (c ResponseHeaderCarrier) Values(key string) []string { return toStrings(c.h.PeekAll(key)) }
+
+// Add implements the ctxwire.MultiValueCarrier interface.
+func (c ResponseHeaderCarrier) Add(key, value string) { c.h.Add(key, value) }
+
+func keys(visitAll func(func(key, value []byte))) []string {
+	var keys []string
+	seen := map[string]bool{}
+	visitAll(func(key, _ []byte) {
+		if k := string(key); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	})
+	return keys
+}
+
+func toStrings(values [][]byte) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = string(v)
+	}
+	return s
+}
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry     *ctxwire.Registry
+	errorHandler func(c context.Context, ctx *app.RequestContext, err error)
+}
+
+// WithRegistry sets the registry propagating the values through the Hertz
+// requests and responses. Defaults to ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+// WithErrorHandler sets the function handling the errors extracting the
+// context values from the request headers in the middleware. Defaults to
+// aborting the request with a 400 Bad Request status and the error attached
+// to the request context.
+func WithErrorHandler(fn func(c context.Context, ctx *app.RequestContext, err error)) Option {
+	return func(c *config) { c.errorHandler = fn }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		registry: ctxwire.DefaultRegistry(),
+		errorHandler: func(_ context.Context, ctx *app.RequestContext, err error) {
+			_ = ctx.AbortWithError(consts.StatusBadRequest, err)
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) inject(ctx context.Context, carrier ctxwire.Carrier, dir ctxwire.Direction) error {
+	return c.registry.InjectCarrier(ctx, carrier, dir)
+}
+
+func (c *config) extract(ctx context.Context, carrier ctxwire.Carrier, dir ctxwire.Direction) (context.Context, error) {
+	return c.registry.ExtractCarrier(ctx, carrier, dir)
+}
+
+// InjectRequest injects the context values into the headers of the given
+// request, except the ResponseOnly ones.
+func InjectRequest(ctx context.Context, req *protocol.Request, opts ...Option) error {
+	return newConfig(opts).inject(ctx, NewRequestHeaderCarrier(&req.Header), ctxwire.RequestOnly)
+}
+
+// ExtractRequest extracts the context values from the headers of the given
+// request into a copy of the given context, except the ResponseOnly ones.
+func ExtractRequest(ctx context.Context, req *protocol.Request, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extract(ctx, NewRequestHeaderCarrier(&req.Header), ctxwire.RequestOnly)
+}
+
+// InjectResponse injects the context values into the headers of the given
+// response, except the RequestOnly ones.
+func InjectResponse(ctx context.Context, resp *protocol.Response, opts ...Option) error {
+	return newConfig(opts).inject(ctx, NewResponseHeaderCarrier(&resp.Header), ctxwire.ResponseOnly)
+}
+
+// ExtractResponse extracts the context values from the headers of the given
+// response into a copy of the given context, except the RequestOnly ones.
+func ExtractResponse(ctx context.Context, resp *protocol.Response, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extract(ctx, NewResponseHeaderCarrier(&resp.Header), ctxwire.ResponseOnly)
+}
+
+// Middleware returns a Hertz server middleware extracting the context values
+// from the request headers into the context passed to the next handlers,
+// except the ResponseOnly ones.
+// Once the handlers return, the values are injected into the response
+// headers, except the RequestOnly ones, including the values recorded with
+// ctxwire.Put. The injection errors are attached to the request context.
+func Middleware(opts ...Option) app.HandlerFunc {
+	cfg := newConfig(opts)
+	return func(c context.Context, ctx *app.RequestContext) {
+		newCtx, err := cfg.extract(c, NewRequestHeaderCarrier(&ctx.Request.Header), ctxwire.RequestOnly)
+		if err != nil {
+			cfg.errorHandler(c, ctx, err)
+			return
+		}
+		c = ctxwire.WithBox(newCtx)
+		ctx.Next(c)
+		if err := cfg.inject(c, NewResponseHeaderCarrier(&ctx.Response.Header), ctxwire.ResponseOnly); err != nil {
+			_ = ctx.Error(err)
+		}
+	}
+}
+
+// ClientMiddleware returns a Hertz client middleware injecting the context
+// values into the request headers, except the ResponseOnly ones, to be
+// installed with client.Client.Use.
+// Since client middlewares can't return a context, use ExtractResponse to
+// extract the values back-propagated in the response headers.
+func ClientMiddleware(opts ...Option) client.Middleware {
+	cfg := newConfig(opts)
+	return func(next client.Endpoint) client.Endpoint {
+		return func(ctx context.Context, req *protocol.Request, resp *protocol.Response) error {
+			if err := cfg.inject(ctx, NewRequestHeaderCarrier(&req.Header), ctxwire.RequestOnly); err != nil {
+				return err
+			}
+			return next(ctx, req, resp)
+		}
+	}
+}
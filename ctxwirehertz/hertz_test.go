@@ -0,0 +1,191 @@
+package ctxwirehertz_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/common/config"
+	"github.com/cloudwego/hertz/pkg/common/ut"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/cloudwego/hertz/pkg/route"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwirehertz"
+)
+
+type (
+	userKey    struct{}
+	sessionKey struct{}
+	localeKey  struct{}
+	costKey    struct{}
+)
+
+func TestCarriers(t *testing.T) {
+	var req protocol.Request
+	c := ctxwirehertz.NewRequestHeaderCarrier(&req.Header)
+	c.Set("x-a", "1")
+	c.Add("x-b", "2")
+	c.Add("x-b", "3")
+	require.Equal(t, "1", c.Get("x-a"))
+	require.Equal(t, []string{"2", "3"}, c.Values("x-b"))
+	require.Nil(t, c.Values("x-c"))
+	require.ElementsMatch(t, []string{"X-A", "X-B"}, c.Keys())
+
+	var resp protocol.Response
+	rc := ctxwirehertz.NewResponseHeaderCarrier(&resp.Header)
+	rc.Set("x-a", "1")
+	rc.Add("x-a", "2")
+	require.Equal(t, "1", rc.Get("x-a"))
+	require.Equal(t, []string{"1", "2"}, rc.Values("x-a"))
+	require.Contains(t, rc.Keys(), "X-A")
+}
+
+func TestMiddleware(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("locale", localeKey{}),
+		ctxwire.NewIntPropagator("cost", costKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(ctxwirehertz.Middleware(ctxwirehertz.WithRegistry(r)))
+	// Hertz buffers the responses, so the values recorded after the body is
+	// written are still sent in the headers.
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		locale, _ := c.Value(localeKey{}).(string)
+		ctx.String(consts.StatusOK, locale)
+		require.True(t, ctxwire.Put(c, costKey{}, 2))
+	})
+	// The values recorded before aborting are injected too.
+	authorize := func(c context.Context, ctx *app.RequestContext) {
+		ctxwire.Put(c, costKey{}, 0)
+		ctx.AbortWithStatus(consts.StatusForbidden)
+	}
+	engine.GET("/admin", authorize, func(_ context.Context, ctx *app.RequestContext) { ctx.Status(consts.StatusOK) })
+
+	w := ut.PerformRequest(engine, consts.MethodGet, "/", nil,
+		ut.Header{Key: "x-ctxwire-locale", Value: "fr-FR"}, ut.Header{Key: "x-ctxwire-cost", Value: "100"})
+	resp := w.Result()
+	require.Equal(t, consts.StatusOK, resp.StatusCode())
+	require.Equal(t, "fr-FR", string(resp.Body()))
+	require.Equal(t, "fr-FR", string(resp.Header.Peek("x-ctxwire-locale")))
+	require.Equal(t, "2", string(resp.Header.Peek("x-ctxwire-cost")))
+
+	w = ut.PerformRequest(engine, consts.MethodGet, "/admin", nil)
+	require.Equal(t, consts.StatusForbidden, w.Result().StatusCode())
+	require.Equal(t, "0", string(w.Result().Header.Peek("x-ctxwire-cost")))
+}
+
+func TestMiddlewareErrors(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("user", userKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewStringPropagator("session", sessionKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	var errs []error
+	called := false
+	engine := route.NewEngine(config.NewOptions(nil))
+	engine.Use(
+		func(c context.Context, ctx *app.RequestContext) {
+			ctx.Next(c)
+			errs = nil
+			for _, err := range ctx.Errors {
+				errs = append(errs, err.Err)
+			}
+		},
+		ctxwirehertz.Middleware(ctxwirehertz.WithRegistry(r)),
+	)
+	engine.GET("/", func(c context.Context, ctx *app.RequestContext) {
+		called = true
+		ctxwire.Put(c, sessionKey{}, 42)
+		ctx.String(consts.StatusOK, "ok")
+	})
+
+	w := ut.PerformRequest(engine, consts.MethodGet, "/", nil, ut.Header{Key: "x-ctxwire-user", Value: "%%%"})
+	require.False(t, called)
+	require.Equal(t, consts.StatusBadRequest, w.Result().StatusCode())
+	require.Len(t, errs, 1)
+
+	w = ut.PerformRequest(engine, consts.MethodGet, "/", nil)
+	require.True(t, called)
+	require.Equal(t, "ok", string(w.Result().Body()))
+	require.Len(t, errs, 1)
+	var e *ctxwire.Error
+	require.ErrorAs(t, errs[0], &e)
+
+	// Custom error handlers get the request context, not the one of the
+	// failed extraction.
+	var requestID any
+	custom := route.NewEngine(config.NewOptions(nil))
+	custom.Use(
+		func(c context.Context, ctx *app.RequestContext) {
+			ctx.Next(context.WithValue(c, localeKey{}, "req-1"))
+		},
+		ctxwirehertz.Middleware(ctxwirehertz.WithRegistry(r), ctxwirehertz.WithErrorHandler(
+			func(c context.Context, ctx *app.RequestContext, err error) {
+				requestID = c.Value(localeKey{})
+				ctx.AbortWithStatus(consts.StatusUnprocessableEntity)
+			})),
+	)
+	custom.GET("/", func(context.Context, *app.RequestContext) { t.Fatal("handler called") })
+	w = ut.PerformRequest(custom, consts.MethodGet, "/", nil, ut.Header{Key: "x-ctxwire-user", Value: "%%%"})
+	require.Equal(t, consts.StatusUnprocessableEntity, w.Result().StatusCode())
+	require.Equal(t, "req-1", requestID)
+}
+
+func TestClientMiddleware(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("locale", localeKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("cost", costKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	endpoint := ctxwirehertz.ClientMiddleware(ctxwirehertz.WithRegistry(r))(
+		func(_ context.Context, req *protocol.Request, resp *protocol.Response) error {
+			require.Equal(t, "fr-FR", string(req.Header.Peek("x-ctxwire-locale")))
+			require.Empty(t, req.Header.Peek("x-ctxwire-cost"))
+			require.Equal(t, "application/json", string(req.Header.Peek("Accept")))
+			resp.Header.Set("x-ctxwire-cost", "3")
+			return nil
+		},
+	)
+	ctx := context.WithValue(context.Background(), localeKey{}, "fr-FR")
+	ctx = context.WithValue(ctx, costKey{}, 1)
+	var req protocol.Request
+	req.Header.Set("Accept", "application/json")
+	var resp protocol.Response
+	require.NoError(t, endpoint(ctx, &req, &resp))
+	ctx, err := ctxwirehertz.ExtractResponse(ctx, &resp, ctxwirehertz.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, 3, ctx.Value(costKey{}))
+
+	failing := ctxwire.NewRegistry()
+	require.NoError(t, failing.Configure(ctxwire.NewStringPropagator("user", userKey{})))
+	endpoint = ctxwirehertz.ClientMiddleware(ctxwirehertz.WithRegistry(failing))(
+		func(context.Context, *protocol.Request, *protocol.Response) error {
+			return errors.New("unexpected call")
+		},
+	)
+	err = endpoint(context.WithValue(context.Background(), userKey{}, 1), &req, &resp)
+	var e *ctxwire.Error
+	require.ErrorAs(t, err, &e)
+}
+
+func TestHelpers(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewStringPropagator("user", userKey{}))
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+
+	var req protocol.Request
+	require.NoError(t, ctxwirehertz.InjectRequest(ctx, &req))
+	got, err := ctxwirehertz.ExtractRequest(context.Background(), &req)
+	require.NoError(t, err)
+	require.Equal(t, "alice", got.Value(userKey{}))
+
+	var resp protocol.Response
+	require.NoError(t, ctxwirehertz.InjectResponse(ctx, &resp))
+	got, err = ctxwirehertz.ExtractResponse(context.Background(), &resp)
+	require.NoError(t, err)
+	require.Equal(t, "alice", got.Value(userKey{}))
+}
@@ -0,0 +1,142 @@
+// Package ctxwirejsonrpc propagates ctxwire context values in JSON-RPC 2.0
+// messages, for services multiplexing calls over a single HTTP request or a
+// raw socket, where calls have no headers of their own.
+// The values are carried in a "meta" member of the request and response
+// objects, as an object holding the header values of the propagators:
+//
+//	{"jsonrpc":"2.0","method":"sum","params":[1,2],"id":1,"meta":{"x-ctxwire-user":"alice"}}
+//
+// Requests carry the values propagated from clients to servers, and responses
+// the values back-propagated to clients. The messages of a batch are handled
+// one by one.
+package ctxwirejsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/trezz/ctxwire"
+)
+
+// DefaultMember is the default name of the member holding the values.
+const DefaultMember = "meta"
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+	member   string
+}
+
+// WithRegistry sets the registry whose values are carried in the meta member
+// of the messages. Defaults to ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+// WithMember sets the name of the member of the messages holding the values.
+// Defaults to DefaultMember.
+func WithMember(name string) Option {
+	return func(c *config) { c.member = name }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry(), member: DefaultMember}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// InjectMeta returns the context values propagated in the given direction as
+// a meta object, for messages modeled as structs with a meta field.
+func InjectMeta(ctx context.Context, dir ctxwire.Direction, opts ...Option) (map[string]string, error) {
+	return newConfig(opts).injectMeta(ctx, dir)
+}
+
+func (c *config) injectMeta(ctx context.Context, dir ctxwire.Direction) (map[string]string, error) {
+	meta := ctxwire.MapCarrier{}
+	if err := c.registry.InjectCarrier(ctx, meta, dir); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// ExtractMeta extracts the context values propagated in the given direction
+// from the given meta object into a copy of the given context.
+func ExtractMeta(ctx context.Context, meta map[string]string, dir ctxwire.Direction, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extractMeta(ctx, meta, dir)
+}
+
+func (c *config) extractMeta(ctx context.Context, meta map[string]string, dir ctxwire.Direction) (context.Context, error) {
+	carrier := ctxwire.MapCarrier{}
+	for key, value := range meta {
+		carrier.Set(key, value)
+	}
+	return c.registry.ExtractCarrier(ctx, carrier, dir)
+}
+
+// inject returns a copy of the given message with the values propagated in
+// the given direction set in its meta member.
+func (c *config) inject(ctx context.Context, msg []byte, dir ctxwire.Direction) ([]byte, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &obj); err != nil {
+		return nil, fmt.Errorf("unmarshal JSON-RPC message: %w", err)
+	}
+	meta, err := c.injectMeta(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(meta) == 0 {
+		return msg, nil
+	}
+	if obj[c.member], err = json.Marshal(meta); err != nil {
+		return nil, fmt.Errorf("marshal %s member: %w", c.member, err)
+	}
+	return json.Marshal(obj)
+}
+
+// extract extracts the values propagated in the given direction from the meta
+// member of the given message into a copy of the given context.
+func (c *config) extract(ctx context.Context, msg []byte, dir ctxwire.Direction) (context.Context, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(msg, &obj); err != nil {
+		return nil, fmt.Errorf("unmarshal JSON-RPC message: %w", err)
+	}
+	var meta map[string]string
+	if data, ok := obj[c.member]; ok {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("unmarshal %s member: %w", c.member, err)
+		}
+	}
+	return c.extractMeta(ctx, meta, dir)
+}
+
+// InjectRequest returns a copy of the given JSON-RPC request or notification
+// holding the context values, except the ResponseOnly ones. Messages are
+// returned as is if there are no values.
+func InjectRequest(ctx context.Context, msg []byte, opts ...Option) ([]byte, error) {
+	return newConfig(opts).inject(ctx, msg, ctxwire.RequestOnly)
+}
+
+// ExtractRequest extracts the context values from the given JSON-RPC request
+// or notification into a copy of the given context, except the ResponseOnly
+// ones.
+func ExtractRequest(ctx context.Context, msg []byte, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extract(ctx, msg, ctxwire.RequestOnly)
+}
+
+// InjectResponse returns a copy of the given JSON-RPC response holding the
+// context values, except the RequestOnly ones. Messages are returned as is if
+// there are no values.
+func InjectResponse(ctx context.Context, msg []byte, opts ...Option) ([]byte, error) {
+	return newConfig(opts).inject(ctx, msg, ctxwire.ResponseOnly)
+}
+
+// ExtractResponse extracts the context values from the given JSON-RPC
+// response into a copy of the given context, except the RequestOnly ones.
+func ExtractResponse(ctx context.Context, msg []byte, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extract(ctx, msg, ctxwire.ResponseOnly)
+}
@@ -0,0 +1,105 @@
+package ctxwirejsonrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwirejsonrpc"
+)
+
+type (
+	traceKey struct{}
+	quotaKey struct{}
+)
+
+func TestRequest(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("trace", traceKey{})))
+	ctx := context.WithValue(context.Background(), traceKey{}, "t1")
+
+	// The members of the request are kept as is, such as the large numbers,
+	// and a stale meta member is replaced.
+	msg, err := ctxwirejsonrpc.InjectRequest(ctx,
+		[]byte(`{"jsonrpc":"2.0","method":"transfer","params":{"amount":12345678901234567890},"id":"a1","meta":{"x-ctxwire-trace":"old"}}`),
+		ctxwirejsonrpc.WithRegistry(r))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","method":"transfer","params":{"amount":12345678901234567890},"id":"a1","meta":{"x-ctxwire-trace":"t1"}}`, string(msg))
+	got, err := ctxwirejsonrpc.ExtractRequest(context.Background(), msg, ctxwirejsonrpc.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "t1", got.Value(traceKey{}))
+
+	// Notifications, without id, carry the values too.
+	msg, err = ctxwirejsonrpc.InjectRequest(ctx, []byte(`{"jsonrpc":"2.0","method":"log"}`), ctxwirejsonrpc.WithRegistry(r))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","method":"log","meta":{"x-ctxwire-trace":"t1"}}`, string(msg))
+
+	// Messages without meta member hold no values.
+	got, err = ctxwirejsonrpc.ExtractRequest(context.Background(), []byte(`{"jsonrpc":"2.0","method":"log"}`), ctxwirejsonrpc.WithRegistry(r))
+	require.NoError(t, err)
+	require.Nil(t, got.Value(traceKey{}))
+}
+
+func TestResponse(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("trace", traceKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("quota", quotaKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	ctx := context.WithValue(context.Background(), traceKey{}, "t1")
+	ctx = context.WithValue(ctx, quotaKey{}, 9)
+
+	// Error responses back-propagate the values too.
+	msg, err := ctxwirejsonrpc.InjectResponse(ctx, []byte(`{"jsonrpc":"2.0","error":{"code":-32000,"message":"quota exceeded"},"id":1}`),
+		ctxwirejsonrpc.WithRegistry(r), ctxwirejsonrpc.WithMember("_meta"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"jsonrpc":"2.0","error":{"code":-32000,"message":"quota exceeded"},"id":1,"_meta":{"x-ctxwire-quota":"9"}}`, string(msg))
+	got, err := ctxwirejsonrpc.ExtractResponse(context.Background(), msg,
+		ctxwirejsonrpc.WithRegistry(r), ctxwirejsonrpc.WithMember("_meta"))
+	require.NoError(t, err)
+	require.Equal(t, 9, got.Value(quotaKey{}))
+
+	// Messages are left untouched when there are no values.
+	msg = []byte(`{"jsonrpc":"2.0", "result":3, "id":1}`)
+	out, err := ctxwirejsonrpc.InjectResponse(context.WithValue(context.Background(), traceKey{}, "t1"), msg, ctxwirejsonrpc.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, msg, out)
+}
+
+func TestMeta(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("trace", traceKey{})))
+	type request struct {
+		Method string            `json:"method"`
+		Meta   map[string]string `json:"meta,omitempty"`
+	}
+	meta, err := ctxwirejsonrpc.InjectMeta(context.WithValue(context.Background(), traceKey{}, "t1"), ctxwire.RequestOnly,
+		ctxwirejsonrpc.WithRegistry(r))
+	require.NoError(t, err)
+	data, err := json.Marshal(request{Method: "sum", Meta: meta})
+	require.NoError(t, err)
+
+	var req request
+	require.NoError(t, json.Unmarshal(data, &req))
+	ctx, err := ctxwirejsonrpc.ExtractMeta(context.Background(), req.Meta, ctxwire.RequestOnly, ctxwirejsonrpc.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "t1", ctx.Value(traceKey{}))
+}
+
+func TestInvalidMessages(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("quota", quotaKey{})))
+
+	// Batches are handled one message at a time.
+	_, err := ctxwirejsonrpc.ExtractRequest(context.Background(), []byte(`[{"jsonrpc":"2.0","method":"log"}]`), ctxwirejsonrpc.WithRegistry(r))
+	require.ErrorContains(t, err, "unmarshal JSON-RPC message")
+	_, err = ctxwirejsonrpc.ExtractRequest(context.Background(), []byte(`{"meta":1}`), ctxwirejsonrpc.WithRegistry(r))
+	require.ErrorContains(t, err, "unmarshal meta member")
+	_, err = ctxwirejsonrpc.InjectRequest(context.Background(), []byte(`{`), ctxwirejsonrpc.WithRegistry(r))
+	require.ErrorContains(t, err, "unmarshal JSON-RPC message")
+	_, err = ctxwirejsonrpc.ExtractResponse(context.Background(), []byte(`{"meta":{"x-ctxwire-quota":"many"}}`), ctxwirejsonrpc.WithRegistry(r))
+	var e *ctxwire.Error
+	require.ErrorAs(t, err, &e)
+}
@@ -0,0 +1,100 @@
+// Package ctxwirekit propagates ctxwire context values over the HTTP
+// transport of go-kit, with client and server options injecting and
+// extracting the values in Before and After functions.
+package ctxwirekit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/transport"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/trezz/ctxwire"
+)
+
+// Option configures the client and server options.
+type Option func(c *config)
+
+type config struct {
+	registry     *ctxwire.Registry
+	errorHandler transport.ErrorHandler
+}
+
+// WithRegistry makes the client and server options propagate the values of
+// the given registry, rather than the ones of ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+// WithErrorHandler sets the handler of the propagation errors. Since the
+// Before and After functions of go-kit can't fail, the propagation errors are
+// ignored by default, and the request is processed without the values.
+func WithErrorHandler(h transport.ErrorHandler) Option {
+	return func(c *config) { c.errorHandler = h }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) handle(ctx context.Context, err error) {
+	if err != nil && c.errorHandler != nil {
+		c.errorHandler.Handle(ctx, err)
+	}
+}
+
+func (c *config) inject(ctx context.Context, h http.Header, dir ctxwire.Direction) {
+	c.handle(ctx, c.registry.InjectCarrier(ctx, ctxwire.HeaderCarrier(h), dir))
+}
+
+func (c *config) extract(ctx context.Context, h http.Header, dir ctxwire.Direction) context.Context {
+	newCtx, err := c.registry.ExtractCarrier(ctx, ctxwire.HeaderCarrier(h), dir)
+	if err != nil {
+		c.handle(ctx, err)
+		return ctx
+	}
+	return newCtx
+}
+
+// ClientOption returns an httptransport.ClientOption injecting the context
+// values into the request headers before sending requests, and extracting the
+// values back-propagated in the response headers into the context given to
+// the response decoder.
+func ClientOption(opts ...Option) httptransport.ClientOption {
+	c := newConfig(opts)
+	before := httptransport.ClientBefore(func(ctx context.Context, req *http.Request) context.Context {
+		c.inject(ctx, req.Header, ctxwire.RequestOnly)
+		return ctx
+	})
+	after := httptransport.ClientAfter(func(ctx context.Context, resp *http.Response) context.Context {
+		return c.extract(ctx, resp.Header, ctxwire.ResponseOnly)
+	})
+	return func(client *httptransport.Client) {
+		before(client)
+		after(client)
+	}
+}
+
+// ServerOption returns an httptransport.ServerOption extracting the context
+// values from the request headers before decoding requests, and injecting them
+// into the response headers before encoding responses.
+// Since the context derived by the endpoint is not visible to the server, the
+// endpoint records the values to back-propagate with ctxwire.Put.
+func ServerOption(opts ...Option) httptransport.ServerOption {
+	c := newConfig(opts)
+	before := httptransport.ServerBefore(func(ctx context.Context, req *http.Request) context.Context {
+		return ctxwire.WithBox(c.extract(ctx, req.Header, ctxwire.RequestOnly))
+	})
+	after := httptransport.ServerAfter(func(ctx context.Context, w http.ResponseWriter) context.Context {
+		c.inject(ctx, w.Header(), ctxwire.ResponseOnly)
+		return ctx
+	})
+	return func(server *httptransport.Server) {
+		before(server)
+		after(server)
+	}
+}
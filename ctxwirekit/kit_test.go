@@ -0,0 +1,117 @@
+package ctxwirekit_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-kit/kit/transport"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwirekit"
+)
+
+type (
+	tenantKey struct{}
+	costKey   struct{}
+	userKey   struct{}
+)
+
+func TestClientServer(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("tenant", tenantKey{}),
+		ctxwire.NewIntPropagator("cost", costKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	server := httptransport.NewServer(
+		func(ctx context.Context, _ any) (any, error) {
+			ctxwire.Put(ctx, costKey{}, 3)
+			return ctx.Value(tenantKey{}), nil
+		},
+		func(context.Context, *http.Request) (any, error) { return nil, nil },
+		// The values are injected before the response encoder writes the
+		// headers.
+		func(_ context.Context, w http.ResponseWriter, resp any) error {
+			w.WriteHeader(http.StatusCreated)
+			return json.NewEncoder(w).Encode(resp)
+		},
+		ctxwirekit.ServerOption(ctxwirekit.WithRegistry(r)),
+	)
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	var respCtx context.Context
+	client := httptransport.NewClient(http.MethodGet, u,
+		func(context.Context, *http.Request, any) error { return nil },
+		func(ctx context.Context, resp *http.Response) (any, error) {
+			respCtx = ctx
+			var tenant string
+			err := json.NewDecoder(resp.Body).Decode(&tenant)
+			return tenant, err
+		},
+		ctxwirekit.ClientOption(ctxwirekit.WithRegistry(r)),
+	)
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	ctx = context.WithValue(ctx, costKey{}, 1)
+	tenant, err := client.Endpoint()(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, "acme", tenant)
+	require.Equal(t, 3, respCtx.Value(costKey{}), "the server cost replaces the one of the client")
+	require.Equal(t, "acme", respCtx.Value(tenantKey{}))
+}
+
+func TestClientErrorHandler(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("cost", costKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly))))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("x-ctxwire-cost", "free")
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	var errs []error
+	var respCtx context.Context
+	client := httptransport.NewClient(http.MethodGet, u,
+		func(context.Context, *http.Request, any) error { return nil },
+		func(ctx context.Context, _ *http.Response) (any, error) {
+			respCtx = ctx
+			return nil, nil
+		},
+		ctxwirekit.ClientOption(ctxwirekit.WithRegistry(r), ctxwirekit.WithErrorHandler(
+			transport.ErrorHandlerFunc(func(_ context.Context, err error) { errs = append(errs, err) }))),
+	)
+
+	// The response is decoded anyway, with the values of the request context.
+	_, err = client.Endpoint()(context.WithValue(context.Background(), costKey{}, 1), nil)
+	require.NoError(t, err)
+	require.Equal(t, 1, respCtx.Value(costKey{}))
+	require.Len(t, errs, 1)
+}
+
+func TestErrorHandler(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("user", userKey{})))
+	var errs []error
+	handler := transport.ErrorHandlerFunc(func(_ context.Context, err error) { errs = append(errs, err) })
+	server := httptransport.NewServer(
+		func(ctx context.Context, _ any) (any, error) { return ctx.Value(userKey{}), nil },
+		func(context.Context, *http.Request) (any, error) { return nil, nil },
+		httptransport.EncodeJSONResponse,
+		ctxwirekit.ServerOption(ctxwirekit.WithRegistry(r), ctxwirekit.WithErrorHandler(handler)),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-ctxwire-user", "%%%")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, "null", w.Body.String())
+	require.Len(t, errs, 1)
+}
@@ -0,0 +1,17 @@
+module github.com/trezz/ctxwire/ctxwirelambda
+
+go 1.23.0
+
+require (
+	github.com/aws/aws-lambda-go v1.48.0
+	github.com/stretchr/testify v1.10.0
+	github.com/trezz/ctxwire v0.0.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/trezz/ctxwire => ../
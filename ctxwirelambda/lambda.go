@@ -0,0 +1,173 @@
+// Package ctxwirelambda propagates ctxwire context values through the AWS
+// Lambda functions serving HTTP requests behind API Gateway or an Application
+// Load Balancer. Handlers extract the values from the headers of the request
+// events and inject the values to back-propagate into the headers of the
+// responses, like regular HTTP servers.
+package ctxwirelambda
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/trezz/ctxwire"
+)
+
+// HeadersCarrier adapts the single-value and multi-value headers of Lambda
+// HTTP events to the ctxwire.MultiValueCarrier interface. Keys are
+// case-insensitive, since events preserve the case of the headers sent by
+// clients.
+// Values are read from the multi-value headers first. They are written to the
+// multi-value headers if not nil, and to the single-value headers otherwise.
+type HeadersCarrier struct {
+	headers      map[string]string
+	multiHeaders map[string][]string
+}
+
+var _ ctxwire.MultiValueCarrier = HeadersCarrier{}
+
+// NewHeadersCarrier returns a new carrier of the given single-value and
+// multi-value headers, any of which can be nil.
+func NewHeadersCarrier(headers map[string]string, multiHeaders map[string][]string) HeadersCarrier {
+	return HeadersCarrier{headers: headers, multiHeaders: multiHeaders}
+}
+
+// lookup returns the key of the given map matching the given key regardless
+// of its case, or the given key if there is none.
+func lookup[V any](m map[string]V, key string) string {
+	if _, ok := m[key]; ok {
+		return key
+	}
+	for k := range m {
+		if strings.EqualFold(k, key) {
+			return k
+		}
+	}
+	return key
+}
+
+// Get implements the ctxwire.Carrier interface.
+func (c HeadersCarrier) Get(key string) string {
+	if v := c.multiHeaders[lookup(c.multiHeaders, key)]; len(v) > 0 {
+		return v[0]
+	}
+	return c.headers[lookup(c.headers, key)]
+}
+
+// Set implements the ctxwire.Carrier interface.
+func (c HeadersCarrier) Set(key, value string) {
+	if c.multiHeaders != nil {
+		c.multiHeaders[lookup(c.multiHeaders, key)] = []string{value}
+		return
+	}
+	c.headers[lookup(c.headers, key)] = value
+}
+
+// Keys implements the ctxwire.Carrier interface.
+func (c HeadersCarrier) Keys() []string {
+	keys := slices.Collect(maps.Keys(c.multiHeaders))
+	for k := range c.headers {
+		if _, ok := c.multiHeaders[lookup(c.multiHeaders, k)]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Values implements the ctxwire.MultiValueCarrier interface.
+func (c HeadersCarrier) Values(key string) []string {
+	if v := c.multiHeaders[lookup(c.multiHeaders, key)]; len(v) > 0 {
+		return v
+	}
+	if v, ok := c.headers[lookup(c.headers, key)]; ok {
+		return []string{v}
+	}
+	return nil
+}
+
+// Add implements the ctxwire.MultiValueCarrier interface. Values are added to
+// the multi-value headers if not nil, and replace the single-value header
+// otherwise.
+func (c HeadersCarrier) Add(key, value string) {
+	if c.multiHeaders != nil {
+		k := lookup(c.multiHeaders, key)
+		c.multiHeaders[k] = append(c.multiHeaders[k], value)
+		return
+	}
+	c.Set(key, value)
+}
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+}
+
+// WithRegistry makes the helpers propagate the values of the given registry
+// in the headers of the events, instead of the values of
+// ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func extract(ctx context.Context, c HeadersCarrier, opts []Option) (context.Context, error) {
+	return newConfig(opts).registry.ExtractCarrier(ctx, c, ctxwire.RequestOnly)
+}
+
+// inject injects the context values into the given response headers,
+// allocating the single-value ones if both are nil.
+func inject(ctx context.Context, headers *map[string]string, multiHeaders map[string][]string, opts []Option) error {
+	if *headers == nil && multiHeaders == nil {
+		*headers = map[string]string{}
+	}
+	c := NewHeadersCarrier(*headers, multiHeaders)
+	return newConfig(opts).registry.InjectCarrier(ctx, c, ctxwire.ResponseOnly)
+}
+
+// ExtractAPIGatewayRequest extracts the context values from the headers of the
+// given API Gateway REST API request into a copy of the given context.
+func ExtractAPIGatewayRequest(ctx context.Context, req *events.APIGatewayProxyRequest, opts ...Option) (context.Context, error) {
+	return extract(ctx, NewHeadersCarrier(req.Headers, req.MultiValueHeaders), opts)
+}
+
+// InjectAPIGatewayResponse injects the context values into the headers of the
+// given API Gateway REST API response.
+func InjectAPIGatewayResponse(ctx context.Context, resp *events.APIGatewayProxyResponse, opts ...Option) error {
+	return inject(ctx, &resp.Headers, resp.MultiValueHeaders, opts)
+}
+
+// ExtractAPIGatewayV2Request extracts the context values from the headers of
+// the given API Gateway HTTP API request into a copy of the given context.
+func ExtractAPIGatewayV2Request(ctx context.Context, req *events.APIGatewayV2HTTPRequest, opts ...Option) (context.Context, error) {
+	return extract(ctx, NewHeadersCarrier(req.Headers, nil), opts)
+}
+
+// InjectAPIGatewayV2Response injects the context values into the headers of
+// the given API Gateway HTTP API response.
+func InjectAPIGatewayV2Response(ctx context.Context, resp *events.APIGatewayV2HTTPResponse, opts ...Option) error {
+	return inject(ctx, &resp.Headers, resp.MultiValueHeaders, opts)
+}
+
+// ExtractALBRequest extracts the context values from the headers of the given
+// Application Load Balancer request into a copy of the given context.
+func ExtractALBRequest(ctx context.Context, req *events.ALBTargetGroupRequest, opts ...Option) (context.Context, error) {
+	return extract(ctx, NewHeadersCarrier(req.Headers, req.MultiValueHeaders), opts)
+}
+
+// InjectALBResponse injects the context values into the headers of the given
+// Application Load Balancer response. Target groups with multi-value headers
+// enabled require the MultiValueHeaders of the response to be set.
+func InjectALBResponse(ctx context.Context, resp *events.ALBTargetGroupResponse, opts ...Option) error {
+	return inject(ctx, &resp.Headers, resp.MultiValueHeaders, opts)
+}
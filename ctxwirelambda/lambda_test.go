@@ -0,0 +1,100 @@
+package ctxwirelambda_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwirelambda"
+)
+
+type (
+	userKey    struct{}
+	requestKey struct{}
+	attemptKey struct{}
+)
+
+func TestAPIGateway(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("user", userKey{})))
+
+	// REST APIs preserve the case of the headers sent by clients.
+	req := events.APIGatewayProxyRequest{Headers: map[string]string{"X-CtxWire-User": "alice"}}
+	ctx, err := ctxwirelambda.ExtractAPIGatewayRequest(context.Background(), &req, ctxwirelambda.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "alice", ctx.Value(userKey{}))
+
+	// Repeated headers only keep their last value in the single-value
+	// headers, so the multi-value ones win.
+	req.Headers = map[string]string{"X-Ctxwire-User": "bob"}
+	req.MultiValueHeaders = map[string][]string{"x-ctxwire-user": {"alice", "bob"}}
+	ctx, err = ctxwirelambda.ExtractAPIGatewayRequest(context.Background(), &req, ctxwirelambda.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "alice", ctx.Value(userKey{}))
+
+	// The headers of responses built without any are allocated.
+	var resp events.APIGatewayProxyResponse
+	require.NoError(t, ctxwirelambda.InjectAPIGatewayResponse(ctx, &resp, ctxwirelambda.WithRegistry(r)))
+	require.Equal(t, map[string]string{"x-ctxwire-user": "alice"}, resp.Headers)
+	require.Nil(t, resp.MultiValueHeaders)
+}
+
+func TestAPIGatewayV2(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("request-id", requestKey{})))
+
+	req := events.APIGatewayV2HTTPRequest{Headers: map[string]string{"x-ctxwire-request-id": "r1"}}
+	ctx, err := ctxwirelambda.ExtractAPIGatewayV2Request(context.Background(), &req, ctxwirelambda.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "r1", ctx.Value(requestKey{}))
+
+	// Headers set by the handler with another case are replaced rather than
+	// sent twice.
+	resp := events.APIGatewayV2HTTPResponse{Headers: map[string]string{"X-Ctxwire-Request-Id": "stale", "Content-Type": "text/plain"}}
+	require.NoError(t, ctxwirelambda.InjectAPIGatewayV2Response(ctx, &resp, ctxwirelambda.WithRegistry(r)))
+	require.Equal(t, map[string]string{"X-Ctxwire-Request-Id": "r1", "Content-Type": "text/plain"}, resp.Headers)
+}
+
+func TestALB(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("attempt", attemptKey{})))
+	ctx := context.WithValue(context.Background(), attemptKey{}, 2)
+
+	// Target groups with multi-value headers enabled only fill the
+	// multi-value headers, of the requests and of the responses.
+	req := events.ALBTargetGroupRequest{MultiValueHeaders: map[string][]string{"x-ctxwire-attempt": {"2"}}}
+	got, err := ctxwirelambda.ExtractALBRequest(context.Background(), &req, ctxwirelambda.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, 2, got.Value(attemptKey{}))
+	resp := events.ALBTargetGroupResponse{MultiValueHeaders: map[string][]string{}}
+	require.NoError(t, ctxwirelambda.InjectALBResponse(ctx, &resp, ctxwirelambda.WithRegistry(r)))
+	require.Nil(t, resp.Headers)
+	require.Equal(t, map[string][]string{"x-ctxwire-attempt": {"2"}}, resp.MultiValueHeaders)
+
+	// The other ones only fill the single-value headers.
+	req = events.ALBTargetGroupRequest{Headers: map[string]string{"x-ctxwire-attempt": "2"}}
+	got, err = ctxwirelambda.ExtractALBRequest(context.Background(), &req, ctxwirelambda.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, 2, got.Value(attemptKey{}))
+	resp = events.ALBTargetGroupResponse{}
+	require.NoError(t, ctxwirelambda.InjectALBResponse(ctx, &resp, ctxwirelambda.WithRegistry(r)))
+	require.Equal(t, map[string]string{"x-ctxwire-attempt": "2"}, resp.Headers)
+	require.Nil(t, resp.MultiValueHeaders)
+
+	req.Headers["x-ctxwire-attempt"] = "second"
+	_, err = ctxwirelambda.ExtractALBRequest(context.Background(), &req, ctxwirelambda.WithRegistry(r))
+	var e *ctxwire.Error
+	require.ErrorAs(t, err, &e)
+}
+
+func TestHeadersCarrier(t *testing.T) {
+	c := ctxwirelambda.NewHeadersCarrier(map[string]string{"A": "1", "B": "2"}, map[string][]string{"b": {"3", "4"}})
+	require.Equal(t, "1", c.Get("a"))
+	require.Equal(t, []string{"3", "4"}, c.Values("B"))
+	require.ElementsMatch(t, []string{"A", "b"}, c.Keys())
+	c.Add("B", "5")
+	require.Equal(t, []string{"3", "4", "5"}, c.Values("b"))
+	require.Nil(t, c.Values("c"))
+}
@@ -0,0 +1,120 @@
+// Package ctxwiremqtt propagates ctxwire context values through MQTT v5
+// brokers in the user properties of the published messages, so that IoT
+// ingestion paths can forward the context of the requests of HTTP frontends
+// into MQTT message flows.
+// Messages only flow from publishers to subscribers, so the ResponseOnly
+// propagators don't run.
+package ctxwiremqtt
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/trezz/ctxwire"
+)
+
+// UserPropertiesCarrier adapts paho.UserProperties to the
+// ctxwire.MultiValueCarrier interface. Keys are case-insensitive, and set
+// lowercase.
+type UserPropertiesCarrier struct {
+	props *paho.UserProperties
+}
+
+var _ ctxwire.MultiValueCarrier = UserPropertiesCarrier{}
+
+// NewUserPropertiesCarrier returns a new carrier of the given user
+// properties.
+func NewUserPropertiesCarrier(props *paho.UserProperties) UserPropertiesCarrier {
+	return UserPropertiesCarrier{props: props}
+}
+
+// Get implements the ctxwire.Carrier interface.
+func (c UserPropertiesCarrier) Get(key string) string {
+	for _, p := range *c.props {
+		if strings.EqualFold(p.Key, key) {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// Set implements the ctxwire.Carrier interface.
+func (c UserPropertiesCarrier) Set(key, value string) {
+	*c.props = slices.DeleteFunc(*c.props, func(p paho.UserProperty) bool {
+		return strings.EqualFold(p.Key, key)
+	})
+	c.Add(key, value)
+}
+
+// Keys implements the ctxwire.Carrier interface.
+func (c UserPropertiesCarrier) Keys() []string {
+	var keys []string
+	for _, p := range *c.props {
+		if !slices.Contains(keys, p.Key) {
+			keys = append(keys, p.Key)
+		}
+	}
+	return keys
+}
+
+// Values implements the ctxwire.MultiValueCarrier interface.
+func (c UserPropertiesCarrier) Values(key string) []string {
+	var values []string
+	for _, p := range *c.props {
+		if strings.EqualFold(p.Key, key) {
+			values = append(values, p.Value)
+		}
+	}
+	return values
+}
+
+// Add implements the ctxwire.MultiValueCarrier interface.
+func (c UserPropertiesCarrier) Add(key, value string) {
+	c.props.Add(strings.ToLower(key), value)
+}
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+}
+
+// WithRegistry sets the registry whose values are carried in the user
+// properties of the messages. Defaults to ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// InjectPublish injects the context values into the user properties of the
+// given message before it is published, allocating its properties if needed.
+func InjectPublish(ctx context.Context, p *paho.Publish, opts ...Option) error {
+	if p.Properties == nil {
+		p.Properties = &paho.PublishProperties{}
+	}
+	cfg := newConfig(opts)
+	c := NewUserPropertiesCarrier(&p.Properties.User)
+	return cfg.registry.InjectCarrier(ctx, c, ctxwire.RequestOnly)
+}
+
+// ExtractPublish extracts the context values from the user properties of the
+// given received message into a copy of the given context.
+func ExtractPublish(ctx context.Context, p *paho.Publish, opts ...Option) (context.Context, error) {
+	var props paho.UserProperties
+	if p.Properties != nil {
+		props = p.Properties.User
+	}
+	cfg := newConfig(opts)
+	c := NewUserPropertiesCarrier(&props)
+	return cfg.registry.ExtractCarrier(ctx, c, ctxwire.RequestOnly)
+}
@@ -0,0 +1,42 @@
+package ctxwiremqtt_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwiremqtt"
+)
+
+type deviceKey struct{}
+
+func TestPublish(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("device", deviceKey{})))
+
+	p := paho.Publish{Topic: "sensors/1", Payload: []byte("21.5")}
+	require.NoError(t, ctxwiremqtt.InjectPublish(context.WithValue(context.Background(), deviceKey{}, "d1"), &p,
+		ctxwiremqtt.WithRegistry(r)))
+	require.Equal(t, paho.UserProperties{{Key: "x-ctxwire-device", Value: "d1"}}, p.Properties.User)
+
+	ctx, err := ctxwiremqtt.ExtractPublish(context.Background(), &p, ctxwiremqtt.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "d1", ctx.Value(deviceKey{}))
+
+	ctx, err = ctxwiremqtt.ExtractPublish(context.Background(), &paho.Publish{}, ctxwiremqtt.WithRegistry(r))
+	require.NoError(t, err)
+	require.Nil(t, ctx.Value(deviceKey{}))
+}
+
+func TestUserPropertiesCarrier(t *testing.T) {
+	props := paho.UserProperties{{Key: "origin", Value: "edge"}, {Key: "X-Ctxwire-A", Value: "1"}}
+	c := ctxwiremqtt.NewUserPropertiesCarrier(&props)
+	require.Equal(t, "1", c.Get("x-ctxwire-a"))
+	c.Add("x-ctxwire-a", "2")
+	require.Equal(t, []string{"1", "2"}, c.Values("X-CTXWIRE-A"))
+	require.Equal(t, []string{"origin", "X-Ctxwire-A", "x-ctxwire-a"}, c.Keys())
+	c.Set("X-Ctxwire-A", "3")
+	require.Equal(t, paho.UserProperties{{Key: "origin", Value: "edge"}, {Key: "x-ctxwire-a", Value: "3"}}, props)
+}
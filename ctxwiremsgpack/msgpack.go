@@ -0,0 +1,53 @@
+// Package ctxwiremsgpack provides ctxwire propagators encoding context values
+// with MessagePack, giving smaller header payloads than JSON while keeping
+// schemaless flexibility.
+package ctxwiremsgpack
+
+import (
+	"context"
+
+	"github.com/trezz/ctxwire"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoder encodes context values with MessagePack.
+var Encoder ctxwire.Encoder = ctxwire.EncoderFunc(encode)
+
+// Decoder decodes context values encoded with MessagePack into any values, as
+// ctxwire.NewJSONPropagator does for JSON.
+var Decoder ctxwire.Decoder = ctxwire.DecoderFunc(decode[any])
+
+// NewPropagator returns a new ctxwire.ValuePropagator with the given name
+// configured to encode and decode the context value with MessagePack.
+// The context key is used to store the context value in the context.
+func NewPropagator(name string, contextKey any, opts ...ctxwire.PropagatorOption) *ctxwire.ValuePropagator {
+	opts = append([]ctxwire.PropagatorOption{ctxwire.WithEncoder(Encoder), ctxwire.WithDecoder(Decoder)}, opts...)
+	return ctxwire.NewPropagator(name, contextKey, opts...)
+}
+
+// NewTypedPropagator returns a new ctxwire.ValuePropagator with the given name
+// configured to encode and decode the context value with MessagePack.
+// Unlike NewPropagator, the value is decoded into a T.
+func NewTypedPropagator[T any](name string, contextKey any, opts ...ctxwire.PropagatorOption) *ctxwire.ValuePropagator {
+	opts = append([]ctxwire.PropagatorOption{
+		ctxwire.WithEncoder(Encoder),
+		ctxwire.WithDecoder(ctxwire.DecoderFunc(decode[T])),
+	}, opts...)
+	return ctxwire.NewPropagator(name, contextKey, opts...)
+}
+
+func encode(ctx context.Context, key any) ([]byte, error) {
+	v := ctx.Value(key)
+	if v == nil {
+		return nil, nil
+	}
+	return msgpack.Marshal(v)
+}
+
+func decode[T any](ctx context.Context, key any, data []byte) (context.Context, error) {
+	var v T
+	if err := msgpack.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, key, v), nil
+}
@@ -0,0 +1,38 @@
+package ctxwiremsgpack_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwiremsgpack"
+)
+
+type (
+	attrsKey struct{}
+	userKey  struct{}
+	user     struct {
+		ID   int    `msgpack:"id"`
+		Name string `msgpack:"name"`
+	}
+)
+
+func TestPropagators(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwiremsgpack.NewPropagator("attrs", attrsKey{}),
+		ctxwiremsgpack.NewTypedPropagator[user]("user", userKey{}),
+	))
+
+	ctx := context.WithValue(context.Background(), attrsKey{}, map[string]any{"index": "products"})
+	ctx = context.WithValue(ctx, userKey{}, user{ID: 1, Name: "alice"})
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"index": "products"}, ctx.Value(attrsKey{}))
+	require.Equal(t, user{ID: 1, Name: "alice"}, ctx.Value(userKey{}))
+}
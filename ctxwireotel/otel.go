@@ -0,0 +1,78 @@
+// Package ctxwireotel adapts OpenTelemetry propagators to ctxwire, so that a
+// single registry manages the propagation of both tracing and application
+// context values.
+package ctxwireotel
+
+import (
+	"context"
+
+	"github.com/trezz/ctxwire"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Option configures a Propagator.
+type Option func(p *Propagator)
+
+// WithPriority sets the priority of the propagator. Defaults to 0.
+func WithPriority(priority int) Option {
+	return func(p *Propagator) { p.priority = priority }
+}
+
+// WithDirection restricts the propagator to the given direction. Defaults to
+// ctxwire.RequestOnly, since tracing context flows from clients to servers.
+func WithDirection(direction ctxwire.Direction) Option {
+	return func(p *Propagator) { p.direction = direction }
+}
+
+// Propagator is a ctxwire.Propagator wrapping an OpenTelemetry
+// propagation.TextMapPropagator, such as propagation.TraceContext,
+// propagation.Baggage or a B3 propagator. The contexts it extracts hold the
+// values of the wrapped propagator, as read by the OpenTelemetry APIs.
+type Propagator struct {
+	name       string
+	propagator propagation.TextMapPropagator
+	priority   int
+	direction  ctxwire.Direction
+}
+
+var _ ctxwire.Propagator = (*Propagator)(nil)
+
+// NewPropagator returns a new Propagator with the given name wrapping the
+// given OpenTelemetry propagator.
+//
+//	ctxwire.Configure(
+//		ctxwireotel.NewPropagator("tracecontext", propagation.TraceContext{}),
+//		ctxwire.NewStringPropagator("tenant", tenantKey{}),
+//	)
+func NewPropagator(name string, p propagation.TextMapPropagator, opts ...Option) *Propagator {
+	wrapped := &Propagator{name: name, propagator: p, direction: ctxwire.RequestOnly}
+	for _, opt := range opts {
+		opt(wrapped)
+	}
+	return wrapped
+}
+
+// Name returns the name of the propagator.
+func (p *Propagator) Name() string { return p.name }
+
+// Priority returns the priority of the propagator.
+func (p *Propagator) Priority() int { return p.priority }
+
+// Direction returns the direction in which the propagator propagates its
+// values.
+func (p *Propagator) Direction() ctxwire.Direction { return p.direction }
+
+// HeaderKeys returns the header keys used by the wrapped propagator.
+func (p *Propagator) HeaderKeys() []string { return p.propagator.Fields() }
+
+// Inject implements the ctxwire.Propagator interface.
+func (p *Propagator) Inject(ctx context.Context, c ctxwire.Carrier) error {
+	p.propagator.Inject(ctx, c)
+	return nil
+}
+
+// Extract implements the ctxwire.Propagator interface. OpenTelemetry
+// propagators ignore invalid values, so it never fails.
+func (p *Propagator) Extract(ctx context.Context, c ctxwire.Carrier) (context.Context, error) {
+	return p.propagator.Extract(ctx, c), nil
+}
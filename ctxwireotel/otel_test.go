@@ -0,0 +1,52 @@
+package ctxwireotel_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwireotel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type tenantKey struct{}
+
+const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwireotel.NewPropagator("tracecontext", propagation.TraceContext{}),
+		ctxwireotel.NewPropagator("baggage", propagation.Baggage{}, ctxwireotel.WithDirection(ctxwire.Both)),
+		ctxwire.NewStringPropagator("tenant", tenantKey{}),
+	))
+	require.Equal(t, []string{"traceparent", "tracestate"}, r.Propagators()[0].HeaderKeys)
+	require.Equal(t, ctxwire.RequestOnly, r.Propagators()[0].Direction)
+
+	h := http.Header{}
+	h.Set("traceparent", traceparent)
+	h.Set("baggage", "user=alice")
+	h.Set("x-ctxwire-tenant", "acme")
+	ctx, err := r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	sc := trace.SpanContextFromContext(ctx)
+	require.True(t, sc.IsRemote())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	require.Equal(t, "alice", baggage.FromContext(ctx).Member("user").Value())
+	require.Equal(t, "acme", ctx.Value(tenantKey{}))
+
+	out := http.Header{}
+	require.NoError(t, r.InjectRequest(ctx, out))
+	require.Equal(t, traceparent, out.Get("traceparent"))
+	require.Equal(t, "user=alice", out.Get("baggage"))
+	require.Equal(t, "acme", out.Get("x-ctxwire-tenant"))
+
+	out = http.Header{}
+	require.NoError(t, r.InjectResponse(ctx, out))
+	require.Empty(t, out.Get("traceparent"))
+	require.Equal(t, "user=alice", out.Get("baggage"))
+}
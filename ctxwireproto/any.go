@@ -0,0 +1,58 @@
+package ctxwireproto
+
+import (
+	"context"
+
+	"github.com/trezz/ctxwire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// NewAnyPropagator returns a new ctxwire.ValuePropagator with the given name
+// propagating the proto.Message associated with the given context key,
+// whatever its type, wrapped in a google.protobuf.Any.
+// Extracted values are messages of the type given by the type URL of the Any,
+// resolved with the global registry of the protobuf runtime, so the types
+// must be linked into the receiving binary. Use AnyCodec with
+// ctxwire.WithCodec to resolve the types with another registry.
+func NewAnyPropagator(name string, contextKey any, opts ...ctxwire.PropagatorOption) *ctxwire.ValuePropagator {
+	opts = append([]ctxwire.PropagatorOption{ctxwire.WithCodec(AnyCodec(protoregistry.GlobalTypes))}, opts...)
+	return ctxwire.NewPropagator(name, contextKey, opts...)
+}
+
+// AnyCodec returns a codec encoding proto messages wrapped in a
+// google.protobuf.Any and decoding them into messages of the type resolved
+// from their type URL with the given registry.
+// Values which already are *anypb.Any messages are not wrapped again.
+func AnyCodec(types *protoregistry.Types) ctxwire.Codec {
+	return ctxwire.Codec{
+		Encoder: ctxwire.EncoderFunc(encodeAny),
+		Decoder: ctxwire.DecoderFunc(func(ctx context.Context, key any, data []byte) (context.Context, error) {
+			var a anypb.Any
+			if err := proto.Unmarshal(data, &a); err != nil {
+				return nil, err
+			}
+			m, err := anypb.UnmarshalNew(&a, proto.UnmarshalOptions{Resolver: types})
+			if err != nil {
+				return nil, err
+			}
+			return context.WithValue(ctx, key, m), nil
+		}),
+	}
+}
+
+func encodeAny(ctx context.Context, key any) ([]byte, error) {
+	m, ok := ctx.Value(key).(proto.Message)
+	if !ok {
+		return nil, nil
+	}
+	a, ok := m.(*anypb.Any)
+	if !ok {
+		var err error
+		if a, err = anypb.New(m); err != nil {
+			return nil, err
+		}
+	}
+	return proto.Marshal(a)
+}
@@ -0,0 +1,54 @@
+package ctxwireproto_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwireproto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type eventKey struct{}
+
+func TestAnyPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwireproto.NewAnyPropagator("event", eventKey{})))
+
+	s, err := structpb.NewStruct(map[string]any{"sub": "alice"})
+	require.NoError(t, err)
+	for _, want := range []proto.Message{s, durationpb.New(42), structpb.NewStringValue("foo")} {
+		h, err := r.Preview(context.WithValue(context.Background(), eventKey{}, want))
+		require.NoError(t, err)
+		ctx, err := r.Extract(context.Background(), h)
+		require.NoError(t, err)
+		got, ok := ctx.Value(eventKey{}).(proto.Message)
+		require.True(t, ok)
+		require.True(t, proto.Equal(want, got))
+	}
+
+	// Any messages are not wrapped again.
+	a, err := anypb.New(durationpb.New(42))
+	require.NoError(t, err)
+	h, err := r.Preview(context.WithValue(context.Background(), eventKey{}, a))
+	require.NoError(t, err)
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(durationpb.New(42), ctx.Value(eventKey{}).(proto.Message)))
+
+	// Types unknown to the resolver fail to be decoded.
+	other := ctxwire.NewRegistry()
+	require.NoError(t, other.Configure(ctxwire.NewPropagator("event", eventKey{},
+		ctxwire.WithCodec(ctxwireproto.AnyCodec(new(protoregistry.Types))))))
+	_, err = other.Extract(context.Background(), h)
+	require.ErrorContains(t, err, "decode context value")
+
+	_, err = r.Extract(context.Background(), http.Header{"X-Ctxwire-Event": {"/w=="}})
+	require.ErrorContains(t, err, "decode context value")
+}
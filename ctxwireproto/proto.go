@@ -0,0 +1,45 @@
+// Package ctxwireproto provides ctxwire propagators encoding context values
+// implementing proto.Message with the protobuf binary format.
+// It gives compact payloads and cross-language schemas for polyglot fleets.
+package ctxwireproto
+
+import (
+	"context"
+
+	"github.com/trezz/ctxwire"
+	"google.golang.org/protobuf/proto"
+)
+
+// NewPropagator returns a new ctxwire.ValuePropagator with the given name
+// propagating the *T message associated with the given context key, encoded
+// with the protobuf binary format.
+// Extracted values are *T messages.
+func NewPropagator[T any, M interface {
+	*T
+	proto.Message
+}](name string, contextKey any, opts ...ctxwire.PropagatorOption) *ctxwire.ValuePropagator {
+	opts = append([]ctxwire.PropagatorOption{
+		ctxwire.WithEncoder(ctxwire.EncoderFunc(encode)),
+		ctxwire.WithDecoder(ctxwire.DecoderFunc(decode[T, M])),
+	}, opts...)
+	return ctxwire.NewPropagator(name, contextKey, opts...)
+}
+
+func encode(ctx context.Context, key any) ([]byte, error) {
+	m, ok := ctx.Value(key).(proto.Message)
+	if !ok {
+		return nil, nil
+	}
+	return proto.Marshal(m)
+}
+
+func decode[T any, M interface {
+	*T
+	proto.Message
+}](ctx context.Context, key any, data []byte) (context.Context, error) {
+	m := M(new(T))
+	if err := proto.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, key, m), nil
+}
@@ -0,0 +1,35 @@
+package ctxwireproto_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwireproto"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type claimsKey struct{}
+
+func TestPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwireproto.NewPropagator[structpb.Struct]("claims", claimsKey{})))
+
+	want, err := structpb.NewStruct(map[string]any{"sub": "alice", "admin": true})
+	require.NoError(t, err)
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), claimsKey{}, want), h))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	got, ok := ctx.Value(claimsKey{}).(*structpb.Struct)
+	require.True(t, ok)
+	require.True(t, proto.Equal(want, got))
+
+	h.Set("x-ctxwire-claims", "/w==")
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorContains(t, err, "decode context value")
+}
@@ -0,0 +1,175 @@
+// Package ctxwirepubsub propagates ctxwire context values through Google
+// Cloud Pub/Sub in message attributes, the map[string]string attributes of the
+// Pub/Sub messages.
+// Pub/Sub limits the size of the attribute keys and values, and the number of
+// attributes of a message. Values exceeding the size limit are automatically
+// split across several attributes and reassembled on extraction, and
+// injections return an error rather than exceeding the other limits, which
+// would fail the publication.
+// Messages only flow from publishers to subscribers, so the ResponseOnly
+// propagators don't run.
+package ctxwirepubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/trezz/ctxwire"
+)
+
+const (
+	// MaxKeySize is the maximum size in bytes of an attribute key.
+	MaxKeySize = 256
+	// MaxValueSize is the maximum size in bytes of an attribute value.
+	MaxValueSize = 1024
+	// MaxAttributes is the maximum number of attributes of a message.
+	MaxAttributes = 100
+)
+
+// ErrAttributeLimit is returned when injecting values which would exceed the
+// limits of the Pub/Sub message attributes.
+var ErrAttributeLimit = errors.New("pubsub attribute limit exceeded")
+
+// AttributesCarrier adapts Pub/Sub message attributes to the ctxwire.Carrier
+// interface, enforcing the Pub/Sub limits. Keys are lowercase.
+// Values larger than MaxValueSize are split into attributes named after the
+// key with a ".1" to ".N" suffix.
+type AttributesCarrier struct {
+	attrs map[string]string
+	errs  []error
+}
+
+var _ ctxwire.Carrier = (*AttributesCarrier)(nil)
+
+// NewAttributesCarrier returns a new carrier of the given attributes.
+func NewAttributesCarrier(attrs map[string]string) *AttributesCarrier {
+	return &AttributesCarrier{attrs: attrs}
+}
+
+// Err returns the errors of the values which couldn't be set without exceeding
+// the limits, which are not set, joined together. It returns nil if all the
+// values were set.
+func (c *AttributesCarrier) Err() error { return errors.Join(c.errs...) }
+
+func partKey(key string, i int) string { return key + "." + strconv.Itoa(i) }
+
+// parts returns the number of attributes holding the parts of the value of the
+// given key, or 0 if the value is held by the key itself.
+func (c *AttributesCarrier) parts(key string) int {
+	n := 0
+	for {
+		if _, ok := c.attrs[partKey(key, n+1)]; !ok {
+			return n
+		}
+		n++
+	}
+}
+
+// Get implements the ctxwire.Carrier interface.
+func (c *AttributesCarrier) Get(key string) string {
+	key = strings.ToLower(key)
+	if v, ok := c.attrs[key]; ok {
+		return v
+	}
+	var b strings.Builder
+	for i := 1; i <= c.parts(key); i++ {
+		b.WriteString(c.attrs[partKey(key, i)])
+	}
+	return b.String()
+}
+
+// Set implements the ctxwire.Carrier interface. Values which can't be set
+// without exceeding the limits are recorded as errors returned by Err.
+func (c *AttributesCarrier) Set(key, value string) {
+	key = strings.ToLower(key)
+	n := (len(value) + MaxValueSize - 1) / MaxValueSize
+	longest := key
+	if n > 1 {
+		longest = partKey(key, n)
+	}
+	if len(longest) > MaxKeySize {
+		c.errs = append(c.errs, fmt.Errorf("%w: key %q exceeds %d bytes", ErrAttributeLimit, longest, MaxKeySize))
+		return
+	}
+	existing := c.parts(key)
+	if _, ok := c.attrs[key]; ok {
+		existing++
+	}
+	if count := len(c.attrs) - existing + max(n, 1); count > MaxAttributes {
+		c.errs = append(c.errs, fmt.Errorf("%w: %q exceeds %d attributes", ErrAttributeLimit, key, MaxAttributes))
+		return
+	}
+	c.remove(key)
+	if n <= 1 {
+		c.attrs[key] = value
+		return
+	}
+	for i := 1; i <= n; i++ {
+		c.attrs[partKey(key, i)] = value[(i-1)*MaxValueSize : min(i*MaxValueSize, len(value))]
+	}
+}
+
+// remove removes the value of the given key and its parts.
+func (c *AttributesCarrier) remove(key string) {
+	for i := c.parts(key); i > 0; i-- {
+		delete(c.attrs, partKey(key, i))
+	}
+	delete(c.attrs, key)
+}
+
+// Keys implements the ctxwire.Carrier interface. The keys of the attributes
+// holding the parts of a value are reported as the key of the value.
+func (c *AttributesCarrier) Keys() []string {
+	keys := map[string]bool{}
+	for key := range c.attrs {
+		if i := strings.LastIndexByte(key, '.'); i > 0 {
+			if _, err := strconv.Atoi(key[i+1:]); err == nil {
+				keys[key[:i]] = true
+				continue
+			}
+		}
+		keys[key] = true
+	}
+	return slices.Collect(maps.Keys(keys))
+}
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+}
+
+// WithRegistry makes Inject and Extract propagate the values of the given
+// registry in the message attributes. Defaults to ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Inject injects the context values into the given non-nil message
+// attributes. It returns an ErrAttributeLimit error if some values couldn't be
+// set without exceeding the limits of Pub/Sub.
+func Inject(ctx context.Context, attrs map[string]string, opts ...Option) error {
+	c := NewAttributesCarrier(attrs)
+	err := newConfig(opts).registry.InjectCarrier(ctx, c, ctxwire.RequestOnly)
+	return errors.Join(err, c.Err())
+}
+
+// Extract extracts the context values from the given message attributes into
+// a copy of the given context.
+func Extract(ctx context.Context, attrs map[string]string, opts ...Option) (context.Context, error) {
+	return newConfig(opts).registry.ExtractCarrier(ctx, NewAttributesCarrier(attrs), ctxwire.RequestOnly)
+}
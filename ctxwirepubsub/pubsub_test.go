@@ -0,0 +1,66 @@
+package ctxwirepubsub_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwirepubsub"
+)
+
+type userKey struct{}
+
+type dataKey struct{}
+
+func TestInjectExtract(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", userKey{}),
+		ctxwire.NewStringPropagator("data", dataKey{}),
+	))
+
+	data := strings.Repeat("a", 2*ctxwirepubsub.MaxValueSize+1)
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	ctx = context.WithValue(ctx, dataKey{}, data)
+	attrs := map[string]string{"origin": "test"}
+	require.NoError(t, ctxwirepubsub.Inject(ctx, attrs, ctxwirepubsub.WithRegistry(r)))
+	require.Len(t, attrs, 5)
+	require.Equal(t, "alice", attrs["x-ctxwire-user"])
+	require.Len(t, attrs["x-ctxwire-data.1"], ctxwirepubsub.MaxValueSize)
+	require.Equal(t, "a", attrs["x-ctxwire-data.3"])
+	require.ElementsMatch(t, []string{"origin", "x-ctxwire-user", "x-ctxwire-data"},
+		ctxwirepubsub.NewAttributesCarrier(attrs).Keys())
+
+	got, err := ctxwirepubsub.Extract(context.Background(), attrs, ctxwirepubsub.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "alice", got.Value(userKey{}))
+	require.Equal(t, data, got.Value(dataKey{}))
+
+	// Shorter values replace the parts of the previous ones.
+	require.NoError(t, ctxwirepubsub.Inject(context.WithValue(ctx, dataKey{}, "b"), attrs, ctxwirepubsub.WithRegistry(r)))
+	require.Len(t, attrs, 3)
+	require.Equal(t, "b", attrs["x-ctxwire-data"])
+}
+
+func TestAttributeLimits(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", userKey{}),
+		ctxwire.NewStringPropagator(strings.Repeat("k", ctxwirepubsub.MaxKeySize), dataKey{}),
+	))
+
+	attrs := map[string]string{}
+	for i := range ctxwirepubsub.MaxAttributes {
+		attrs[strconv.Itoa(i)] = ""
+	}
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	ctx = context.WithValue(ctx, dataKey{}, "data")
+	err := ctxwirepubsub.Inject(ctx, attrs, ctxwirepubsub.WithRegistry(r))
+	require.ErrorIs(t, err, ctxwirepubsub.ErrAttributeLimit)
+	require.ErrorContains(t, err, `"x-ctxwire-user" exceeds 100 attributes`)
+	require.ErrorContains(t, err, "exceeds 256 bytes")
+	require.Len(t, attrs, ctxwirepubsub.MaxAttributes)
+}
@@ -0,0 +1,181 @@
+// Package ctxwiresse propagates ctxwire context values over Server-Sent
+// Events streams. Since SSE responses stream long after their headers are
+// sent and can't use trailers, servers write the values as dedicated events
+// of the stream, which clients extract back while reading it.
+//
+// The values are written as an event named "ctxwire" by default, holding one
+// "key: value" data line per header:
+//
+//	event: ctxwire
+//	data: x-ctxwire-user: alice
+package ctxwiresse
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/trezz/ctxwire"
+)
+
+// DefaultEventName is the default name of the events holding the values.
+const DefaultEventName = "ctxwire"
+
+// Option configures the writers and readers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry  *ctxwire.Registry
+	eventName string
+}
+
+// WithRegistry makes WriteValues and the readers carry the values of the
+// given registry in the value events, instead of the values of
+// ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+// WithEventName sets the name of the events holding the values. Defaults to
+// DefaultEventName.
+func WithEventName(name string) Option {
+	return func(c *config) { c.eventName = name }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry(), eventName: DefaultEventName}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WriteValues writes an event holding the context values propagated back to
+// clients, except the RequestOnly ones, to the given SSE stream. Nothing is
+// written if there are no values. If w implements http.Flusher, the event is
+// flushed.
+func WriteValues(ctx context.Context, w io.Writer, opts ...Option) error {
+	cfg := newConfig(opts)
+	c := ctxwire.MapCarrier{}
+	if err := cfg.registry.InjectCarrier(ctx, c, ctxwire.ResponseOnly); err != nil || len(c) == 0 {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", cfg.eventName)
+	for _, key := range slices.Sorted(maps.Keys(c)) {
+		fmt.Fprintf(&b, "data: %s: %s\n", key, c[key])
+	}
+	b.WriteString("\n")
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return err
+	}
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// Event is an event of an SSE stream.
+type Event struct {
+	// Event is the name of the event. It is empty for unnamed events.
+	Event string
+	// Data is the data of the event, its data lines joined by newlines.
+	Data string
+	// ID is the last event ID of the stream.
+	ID string
+}
+
+// Reader reads the events of an SSE stream, extracting the context values of
+// the events written by WriteValues.
+type Reader struct {
+	cfg     *config
+	scanner *bufio.Scanner
+	ctx     context.Context
+	id      string
+}
+
+// NewReader returns a new Reader reading the SSE stream of the given reader.
+// The values are extracted into copies of the given context.
+func NewReader(ctx context.Context, r io.Reader, opts ...Option) *Reader {
+	return &Reader{cfg: newConfig(opts), scanner: bufio.NewScanner(r), ctx: ctx}
+}
+
+// Context returns a copy of the context given to NewReader holding the values
+// extracted from the events read so far.
+func (r *Reader) Context() context.Context { return r.ctx }
+
+// Next returns the next event of the stream, skipping and extracting the
+// events holding context values. It returns io.EOF at the end of the stream.
+func (r *Reader) Next() (Event, error) {
+	for {
+		e, err := r.read()
+		if err != nil {
+			return Event{}, err
+		}
+		if e.Event != r.cfg.eventName {
+			return e, nil
+		}
+		if err := r.extract(e.Data); err != nil {
+			return Event{}, err
+		}
+	}
+}
+
+func (r *Reader) extract(data string) error {
+	c := ctxwire.MapCarrier{}
+	for _, line := range strings.Split(data, "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			return fmt.Errorf("invalid %s event line %q", r.cfg.eventName, line)
+		}
+		c.Set(key, value)
+	}
+	ctx, err := r.cfg.registry.ExtractCarrier(r.ctx, c, ctxwire.ResponseOnly)
+	if err != nil {
+		return err
+	}
+	r.ctx = ctx
+	return nil
+}
+
+// read reads the next event of the stream, following the event stream
+// interpretation rules of the HTML specification. Events without data are
+// skipped.
+func (r *Reader) read() (Event, error) {
+	var name string
+	var data []string
+	for r.scanner.Scan() {
+		line := strings.TrimSuffix(r.scanner.Text(), "\r")
+		if line == "" {
+			if data == nil {
+				name = ""
+				continue
+			}
+			return Event{Event: name, Data: strings.Join(data, "\n"), ID: r.id}, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				r.id = value
+			}
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Event{}, err
+	}
+	return Event{}, io.EOF
+}
@@ -0,0 +1,59 @@
+package ctxwiresse_test
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwiresse"
+)
+
+type userKey struct{}
+
+type progressKey struct{}
+
+func TestStream(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", userKey{}, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewStringPropagator("progress", progressKey{}),
+	))
+
+	w := httptest.NewRecorder()
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+	require.NoError(t, ctxwiresse.WriteValues(ctx, w, ctxwiresse.WithRegistry(r)))
+	require.Empty(t, w.Body.String())
+	require.False(t, w.Flushed)
+
+	w.Body.WriteString(": keep-alive\n\nid: 1\ndata: first\n\n")
+	require.NoError(t, ctxwiresse.WriteValues(context.WithValue(ctx, progressKey{}, "50"), w, ctxwiresse.WithRegistry(r)))
+	require.True(t, w.Flushed)
+	w.Body.WriteString("event: update\r\ndata: second\r\ndata: line\r\n\r\n")
+	require.Contains(t, w.Body.String(), "event: ctxwire\ndata: x-ctxwire-progress: 50\n\n")
+
+	sr := ctxwiresse.NewReader(context.Background(), strings.NewReader(w.Body.String()), ctxwiresse.WithRegistry(r))
+	e, err := sr.Next()
+	require.NoError(t, err)
+	require.Equal(t, ctxwiresse.Event{Data: "first", ID: "1"}, e)
+	require.Nil(t, sr.Context().Value(progressKey{}))
+
+	e, err = sr.Next()
+	require.NoError(t, err)
+	require.Equal(t, ctxwiresse.Event{Event: "update", Data: "second\nline", ID: "1"}, e)
+	require.Equal(t, "50", sr.Context().Value(progressKey{}))
+	require.Nil(t, sr.Context().Value(userKey{}))
+
+	_, err = sr.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
+
+func TestReaderInvalidEvent(t *testing.T) {
+	sr := ctxwiresse.NewReader(context.Background(), strings.NewReader("event: values\ndata: garbage\n\n"),
+		ctxwiresse.WithRegistry(ctxwire.NewRegistry()), ctxwiresse.WithEventName("values"))
+	_, err := sr.Next()
+	require.ErrorContains(t, err, `invalid values event line "garbage"`)
+}
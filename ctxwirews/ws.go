@@ -0,0 +1,119 @@
+// Package ctxwirews propagates ctxwire context values over WebSocket
+// connections. Values are injected into the headers of the handshake, and
+// continue to flow over the established connection in both directions by
+// wrapping messages into an envelope holding the values.
+// The package doesn't depend on a WebSocket implementation: handshake headers
+// and messages are exchanged as http.Header and byte slices.
+package ctxwirews
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/trezz/ctxwire"
+)
+
+// Option configures the helpers of the package.
+type Option func(c *config)
+
+type config struct {
+	registry *ctxwire.Registry
+}
+
+// WithRegistry makes the helpers propagate the values of the given registry
+// in the handshakes and message envelopes, instead of the values of
+// ctxwire.DefaultRegistry.
+func WithRegistry(r *ctxwire.Registry) Option {
+	return func(c *config) { c.registry = r }
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{registry: ctxwire.DefaultRegistry()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *config) inject(ctx context.Context, carrier ctxwire.Carrier, dir ctxwire.Direction) error {
+	return c.registry.InjectCarrier(ctx, carrier, dir)
+}
+
+func (c *config) extract(ctx context.Context, carrier ctxwire.Carrier, dir ctxwire.Direction) (context.Context, error) {
+	return c.registry.ExtractCarrier(ctx, carrier, dir)
+}
+
+// HandshakeHeader returns the headers holding the context values to send with
+// the handshake request when dialing a WebSocket server.
+func HandshakeHeader(ctx context.Context, opts ...Option) (http.Header, error) {
+	h := http.Header{}
+	if err := newConfig(opts).inject(ctx, ctxwire.HeaderCarrier(h), ctxwire.RequestOnly); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ExtractHandshake extracts the context values from the headers of the given
+// handshake request into a copy of its context.
+func ExtractHandshake(r *http.Request, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extract(r.Context(), ctxwire.HeaderCarrier(r.Header), ctxwire.RequestOnly)
+}
+
+// HandshakeResponseHeader returns the headers holding the context values to
+// send back with the handshake response when upgrading a connection.
+func HandshakeResponseHeader(ctx context.Context, opts ...Option) (http.Header, error) {
+	h := http.Header{}
+	if err := newConfig(opts).inject(ctx, ctxwire.HeaderCarrier(h), ctxwire.ResponseOnly); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// ExtractHandshakeResponse extracts the context values from the headers of the
+// given handshake response into a copy of the given context.
+func ExtractHandshakeResponse(ctx context.Context, resp *http.Response, opts ...Option) (context.Context, error) {
+	return newConfig(opts).extract(ctx, ctxwire.HeaderCarrier(resp.Header), ctxwire.ResponseOnly)
+}
+
+// Wrap returns the given payload wrapped into an envelope holding the context
+// values propagated in the given direction: RequestOnly for the messages sent
+// by clients, and ResponseOnly for the messages sent by servers.
+// The envelope is a line holding the values as a JSON object, followed by the
+// payload, so that wrapped text payloads can still be sent as text messages.
+func Wrap(ctx context.Context, dir ctxwire.Direction, payload []byte, opts ...Option) ([]byte, error) {
+	c := ctxwire.MapCarrier{}
+	if err := newConfig(opts).inject(ctx, c, dir); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(map[string]string(c))
+	if err != nil {
+		return nil, fmt.Errorf("marshal message envelope: %w", err)
+	}
+	msg := make([]byte, 0, len(data)+1+len(payload))
+	msg = append(msg, data...)
+	msg = append(msg, '\n')
+	return append(msg, payload...), nil
+}
+
+// Unwrap extracts the context values propagated in the given direction from
+// the envelope of the given message, wrapped with Wrap, into a copy of the
+// given context. It also returns the payload of the message.
+func Unwrap(ctx context.Context, dir ctxwire.Direction, msg []byte, opts ...Option) (context.Context, []byte, error) {
+	data, payload, ok := bytes.Cut(msg, []byte{'\n'})
+	if !ok {
+		return nil, nil, errors.New("missing message envelope")
+	}
+	c := ctxwire.MapCarrier{}
+	if err := json.Unmarshal(data, (*map[string]string)(&c)); err != nil {
+		return nil, nil, fmt.Errorf("unmarshal message envelope: %w", err)
+	}
+	ctx, err := newConfig(opts).extract(ctx, c, dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, payload, nil
+}
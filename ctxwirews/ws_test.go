@@ -0,0 +1,98 @@
+package ctxwirews_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwirews"
+)
+
+type (
+	userKey struct{}
+	roomKey struct{}
+	seqKey  struct{}
+)
+
+func TestHandshake(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", userKey{}),
+		ctxwire.NewStringPropagator("room", roomKey{}, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+
+	h, err := ctxwirews.HandshakeHeader(ctx, ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, http.Header{"X-Ctxwire-User": {"alice"}}, h)
+
+	// The values are extracted from the upgrade request, along with the
+	// WebSocket headers.
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header = h.Clone()
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("X-Ctxwire-Room", "lobby")
+	srvCtx, err := ctxwirews.ExtractHandshake(req, ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "alice", srvCtx.Value(userKey{}))
+	require.Nil(t, srvCtx.Value(roomKey{}), "response-only values are not extracted from the handshake request")
+
+	h, err = ctxwirews.HandshakeResponseHeader(context.WithValue(srvCtx, roomKey{}, "lobby"), ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, http.Header{"X-Ctxwire-User": {"alice"}, "X-Ctxwire-Room": {"lobby"}}, h)
+	resp := &http.Response{StatusCode: http.StatusSwitchingProtocols, Header: h}
+	got, err := ctxwirews.ExtractHandshakeResponse(context.Background(), resp, ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "lobby", got.Value(roomKey{}))
+}
+
+func TestMessages(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("user", userKey{}, ctxwire.WithDirection(ctxwire.RequestOnly))))
+	ctx := context.WithValue(context.Background(), userKey{}, "alice")
+
+	// Text payloads keep their own line breaks.
+	msg, err := ctxwirews.Wrap(ctx, ctxwire.RequestOnly, []byte("hello\nworld"), ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "{\"x-ctxwire-user\":\"alice\"}\nhello\nworld", string(msg))
+	got, payload, err := ctxwirews.Unwrap(context.Background(), ctxwire.RequestOnly, msg, ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "hello\nworld", string(payload))
+	require.Equal(t, "alice", got.Value(userKey{}))
+
+	// Binary payloads are kept as is.
+	binary := []byte{0x00, '\n', 0xff, 0xfe}
+	msg, err = ctxwirews.Wrap(ctx, ctxwire.RequestOnly, binary, ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	_, payload, err = ctxwirews.Unwrap(context.Background(), ctxwire.RequestOnly, msg, ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, binary, payload)
+
+	// Server messages don't carry the request-only values, but still have an
+	// envelope so that they can be unwrapped.
+	msg, err = ctxwirews.Wrap(ctx, ctxwire.ResponseOnly, []byte("ack"), ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "{}\nack", string(msg))
+	got, payload, err = ctxwirews.Unwrap(context.Background(), ctxwire.ResponseOnly, msg, ctxwirews.WithRegistry(r))
+	require.NoError(t, err)
+	require.Equal(t, "ack", string(payload))
+	require.Nil(t, got.Value(userKey{}))
+}
+
+func TestUnwrapErrors(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("seq", seqKey{})))
+
+	_, _, err := ctxwirews.Unwrap(context.Background(), ctxwire.ResponseOnly, []byte("hello"), ctxwirews.WithRegistry(r))
+	require.EqualError(t, err, "missing message envelope")
+	_, _, err = ctxwirews.Unwrap(context.Background(), ctxwire.ResponseOnly, []byte("{\n"), ctxwirews.WithRegistry(r))
+	require.ErrorContains(t, err, "unmarshal message envelope")
+	_, _, err = ctxwirews.Unwrap(context.Background(), ctxwire.ResponseOnly, []byte("{\"x-ctxwire-seq\":\"one\"}\n"), ctxwirews.WithRegistry(r))
+	var e *ctxwire.Error
+	require.ErrorAs(t, err, &e)
+}
@@ -0,0 +1,106 @@
+// Package ctxwirezstd provides a zstd compression wrapper for ctxwire encoders
+// and decoders, with an optional shared dictionary giving much better ratios
+// for small and repetitive payloads such as JSON context values.
+package ctxwirezstd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/trezz/ctxwire"
+)
+
+// Compression flags prefixing compressed payloads.
+const (
+	flagUncompressed byte = iota
+	flagZstd
+)
+
+// Option configures the compression of Compress.
+type Option func(c *compressor)
+
+// WithThreshold sets the size in bytes from which payloads are compressed.
+// Smaller payloads are sent uncompressed. Defaults to 64 bytes.
+func WithThreshold(threshold int) Option {
+	return func(c *compressor) { c.threshold = threshold }
+}
+
+// WithDictionary sets a shared raw dictionary used to compress and decompress
+// the payloads. The content is typically made of sample payloads. The same id
+// and content must be used on both sides.
+func WithDictionary(id uint32, content []byte) Option {
+	return func(c *compressor) {
+		c.encOpts = append(c.encOpts, zstd.WithEncoderDictRaw(id, content))
+		c.decOpts = append(c.decOpts, zstd.WithDecoderDictRaw(id, content))
+	}
+}
+
+// WithMaxDecompressedSize sets the maximum size in bytes of decompressed
+// payloads, protecting receivers from decompression bombs. Defaults to 1 MiB.
+func WithMaxDecompressedSize(size uint64) Option {
+	return func(c *compressor) { c.maxSize = size }
+}
+
+type compressor struct {
+	enc       ctxwire.Encoder
+	dec       ctxwire.Decoder
+	threshold int
+	maxSize   uint64
+	encOpts   []zstd.EOption
+	decOpts   []zstd.DOption
+	zenc      *zstd.Encoder
+	zdec      *zstd.Decoder
+}
+
+// Compress returns an encoder and a decoder wrapping the given ones to compress
+// the encoded payloads larger than a threshold with zstd.
+// Compressed payloads are prefixed with a flag byte, so the returned encoder
+// and decoder must be used on both sides.
+// Mergers implemented by the given decoder must be set explicitly with
+// ctxwire.WithMerger.
+func Compress(enc ctxwire.Encoder, dec ctxwire.Decoder, opts ...Option) (ctxwire.Encoder, ctxwire.Decoder, error) {
+	c := &compressor{enc: enc, dec: dec, threshold: 64, maxSize: 1 << 20}
+	for _, opt := range opts {
+		opt(c)
+	}
+	var err error
+	if c.zenc, err = zstd.NewWriter(nil, c.encOpts...); err != nil {
+		return nil, nil, err
+	}
+	decOpts := append(c.decOpts, zstd.WithDecoderMaxMemory(c.maxSize), zstd.WithDecoderConcurrency(0))
+	if c.zdec, err = zstd.NewReader(nil, decOpts...); err != nil {
+		return nil, nil, err
+	}
+	return ctxwire.EncoderFunc(c.encode), ctxwire.DecoderFunc(c.decode), nil
+}
+
+func (c *compressor) encode(ctx context.Context, key any) ([]byte, error) {
+	data, err := c.enc.Encode(ctx, key)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	if len(data) < c.threshold {
+		return append([]byte{flagUncompressed}, data...), nil
+	}
+	return c.zenc.EncodeAll(data, []byte{flagZstd}), nil
+}
+
+func (c *compressor) decode(ctx context.Context, key any, data []byte) (context.Context, error) {
+	if len(data) == 0 {
+		return nil, errors.New("missing compression flag")
+	}
+	switch data[0] {
+	case flagUncompressed:
+		return c.dec.Decode(ctx, key, data[1:])
+	case flagZstd:
+		decompressed, err := c.zdec.DecodeAll(data[1:], nil)
+		if err != nil {
+			return nil, err
+		}
+		return c.dec.Decode(ctx, key, decompressed)
+	default:
+		return nil, fmt.Errorf("unknown compression flag %#x", data[0])
+	}
+}
@@ -0,0 +1,72 @@
+package ctxwirezstd_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+	"github.com/trezz/ctxwire/ctxwirezstd"
+)
+
+type logKey struct{}
+
+func encodeJSON(ctx context.Context, key any) ([]byte, error) {
+	v := ctx.Value(key)
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+func decodeJSON(ctx context.Context, key any, data []byte) (context.Context, error) {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, key, v), nil
+}
+
+func TestCompress(t *testing.T) {
+	sample := []byte(`{"service":"search","index":"products","user_token":"","latency_ms":0}`)
+	newRegistry := func(opts ...ctxwirezstd.Option) *ctxwire.Registry {
+		enc, dec, err := ctxwirezstd.Compress(
+			ctxwire.EncoderFunc(encodeJSON),
+			ctxwire.DecoderFunc(decodeJSON),
+			opts...,
+		)
+		require.NoError(t, err)
+		r := ctxwire.NewRegistry()
+		require.NoError(t, r.Configure(ctxwire.NewPropagator("log", logKey{}, ctxwire.WithEncoder(enc), ctxwire.WithDecoder(dec))))
+		return r
+	}
+	plain := newRegistry(ctxwirezstd.WithThreshold(1 << 30))
+	compressed := newRegistry(ctxwirezstd.WithThreshold(16), ctxwirezstd.WithDictionary(1, sample))
+
+	v := map[string]any{"service": "search", "index": "products", "user_token": "abc", "latency_ms": float64(42)}
+	ctx := context.WithValue(context.Background(), logKey{}, v)
+	plainHeader, err := plain.Preview(ctx)
+	require.NoError(t, err)
+	compressedHeader, err := compressed.Preview(ctx)
+	require.NoError(t, err)
+	require.Less(t, len(compressedHeader.Get("x-ctxwire-log")), len(plainHeader.Get("x-ctxwire-log")))
+
+	for _, h := range []http.Header{plainHeader, compressedHeader} {
+		ctx, err := compressed.Extract(context.Background(), h)
+		require.NoError(t, err)
+		require.Equal(t, v, ctx.Value(logKey{}))
+	}
+
+	// Decompression fails without the dictionary.
+	_, err = newRegistry().Extract(context.Background(), compressedHeader)
+	require.Error(t, err)
+
+	large := context.WithValue(context.Background(), logKey{}, strings.Repeat("x", 4096))
+	h, err := compressed.Preview(large)
+	require.NoError(t, err)
+	_, err = newRegistry(ctxwirezstd.WithDictionary(1, sample), ctxwirezstd.WithMaxDecompressedSize(1024)).Extract(context.Background(), h)
+	require.Error(t, err)
+}
@@ -0,0 +1,89 @@
+package ctxwire
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// DatadogPropagator propagates the trace identifiers set with
+// ContextWithTraceParent in the "x-datadog-*" headers of Datadog, so that
+// ctxwire deployments can coexist with fleets standardized on Datadog.
+// The lower 64 bits of the trace IDs are propagated in the
+// "x-datadog-trace-id" header, and the upper 64 bits in the "_dd.p.tid" tag
+// of the "x-datadog-tags" header. Positive sampling priorities are extracted
+// as the sampled flag. Invalid incoming values are ignored.
+// It only propagates values from clients to servers.
+// It implements the Propagator interface.
+type DatadogPropagator struct{}
+
+var _ Propagator = (*DatadogPropagator)(nil)
+
+// NewDatadogPropagator returns a new DatadogPropagator.
+func NewDatadogPropagator() *DatadogPropagator { return &DatadogPropagator{} }
+
+const (
+	datadogTraceIDKey  = "x-datadog-trace-id"
+	datadogParentIDKey = "x-datadog-parent-id"
+	datadogPriorityKey = "x-datadog-sampling-priority"
+	datadogTagsKey     = "x-datadog-tags"
+	datadogTIDTag      = "_dd.p.tid"
+)
+
+// Name returns the name of the propagator.
+func (p *DatadogPropagator) Name() string { return "datadog" }
+
+// Direction returns RequestOnly.
+func (p *DatadogPropagator) Direction() Direction { return RequestOnly }
+
+// HeaderKeys returns the header keys used by the propagator.
+func (p *DatadogPropagator) HeaderKeys() []string {
+	return []string{datadogTraceIDKey, datadogParentIDKey, datadogPriorityKey, datadogTagsKey}
+}
+
+// Inject implements the Propagator interface.
+func (p *DatadogPropagator) Inject(ctx context.Context, c Carrier) error {
+	tp, ok := TraceParentFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	c.Set(datadogTraceIDKey, strconv.FormatUint(binary.BigEndian.Uint64(tp.TraceID[8:]), 10))
+	c.Set(datadogParentIDKey, strconv.FormatUint(binary.BigEndian.Uint64(tp.SpanID[:]), 10))
+	priority := "0"
+	if tp.Sampled() {
+		priority = "1"
+	}
+	c.Set(datadogPriorityKey, priority)
+	if high := tp.TraceID[:8]; !isZero(high) {
+		c.Set(datadogTagsKey, datadogTIDTag+"="+hex.EncodeToString(high))
+	}
+	return nil
+}
+
+// Extract implements the Propagator interface.
+func (p *DatadogPropagator) Extract(ctx context.Context, c Carrier) (context.Context, error) {
+	low, err := strconv.ParseUint(c.Get(datadogTraceIDKey), 10, 64)
+	if err != nil || low == 0 {
+		return ctx, nil
+	}
+	spanID, err := strconv.ParseUint(c.Get(datadogParentIDKey), 10, 64)
+	if err != nil || spanID == 0 {
+		return ctx, nil
+	}
+	var tp TraceParent
+	binary.BigEndian.PutUint64(tp.TraceID[8:], low)
+	binary.BigEndian.PutUint64(tp.SpanID[:], spanID)
+	for _, tag := range strings.Split(c.Get(datadogTagsKey), ",") {
+		if k, v, _ := strings.Cut(tag, "="); k == datadogTIDTag {
+			if high, ok := decodeLowerHex(v); ok && len(high) == 8 {
+				copy(tp.TraceID[:8], high)
+			}
+		}
+	}
+	if priority, err := strconv.Atoi(c.Get(datadogPriorityKey)); err == nil && priority > 0 {
+		tp.Flags = 1
+	}
+	return ContextWithTraceParent(ctx, tp), nil
+}
@@ -0,0 +1,52 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestDatadogPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewDatadogPropagator()))
+
+	h := http.Header{}
+	h.Set("x-datadog-trace-id", "7277407061855694839")
+	h.Set("x-datadog-parent-id", "16453819474850114513")
+	h.Set("x-datadog-sampling-priority", "2")
+	h.Set("x-datadog-tags", "_dd.p.dm=-4,_dd.p.tid=80f198ee56343ba8")
+	ctx, err := r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	tp, ok := ctxwire.TraceParentFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-01", tp.String())
+
+	out := http.Header{}
+	require.NoError(t, r.InjectRequest(ctx, out))
+	require.Equal(t, http.Header{
+		"X-Datadog-Trace-Id":          {"7277407061855694839"},
+		"X-Datadog-Parent-Id":         {"16453819474850114513"},
+		"X-Datadog-Sampling-Priority": {"1"},
+		"X-Datadog-Tags":              {"_dd.p.tid=80f198ee56343ba8"},
+	}, out)
+
+	// 64-bit trace IDs have no tags, and dropped traces are not sampled.
+	h.Del("x-datadog-tags")
+	h.Set("x-datadog-sampling-priority", "-1")
+	ctx, err = r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	tp, _ = ctxwire.TraceParentFromContext(ctx)
+	require.Equal(t, "00-000000000000000064fe8b2a57d3eff7-e457b5a2e4d86bd1-00", tp.String())
+	out = http.Header{}
+	require.NoError(t, r.InjectRequest(ctx, out))
+	require.Empty(t, out.Get("x-datadog-tags"))
+
+	h.Set("x-datadog-parent-id", "abc")
+	ctx, err = r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	_, ok = ctxwire.TraceParentFromContext(ctx)
+	require.False(t, ok)
+}
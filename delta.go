@@ -0,0 +1,79 @@
+package ctxwire
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// deltaKey is the context key of the header values extracted by a registry
+// using delta injection.
+type deltaKey struct{ r *Registry }
+
+// WithDeltaInjection makes the registry record the header values it extracts
+// into the context, and skip on Inject the propagators whose header values are
+// identical to the ones extracted. It cuts the response headers of servers
+// merely passing values through.
+// Only propagators exposing their header keys with a HeaderKeys() []string
+// method, such as ValuePropagator, are skipped.
+func WithDeltaInjection() RegistryOption {
+	return func(r *Registry) { r.delta = true }
+}
+
+// recordExtracted returns a copy of the given context recording the header
+// values found in the given carrier for the propagators of the registry.
+func (r *Registry) recordExtracted(ctx context.Context, c Carrier) context.Context {
+	extracted := make(map[string][]string)
+	for _, p := range r.load() {
+		k, ok := p.(headerKeyer)
+		if !ok {
+			continue
+		}
+		for _, key := range k.HeaderKeys() {
+			if vs := values(c, key); len(vs) > 0 {
+				extracted[strings.ToLower(key)] = slices.Clone(vs)
+			}
+		}
+	}
+	return context.WithValue(ctx, deltaKey{r}, extracted)
+}
+
+// multiValued is implemented by propagators adding their values to the ones
+// already held by multi-value carriers instead of replacing them.
+type multiValued interface {
+	multiValued() bool
+}
+
+// injectDelta injects the values of the given propagator into the given
+// carrier, unless its header values are identical to the extracted ones.
+func injectDelta(ctx context.Context, p Propagator, c Carrier, extracted map[string][]string) error {
+	k, ok := p.(headerKeyer)
+	if !ok {
+		return p.Inject(ctx, c)
+	}
+	h := http.Header{}
+	if err := p.Inject(ctx, HeaderCarrier(h)); err != nil {
+		return err
+	}
+	keys := k.HeaderKeys()
+	if !slices.ContainsFunc(keys, func(key string) bool {
+		return !slices.Equal(h.Values(key), extracted[strings.ToLower(key)])
+	}) {
+		return nil
+	}
+	mc, multi := c.(MultiValueCarrier)
+	if m, ok := p.(multiValued); !ok || !m.multiValued() {
+		multi = false
+	}
+	for key, vs := range h {
+		if !multi {
+			c.Set(key, vs[len(vs)-1])
+			continue
+		}
+		for _, v := range vs {
+			mc.Add(key, v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestDeltaInjection(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithDeltaInjection())
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("str", keyStr),
+		ctxwire.NewIntPropagator("int", keyInt),
+	))
+
+	ctx, err := r.Extract(context.Background(), http.Header{
+		"X-Ctxwire-Str": {"foo"},
+		"X-Ctxwire-Int": {"1"},
+	})
+	require.NoError(t, err)
+
+	// Only the changed value is injected.
+	ctx = context.WithValue(ctx, keyInt, 2)
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Equal(t, http.Header{"X-Ctxwire-Int": {"2"}}, h)
+
+	// Registries without delta injection inject all the values.
+	other := ctxwire.NewRegistry()
+	require.NoError(t, other.Configure(ctxwire.NewStringPropagator("str", keyStr)))
+	h = http.Header{}
+	require.NoError(t, other.Inject(ctx, h))
+	require.Equal(t, http.Header{"X-Ctxwire-Str": {"foo"}}, h)
+
+	// Contexts not extracted by the registry inject all the values.
+	h = http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "foo"), h))
+	require.Equal(t, http.Header{"X-Ctxwire-Str": {"foo"}}, h)
+}
+
+func TestDeltaInjectionReplacesValues(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithDeltaInjection())
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("str", keyStr),
+		ctxwire.NewStringPropagator("list", keyInt, ctxwire.WithMultiValue()),
+	))
+	ctx, err := r.Extract(context.Background(), http.Header{
+		"X-Ctxwire-Str":  {"foo"},
+		"X-Ctxwire-List": {"a"},
+	})
+	require.NoError(t, err)
+
+	// Single-value propagators replace the stale values of the carrier, while
+	// multi-value ones add to them.
+	ctx = context.WithValue(context.WithValue(ctx, keyStr, "bar"), keyInt, "b")
+	h := http.Header{"X-Ctxwire-Str": {"stale"}, "X-Ctxwire-List": {"a"}}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Equal(t, http.Header{"X-Ctxwire-Str": {"bar"}, "X-Ctxwire-List": {"a", "b"}}, h)
+}
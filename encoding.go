@@ -0,0 +1,83 @@
+package ctxwire
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+)
+
+// ByteEncoding encodes the bytes produced by encoders into header values.
+type ByteEncoding interface {
+	// EncodeToString returns the header value encoding the given bytes.
+	EncodeToString(src []byte) string
+	// DecodeString returns the bytes encoded in the given header value.
+	DecodeString(s string) ([]byte, error)
+}
+
+var (
+	// Base64 is the standard base64 encoding, with padding. It is the default
+	// byte encoding.
+	Base64 ByteEncoding = base64.StdEncoding
+	// Base64URL is the URL-safe base64 encoding, without padding. It avoids the
+	// '+', '/' and '=' characters mangled by some proxies and logging systems.
+	Base64URL ByteEncoding = base64.RawURLEncoding
+	// Hex is the hexadecimal encoding.
+	Hex ByteEncoding = hexEncoding{}
+	// Raw sends the bytes as is. It must only be used when the encoded values
+	// are already header-safe: values containing control characters, non-ASCII
+	// characters, leading or trailing spaces, or equal to TombstoneValue are
+	// rejected on Inject.
+	Raw ByteEncoding = rawEncoding{}
+)
+
+// byteEncoded is implemented by propagators exposing the byte encoding of
+// their header values. A nil encoding stands for Base64.
+type byteEncoded interface {
+	headerEncoding() ByteEncoding
+}
+
+type hexEncoding struct{}
+
+func (hexEncoding) EncodeToString(src []byte) string      { return hex.EncodeToString(src) }
+func (hexEncoding) DecodeString(s string) ([]byte, error) { return hex.DecodeString(s) }
+
+type rawEncoding struct{}
+
+func (rawEncoding) EncodeToString(src []byte) string      { return string(src) }
+func (rawEncoding) DecodeString(s string) ([]byte, error) { return []byte(s), nil }
+
+var errUnsafeValue = errors.New("value is not header-safe")
+
+// encodeValue encodes the given bytes into a header value using the given
+// byte encoding, or Base64 if nil.
+func encodeValue(enc ByteEncoding, data []byte) (string, error) {
+	if enc == nil {
+		enc = Base64
+	}
+	s := enc.EncodeToString(data)
+	if !headerSafe(s) || s == TombstoneValue {
+		return "", errUnsafeValue
+	}
+	return s, nil
+}
+
+// decodeValue decodes the given header value using the given byte encoding,
+// or Base64 if nil.
+func decodeValue(enc ByteEncoding, s string) ([]byte, error) {
+	if enc == nil {
+		enc = Base64
+	}
+	return enc.DecodeString(s)
+}
+
+// headerSafe reports whether the given value can be sent as is in a header:
+// it is made of printable ASCII characters and doesn't start or end with a
+// space.
+func headerSafe(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return s == "" || (s[0] != ' ' && s[len(s)-1] != ' ')
+}
@@ -0,0 +1,88 @@
+package ctxwire_test
+
+import (
+	"context"
+	"encoding/base32"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestByteEncoding(t *testing.T) {
+	for name, tc := range map[string]struct {
+		enc  ctxwire.ByteEncoding
+		want string
+	}{
+		"base64":    {ctxwire.Base64, "Ij8/fiI="},
+		"base64url": {ctxwire.Base64URL, "Ij8_fiI"},
+		"hex":       {ctxwire.Hex, "223f3f7e22"},
+		"raw":       {ctxwire.Raw, `"??~"`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := ctxwire.NewRegistry()
+			require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithByteEncoding(tc.enc))))
+
+			h := http.Header{}
+			require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "??~"), h))
+			require.Equal(t, tc.want, h.Get("x-ctxwire-str"))
+
+			ctx, report, err := r.ExtractWithReport(context.Background(), h)
+			require.NoError(t, err)
+			require.Equal(t, "??~", ctx.Value(keyStr))
+			require.Equal(t, 5, report.Propagators[0].Size)
+		})
+	}
+}
+
+func TestByteEncodingRawUnsafe(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithByteEncoding(ctxwire.Raw))))
+
+	h := http.Header{}
+	err := r.Inject(context.WithValue(context.Background(), keyStr, "café"), h)
+	require.EqualError(t, err, "encode context value: value is not header-safe")
+	require.Empty(t, h)
+
+	h.Set("x-ctxwire-str", "not json")
+	_, err = r.Extract(context.Background(), h)
+	require.Error(t, err)
+}
+
+func TestByteEncodingInvalid(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithByteEncoding(ctxwire.Hex))))
+
+	_, err := r.Extract(context.Background(), http.Header{"X-Ctxwire-Str": {"zz"}})
+	var ctxErr *ctxwire.Error
+	require.ErrorAs(t, err, &ctxErr)
+	require.Equal(t, ctxwire.OpDecode, ctxErr.Op())
+}
+
+func TestDefaultByteEncoding(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithDefaultByteEncoding(base32.StdEncoding))
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("int", keyInt, ctxwire.WithByteEncoding(ctxwire.Hex)),
+		ctxwire.NewMultiPropagator("multi", keyLog),
+	))
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	ctx = context.WithValue(ctx, keyLog, "bar")
+	h, err := r.Preview(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.Header{
+		"X-Ctxwire-Str":   {"EJTG63ZC"},
+		"X-Ctxwire-Int":   {"3432"},
+		"X-Ctxwire-Multi": {"LMRGEYLSEJOQ===="},
+	}, h)
+
+	got, report, err := r.ExtractWithReport(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", got.Value(keyStr))
+	require.Equal(t, float64(42), got.Value(keyInt))
+	require.Equal(t, "bar", got.Value(keyLog))
+	require.Equal(t, 5, report.Propagators[0].Size)
+}
@@ -0,0 +1,121 @@
+package ctxwire
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidCiphertext is returned when decrypting a payload which wasn't
+// encrypted with the key of the id it carries, or was tampered with.
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+type encrypter struct {
+	enc   Encoder
+	dec   Decoder
+	keyID string
+	keys  KeyProvider
+}
+
+// Encrypt returns an encoder and a decoder wrapping the given ones to encrypt
+// the encoded payloads with AES-GCM, so that sensitive values, such as user
+// identities or claims, can cross semi-trusted proxies without showing up in
+// their access logs. The encoder encrypts the payloads with the key of the
+// given id, which must be 16, 24 or 32 bytes long to select AES-128, AES-192 or
+// AES-256, and the decoder decrypts them with the key of the id they carry,
+// rejecting tampered payloads, and payloads moved to the header of another
+// propagator, with ErrInvalidCiphertext. Key ids are made of at most 255 bytes
+// and are sent in clear. With an empty key id and a PrimaryKeyProvider, such
+// as a KeyRing, the payloads are encrypted with its current primary key, so
+// that keys can rotate.
+// Mergers implemented by the given decoder must be set explicitly with
+// WithMerger.
+func Encrypt(enc Encoder, dec Decoder, keyID string, keys KeyProvider) (Encoder, Decoder) {
+	if len(keyID) > maxKeyIDLen {
+		panic(fmt.Sprintf("ctxwire: Encrypt: key id %q exceeds %d bytes", keyID, maxKeyIDLen))
+	}
+	e := &encrypter{enc: enc, dec: dec, keyID: keyID, keys: keys}
+	return EncoderFunc(e.encode), DecoderFunc(e.decode)
+}
+
+// EncryptMiddleware returns a middleware encrypting the payloads as Encrypt
+// does.
+func EncryptMiddleware(keyID string, keys KeyProvider) CodecMiddleware {
+	return func(c Codec) Codec {
+		enc, dec := Encrypt(c.Encoder, c.Decoder, keyID, keys)
+		return Codec{Encoder: enc, Decoder: dec}
+	}
+}
+
+// Encrypted payloads are made of the length of the key id on one byte, the key
+// id, the nonce and the sealed payload, authenticated along with the key id
+// and the header key of the value.
+func (e *encrypter) encode(ctx context.Context, key any) ([]byte, error) {
+	data, err := e.enc.Encode(ctx, key)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	keyID, secret, err := encodingKey(e.keys, e.keyID)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %q: %w", keyID, err)
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %q: %w", keyID, err)
+	}
+	out := make([]byte, 0, 1+len(keyID)+aead.NonceSize()+len(data)+aead.Overhead())
+	out = append(out, byte(len(keyID)))
+	out = append(out, keyID...)
+	header := len(out)
+	out = out[:header+aead.NonceSize()]
+	if _, err := rand.Read(out[header:]); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, out[header:], data, additionalData(ctx, out[:header])), nil
+}
+
+func (e *encrypter) decode(ctx context.Context, key any, data []byte) (context.Context, error) {
+	if len(data) == 0 || len(data) < 1+int(data[0]) {
+		return nil, ErrInvalidCiphertext
+	}
+	header := 1 + int(data[0])
+	keyID := string(data[1:header])
+	secret, err := e.keys.Key(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("decryption key %q: %w", keyID, err)
+	}
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return nil, fmt.Errorf("decryption key %q: %w", keyID, err)
+	}
+	if len(data) < header+aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, sealed := data[header:header+aead.NonceSize()], data[header+aead.NonceSize():]
+	payload, err := aead.Open(nil, nonce, sealed, additionalData(ctx, data[:header]))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return e.dec.Decode(ctx, key, payload)
+}
+
+// additionalData returns the data authenticated along with the payloads: the
+// header key of the value and the given header of the encrypted payload, so
+// that ciphertexts can't be moved to the header of another propagator sharing
+// the key.
+func additionalData(ctx context.Context, header []byte) []byte {
+	ad := append([]byte(headerKeyFrom(ctx)), 0)
+	return append(ad, header...)
+}
+
+// newAEAD returns the AES-GCM cipher using the given key.
+func newAEAD(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,67 @@
+package ctxwire_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestEncrypt(t *testing.T) {
+	keys := keyProvider(map[string]string{"k1": strings.Repeat("k", 32), "short": "secret"})
+	r := ctxwire.NewRegistry()
+	codec := ctxwire.JSONCodec.Wrap(ctxwire.EncryptMiddleware("k1", keys))
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithCodec(codec))))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "alice@example.com"), h))
+	data, err := base64.StdEncoding.DecodeString(h.Get("x-ctxwire-str"))
+	require.NoError(t, err)
+	require.NotContains(t, string(data), "alice")
+	require.Equal(t, "\x02k1", string(data[:3]))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "alice@example.com", ctx.Value(keyStr))
+
+	other := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "alice@example.com"), other))
+	require.NotEqual(t, h.Get("x-ctxwire-str"), other.Get("x-ctxwire-str"))
+
+	data[len(data)-1] ^= 1
+	h.Set("x-ctxwire-str", base64.StdEncoding.EncodeToString(data))
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorIs(t, err, ctxwire.ErrInvalidCiphertext)
+
+	h.Set("x-ctxwire-str", base64.StdEncoding.EncodeToString([]byte("\x05k1")))
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorIs(t, err, ctxwire.ErrInvalidCiphertext)
+
+	h.Set("x-ctxwire-str", base64.StdEncoding.EncodeToString([]byte("\x02k3")))
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorIs(t, err, errUnknownKey)
+
+	enc, _ := ctxwire.Encrypt(ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON), "short", keys)
+	_, err = enc.Encode(context.WithValue(context.Background(), keyStr, "v"), keyStr)
+	require.ErrorContains(t, err, `encryption key "short"`)
+}
+
+func TestEncryptHeaderBinding(t *testing.T) {
+	keys := keyProvider(map[string]string{"k1": strings.Repeat("k", 32)})
+	codec := ctxwire.JSONCodec.Wrap(ctxwire.EncryptMiddleware("k1", keys))
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewPropagator("user", keyStr, ctxwire.WithCodec(codec)),
+		ctxwire.NewPropagator("admin", keyInt, ctxwire.WithCodec(codec)),
+	))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "alice"), h))
+	h.Set("x-ctxwire-admin", h.Get("x-ctxwire-user"))
+	_, err := r.Extract(context.Background(), h)
+	require.ErrorIs(t, err, ctxwire.ErrInvalidCiphertext)
+}
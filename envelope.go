@@ -0,0 +1,207 @@
+package ctxwire
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// envelopeFormat is the format of the envelope header of a registry.
+type envelopeFormat int
+
+const (
+	noEnvelope envelopeFormat = iota
+	jsonEnvelope
+	compactEnvelope
+)
+
+// WithEnvelope makes the registry serialize the values of all its propagators
+// into a single envelope header, named after the header prefix of the registry
+// without its trailing dash ("x-ctxwire" by default).
+// The envelope is a JSON object mapping the header keys of the propagators,
+// without the header prefix, to their values. It reduces the per-header
+// overhead and lets gateways allowlist exactly one header.
+// On Extract, carriers without an envelope header are read as is, allowing
+// a gradual migration of the senders. Both the JSON and the compact envelopes
+// are accepted.
+func WithEnvelope() RegistryOption {
+	return func(r *Registry) { r.envelope = jsonEnvelope }
+}
+
+// WithCompactEnvelope is like WithEnvelope, but serializes the envelope in a
+// length-prefixed binary format encoded with unpadded URL-safe base64.
+// Each value is stored as its name, an encoding id and its payload, base64
+// values being stored decoded. The compact envelope is significantly smaller
+// than the JSON one and cheaper to parse.
+func WithCompactEnvelope() RegistryOption {
+	return func(r *Registry) { r.envelope = compactEnvelope }
+}
+
+// envelopeKey returns the key of the envelope header of the registry.
+func (r *Registry) envelopeKey() string {
+	return strings.TrimSuffix(r.naming.headerPrefix(), "-")
+}
+
+// injectEnvelope injects the context values into the envelope header of the
+// given carrier.
+func (r *Registry) injectEnvelope(ctx context.Context, c Carrier, s scope) error {
+	env := newEnvelopeCarrier(r.naming.headerPrefix())
+	err := r.inject(ctx, env, s)
+	if err != nil && r.errorPolicy == FailFast {
+		return err
+	}
+	if len(env.values) == 0 {
+		return err
+	}
+	v, encErr := env.marshal(r.envelope)
+	if encErr != nil {
+		return newError(OpInject, r.envelopeKey(), "encode envelope", encErr)
+	}
+	c.Set(r.envelopeKey(), v)
+	return err
+}
+
+// openEnvelope returns the carrier holding the values of the envelope header
+// of the given carrier, or the given carrier if it has no envelope header.
+func (r *Registry) openEnvelope(c Carrier) (Carrier, error) {
+	v := c.Get(r.envelopeKey())
+	if v == "" {
+		return c, nil
+	}
+	env := newEnvelopeCarrier(r.naming.headerPrefix())
+	if err := env.unmarshal(v); err != nil {
+		return nil, newError(OpExtract, r.envelopeKey(), "decode envelope", err)
+	}
+	return env, nil
+}
+
+// envelopeCarrier is the in-memory carrier of the values of an envelope.
+// Keys are case-insensitive and stored without the header prefix.
+type envelopeCarrier struct {
+	prefix string
+	values map[string][]string
+}
+
+var _ MultiValueCarrier = (*envelopeCarrier)(nil)
+
+func newEnvelopeCarrier(prefix string) *envelopeCarrier {
+	return &envelopeCarrier{prefix: strings.ToLower(prefix), values: make(map[string][]string)}
+}
+
+func (c *envelopeCarrier) name(key string) string {
+	return strings.TrimPrefix(strings.ToLower(key), c.prefix)
+}
+
+func (c *envelopeCarrier) Get(key string) string {
+	if vs := c.values[c.name(key)]; len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+func (c *envelopeCarrier) Set(key, value string) { c.values[c.name(key)] = []string{value} }
+
+// Keys returns the keys of the envelope, with the header prefix.
+func (c *envelopeCarrier) Keys() []string {
+	keys := slices.Collect(maps.Keys(c.values))
+	for i, name := range keys {
+		keys[i] = c.prefix + name
+	}
+	return keys
+}
+
+func (c *envelopeCarrier) Values(key string) []string { return c.values[c.name(key)] }
+
+func (c *envelopeCarrier) Add(key, value string) {
+	name := c.name(key)
+	c.values[name] = append(c.values[name], value)
+}
+
+// Encoding ids of the values of compact envelopes.
+const (
+	// envelopeString values are stored as is.
+	envelopeString byte = iota
+	// envelopeBase64 values are stored decoded from standard base64.
+	envelopeBase64
+)
+
+// marshal returns the envelope header value of the carrier in the given format.
+func (c *envelopeCarrier) marshal(format envelopeFormat) (string, error) {
+	if format == jsonEnvelope {
+		data, err := json.Marshal(c.values)
+		return string(data), err
+	}
+	var buf []byte
+	for _, name := range slices.Sorted(maps.Keys(c.values)) {
+		for _, v := range c.values[name] {
+			id, payload := envelopeString, []byte(v)
+			if data, err := base64.StdEncoding.DecodeString(v); err == nil && base64.StdEncoding.EncodeToString(data) == v {
+				id, payload = envelopeBase64, data
+			}
+			buf = binary.AppendUvarint(buf, uint64(len(name)))
+			buf = append(buf, name...)
+			buf = append(buf, id)
+			buf = binary.AppendUvarint(buf, uint64(len(payload)))
+			buf = append(buf, payload...)
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+var errTruncatedEnvelope = errors.New("truncated envelope")
+
+// unmarshal sets the values of the carrier from the given envelope header
+// value, in any format.
+func (c *envelopeCarrier) unmarshal(v string) error {
+	if strings.HasPrefix(v, "{") {
+		return json.Unmarshal([]byte(v), &c.values)
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(v)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(buf)
+	for r.Len() > 0 {
+		name, err := readEnvelopeBytes(r)
+		if err != nil {
+			return err
+		}
+		id, err := r.ReadByte()
+		if err != nil {
+			return errTruncatedEnvelope
+		}
+		payload, err := readEnvelopeBytes(r)
+		if err != nil {
+			return err
+		}
+		var value string
+		switch id {
+		case envelopeString:
+			value = string(payload)
+		case envelopeBase64:
+			value = base64.StdEncoding.EncodeToString(payload)
+		default:
+			return fmt.Errorf("unknown envelope encoding id %#x", id)
+		}
+		c.values[string(name)] = append(c.values[string(name)], value)
+	}
+	return nil
+}
+
+// readEnvelopeBytes reads a length-prefixed byte slice from r.
+func readEnvelopeBytes(r *bytes.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil || n > uint64(r.Len()) {
+		return nil, errTruncatedEnvelope
+	}
+	b := make([]byte, n)
+	_, _ = r.Read(b)
+	return b, nil
+}
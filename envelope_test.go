@@ -0,0 +1,87 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestEnvelope(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithEnvelope())
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("int", keyInt),
+	))
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Equal(t, http.Header{"X-Ctxwire": {`{"int":["NDI="],"str":["foo"]}`}}, h)
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+	require.Equal(t, float64(42), ctx.Value(keyInt))
+
+	// Carriers without envelope are read as is.
+	h = http.Header{"X-Ctxwire-Str": {"bar"}}
+	ctx, err = r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "bar", ctx.Value(keyStr))
+
+	h = http.Header{"X-Ctxwire": {"{"}}
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorContains(t, err, "decode envelope")
+}
+
+func TestEnvelopeHeaderPrefix(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithEnvelope(), ctxwire.WithHeaderPrefix("x-acme-"))
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("str", keyStr)))
+
+	h, err := r.Preview(context.WithValue(context.Background(), keyStr, "foo"))
+	require.NoError(t, err)
+	require.Equal(t, http.Header{"X-Acme": {`{"str":["foo"]}`}}, h)
+
+	// Nothing is injected without values.
+	h, err = r.Preview(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, h)
+}
+
+func TestCompactEnvelope(t *testing.T) {
+	propagators := []ctxwire.Propagator{
+		ctxwire.NewStringPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("log", keyLog),
+	}
+	jsonRegistry := ctxwire.NewRegistry(ctxwire.WithEnvelope())
+	require.NoError(t, jsonRegistry.Configure(propagators...))
+	compactRegistry := ctxwire.NewRegistry(ctxwire.WithCompactEnvelope())
+	require.NoError(t, compactRegistry.Configure(propagators...))
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyLog, map[string]any{"service": "search", "index": "products"})
+	jsonHeader, err := jsonRegistry.Preview(ctx)
+	require.NoError(t, err)
+	compactHeader, err := compactRegistry.Preview(ctx)
+	require.NoError(t, err)
+	require.Less(t, len(compactHeader.Get("x-ctxwire")), len(jsonHeader.Get("x-ctxwire")))
+
+	// Both formats are accepted by both registries.
+	for _, r := range []*ctxwire.Registry{jsonRegistry, compactRegistry} {
+		for _, h := range []http.Header{jsonHeader, compactHeader} {
+			got, err := r.Extract(context.Background(), h)
+			require.NoError(t, err)
+			require.Equal(t, "foo", got.Value(keyStr))
+			require.Equal(t, ctx.Value(keyLog), got.Value(keyLog))
+		}
+	}
+
+	for _, v := range []string{"AQ", "A3N0cgkA", "!"} {
+		_, err := compactRegistry.Extract(context.Background(), http.Header{"X-Ctxwire": {v}})
+		require.ErrorContains(t, err, "decode envelope", v)
+	}
+}
@@ -0,0 +1,84 @@
+package ctxwire
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Op is the operation during which an error occurred.
+type Op string
+
+// Operations reported by errors.
+const (
+	OpConfigure Op = "configure"
+	OpInject    Op = "inject"
+	OpExtract   Op = "extract"
+	OpEncode    Op = "encode"
+	OpDecode    Op = "decode"
+	OpMerge     Op = "merge"
+)
+
+// Error is the error type used by the package.
+// It wraps the original error and adds a message, along with metadata about
+// the failing operation.
+type Error struct {
+	op         Op
+	propagator string
+	headerKey  string
+	message    string
+	err        error
+}
+
+var _ error = (*Error)(nil)
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	if e.message == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.message, e.err.Error())
+}
+
+// Unwrap implements the errors.Wrapper interface.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Op returns the operation during which the error occurred.
+func (e *Error) Op() Op { return e.op }
+
+// PropagatorName returns the name of the failing propagator, or an empty
+// string if unknown.
+func (e *Error) PropagatorName() string { return e.propagator }
+
+// HeaderKey returns the header key of the failing value, or an empty string if
+// unknown.
+func (e *Error) HeaderKey() string { return e.headerKey }
+
+// newError returns a new *Error wrapping err, unless err already wraps one.
+func newError(op Op, headerKey, message string, err error) error {
+	var ctxwireErr *Error
+	if errors.As(err, &ctxwireErr) {
+		return err
+	}
+	return &Error{op: op, headerKey: headerKey, message: message, err: err}
+}
+
+// withPropagator returns err with the name of the given propagator set in the
+// *Error it wraps, if not set already. The *Error is copied rather than
+// modified, since propagators may return the same error to concurrent calls.
+func withPropagator(p Propagator, err error) error {
+	var ctxwireErr *Error
+	n, ok := p.(named)
+	if !ok || !errors.As(err, &ctxwireErr) || ctxwireErr.propagator != "" {
+		return err
+	}
+	if err == error(ctxwireErr) {
+		cp := *ctxwireErr
+		cp.propagator = n.Name()
+		return &cp
+	}
+	// The *Error is wrapped by other errors: wrap them in turn, without a
+	// message, so that the named copy is found first.
+	return &Error{op: ctxwireErr.op, propagator: n.Name(), headerKey: ctxwireErr.headerKey, err: err}
+}
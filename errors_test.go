@@ -0,0 +1,93 @@
+package ctxwire_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type plainPropagator struct{}
+
+func (plainPropagator) Name() string { return "plain" }
+
+func (plainPropagator) Inject(context.Context, ctxwire.Carrier) error {
+	return errors.New("failed!")
+}
+
+func (plainPropagator) Extract(ctx context.Context, _ ctxwire.Carrier) (context.Context, error) {
+	return ctx, nil
+}
+
+type sharedErrPropagator struct {
+	name string
+	err  error
+}
+
+func (p sharedErrPropagator) Name() string { return p.name }
+
+func (p sharedErrPropagator) Inject(context.Context, ctxwire.Carrier) error {
+	return p.err
+}
+
+func (sharedErrPropagator) Extract(ctx context.Context, _ ctxwire.Carrier) (context.Context, error) {
+	return ctx, nil
+}
+
+func TestErrorMetadataCopy(t *testing.T) {
+	inner := ctxwire.NewPropagator("inner", keyEncode, ctxwire.WithEncoder(ctxwire.EncoderFunc(errEncoder)))
+	shared := inner.Inject(context.WithValue(context.Background(), keyEncode, "foo"), ctxwire.MapCarrier{})
+	var ctxwireErr *ctxwire.Error
+	require.ErrorAs(t, shared, &ctxwireErr)
+	require.Empty(t, ctxwireErr.PropagatorName())
+
+	for _, err := range []error{shared, fmt.Errorf("wrapped: %w", shared)} {
+		r := ctxwire.NewRegistry()
+		require.NoError(t, r.Configure(sharedErrPropagator{name: "outer", err: err}))
+		got := r.Inject(context.Background(), http.Header{})
+		require.EqualError(t, got, err.Error())
+		require.ErrorAs(t, got, &ctxwireErr)
+		require.Equal(t, "outer", ctxwireErr.PropagatorName())
+		require.Equal(t, ctxwire.OpEncode, ctxwireErr.Op())
+		require.Equal(t, "x-ctxwire-inner", ctxwireErr.HeaderKey())
+
+		require.ErrorAs(t, shared, &ctxwireErr)
+		require.Empty(t, ctxwireErr.PropagatorName())
+	}
+}
+
+func TestErrorMetadata(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewPropagator("decode", keyDecode, ctxwire.WithDecoder(ctxwire.DecoderFunc(errDecoder))),
+	))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyDecode, "foo"), h))
+	_, err := r.Extract(context.Background(), h)
+	var ctxwireErr *ctxwire.Error
+	require.ErrorAs(t, err, &ctxwireErr)
+	require.Equal(t, ctxwire.OpDecode, ctxwireErr.Op())
+	require.Equal(t, "decode", ctxwireErr.PropagatorName())
+	require.Equal(t, "x-ctxwire-decode", ctxwireErr.HeaderKey())
+
+	h.Set("x-ctxwire-decode", "not base64!")
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorAs(t, err, &ctxwireErr)
+	require.Equal(t, ctxwire.OpDecode, ctxwireErr.Op())
+	require.Equal(t, "decode", ctxwireErr.PropagatorName())
+	require.Equal(t, "x-ctxwire-decode", ctxwireErr.HeaderKey())
+
+	r.Reset()
+	require.NoError(t, r.Configure(plainPropagator{}))
+	err = r.Inject(context.Background(), h)
+	require.EqualError(t, err, "inject context values: failed!")
+	require.ErrorAs(t, err, &ctxwireErr)
+	require.Equal(t, ctxwire.OpInject, ctxwireErr.Op())
+	require.Equal(t, "plain", ctxwireErr.PropagatorName())
+	require.Empty(t, ctxwireErr.HeaderKey())
+}
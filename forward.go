@@ -0,0 +1,97 @@
+package ctxwire
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+type forwardKey struct{}
+
+// forwarded is a header captured from an inbound request to be forwarded to
+// the outbound ones.
+type forwarded struct {
+	// name is the name of the propagator using the header, or the key of the
+	// header without the header prefix.
+	name   string
+	values []string
+}
+
+// WithForwardChaining makes the middleware capture the ctxwire headers of the
+// requests, so that the Transport forwards them to the outbound requests sent
+// with the request context, even when the registry doesn't know them. It
+// allows multi-hop chains to propagate values without every service
+// registering their propagators.
+// Only the headers of the given propagator names are forwarded, the names of
+// the unknown headers being their keys without the header prefix, or all of
+// them if no name is given. The headers of the ResponseOnly propagators are
+// not forwarded, nor the version and capability headers of the registry, and
+// the ones already injected by the Transport are not overwritten. As the
+// registered values, the captured headers are only forwarded to the hosts
+// allowed by the Transport.
+func WithForwardChaining(names ...string) HandlerOption {
+	return func(h *handler) {
+		h.forward = true
+		h.forwardNames = names
+	}
+}
+
+// captureForwarded returns a copy of the given context holding the headers of
+// the registry to forward, among the given request headers.
+func (r *Registry) captureForwarded(ctx context.Context, h http.Header, names []string) context.Context {
+	allowed := map[string]bool{}
+	for _, name := range names {
+		allowed[name] = true
+	}
+	owners := map[string]PropagatorInfo{}
+	for _, info := range r.Propagators() {
+		for _, key := range info.HeaderKeys {
+			owners[http.CanonicalHeaderKey(key)] = info
+		}
+	}
+	control := map[string]bool{
+		http.CanonicalHeaderKey(r.versionKey()):    true,
+		http.CanonicalHeaderKey(r.acceptKey()):     true,
+		http.CanonicalHeaderKey(r.acceptTagsKey()): true,
+	}
+	prefix := strings.ToLower(r.naming.headerPrefix())
+	captured := map[string]forwarded{}
+	for key, vs := range h {
+		ck := http.CanonicalHeaderKey(key)
+		if control[ck] {
+			continue
+		}
+		f := forwarded{values: slices.Clone(vs)}
+		if info, ok := owners[ck]; ok {
+			if info.Direction == ResponseOnly {
+				continue
+			}
+			f.name = info.Name
+		} else if prefix != "" && strings.HasPrefix(strings.ToLower(key), prefix) {
+			f.name = strings.ToLower(key[len(prefix):])
+		} else {
+			continue
+		}
+		if len(allowed) == 0 || allowed[f.name] {
+			captured[ck] = f
+		}
+	}
+	if len(captured) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, forwardKey{}, captured)
+}
+
+// injectForwarded sets the headers captured in the given context into the
+// given request headers, unless already set or suppressed.
+func injectForwarded(ctx context.Context, h http.Header) {
+	captured, _ := ctx.Value(forwardKey{}).(map[string]forwarded)
+	s := suppressionFrom(ctx)
+	for key, f := range captured {
+		if len(h.Values(key)) > 0 || (s != nil && (s.all || s.names[f.name])) {
+			continue
+		}
+		h[key] = append([]string(nil), f.values...)
+	}
+}
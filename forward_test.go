@@ -0,0 +1,102 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestForwardChaining(t *testing.T) {
+	var received http.Header
+	downstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		received = req.Header
+	}))
+	defer downstream.Close()
+
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", keyStr),
+		ctxwire.NewIntPropagator("count", keyInt, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	client := ctxwire.NewClient(nil, ctxwire.WithTransportRegistry(r))
+	call := func(ctx context.Context) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, downstream.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+	serve := func(h http.Handler) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("x-ctxwire-user", "alice")
+		req.Header.Set("x-ctxwire-count", "1")
+		req.Header.Set("x-ctxwire-tenant", "acme")
+		req.Header.Add("x-ctxwire-debug", "a")
+		req.Header.Add("x-ctxwire-debug", "b")
+		req.Header.Set("x-other", "1")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(r.Handler(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		call(context.WithValue(req.Context(), keyStr, "bob"))
+	}), ctxwire.WithForwardChaining()))
+	require.Equal(t, "bob", received.Get("x-ctxwire-user"))
+	require.Equal(t, "acme", received.Get("x-ctxwire-tenant"))
+	require.Equal(t, []string{"a", "b"}, received.Values("x-ctxwire-debug"))
+	require.Empty(t, received.Get("x-ctxwire-count"))
+	require.Empty(t, received.Get("x-other"))
+
+	serve(r.Handler(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		call(ctxwire.Suppress(req.Context(), "user"))
+	}), ctxwire.WithForwardChaining("user", "tenant")))
+	require.Empty(t, received.Get("x-ctxwire-user"))
+	require.Equal(t, "acme", received.Get("x-ctxwire-tenant"))
+	require.Empty(t, received.Get("x-ctxwire-debug"))
+
+	// Without forward chaining, only the registered values are propagated.
+	serve(r.Handler(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		call(req.Context())
+	})))
+	require.Equal(t, "alice", received.Get("x-ctxwire-user"))
+	require.Empty(t, received.Get("x-ctxwire-tenant"))
+}
+
+func TestForwardChainingControlHeaders(t *testing.T) {
+	var received http.Header
+	downstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		received = req.Header
+	}))
+	defer downstream.Close()
+
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("user", keyStr)))
+	serve := func(client *http.Client) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("x-ctxwire-tenant", "acme")
+		req.Header.Set("x-ctxwire-version", "2")
+		req.Header.Set("x-ctxwire-accept", "user")
+		req.Header.Set("x-ctxwire-accept-tags", "j")
+		r.Handler(http.HandlerFunc(func(_ http.ResponseWriter, in *http.Request) {
+			// The captured headers don't alias the ones of the inbound request.
+			in.Header["X-Ctxwire-Tenant"][0] = "other"
+			out, err := http.NewRequestWithContext(in.Context(), http.MethodGet, downstream.URL, nil)
+			require.NoError(t, err)
+			resp, err := client.Do(out)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+		}), ctxwire.WithForwardChaining()).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(ctxwire.NewClient(nil, ctxwire.WithTransportRegistry(r)))
+	require.Equal(t, "acme", received.Get("x-ctxwire-tenant"))
+	require.Empty(t, received.Get("x-ctxwire-version"))
+	require.Empty(t, received.Get("x-ctxwire-accept"))
+	require.Empty(t, received.Get("x-ctxwire-accept-tags"))
+
+	serve(ctxwire.NewClient(nil, ctxwire.WithTransportRegistry(r), ctxwire.WithAllowedHosts("api.example.com")))
+	require.Empty(t, received.Get("x-ctxwire-tenant"))
+}
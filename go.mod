@@ -2,7 +2,7 @@ module github.com/trezz/ctxwire
 
 go 1.23.0
 
-require github.com/stretchr/testify v1.9.0
+require github.com/stretchr/testify v1.10.0
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,42 @@
+package ctxwire
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+)
+
+// NewGobPropagator returns a new ValuePropagator with the given name configured
+// to encode and decode the context value with encoding/gob.
+// The value is encoded as an interface value, so its concrete type must be
+// registered with gob.Register on both sides. Extracted values have the same
+// concrete type as the injected ones, including types implementing
+// gob.GobEncoder to propagate unexported fields.
+// It is meant for services written in Go on both sides.
+func NewGobPropagator(name string, contextKey any, opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{
+		WithEncoder(EncoderFunc(encodeGob)),
+		WithDecoder(DecoderFunc(decodeGob)),
+	}, opts...)
+	return NewPropagator(name, contextKey, opts...)
+}
+
+func encodeGob(ctx context.Context, key any) ([]byte, error) {
+	v := ctx.Value(key)
+	if v == nil {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeGob(ctx context.Context, key any, data []byte) (context.Context, error) {
+	var v any
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, key, v), nil
+}
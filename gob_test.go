@@ -0,0 +1,41 @@
+package ctxwire_test
+
+import (
+	"context"
+	"encoding/gob"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type (
+	deadlineKey struct{}
+	deadline    struct {
+		At     time.Time
+		Budget time.Duration
+	}
+)
+
+func init() {
+	gob.Register(deadline{})
+}
+
+func TestGobPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewGobPropagator("deadline", deadlineKey{})))
+
+	want := deadline{At: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Budget: 150 * time.Millisecond}
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), deadlineKey{}, want), h))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, want, ctx.Value(deadlineKey{}))
+
+	type unregistered struct{ A int }
+	err = r.Inject(context.WithValue(context.Background(), deadlineKey{}, unregistered{A: 1}), h)
+	require.ErrorContains(t, err, "encode context value")
+}
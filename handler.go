@@ -0,0 +1,102 @@
+package ctxwire
+
+import (
+	"log"
+	"net/http"
+)
+
+// HandlerOption configures the middleware returned by Handler.
+type HandlerOption func(h *handler)
+
+// WithExtractErrorHandler sets the function handling the errors extracting
+// the context values from the request headers, in which case the wrapped
+// handler is not called. Defaults to logging the error and replying with a
+// 400 Bad Request error, which doesn't disclose the error to the client.
+func WithExtractErrorHandler(fn func(w http.ResponseWriter, req *http.Request, err error)) HandlerOption {
+	return func(h *handler) { h.extractError = fn }
+}
+
+// WithInjectErrorHandler sets the function handling the errors injecting the
+// context values into the response headers and trailers. The response is sent
+// anyway, without the values which could not be injected. By default, the
+// errors are ignored.
+func WithInjectErrorHandler(fn func(req *http.Request, err error)) HandlerOption {
+	return func(h *handler) { h.injectError = fn }
+}
+
+// WithErrorLog sets the logger of the errors extracting the context values
+// from the request headers, when handled by the default extract error
+// handler. By default, the errors are logged with the standard logger of the
+// log package.
+func WithErrorLog(l *log.Logger) HandlerOption {
+	return func(h *handler) { h.errorLog = l }
+}
+
+type handler struct {
+	registry     *Registry
+	next         http.Handler
+	extractError func(w http.ResponseWriter, req *http.Request, err error)
+	injectError  func(req *http.Request, err error)
+	errorLog     *log.Logger
+	forward      bool
+	forwardNames []string
+}
+
+// Handler returns a middleware extracting the context values from the request
+// headers into the request context, using the propagators of the default
+// registry.
+// The values are injected into the response headers when the wrapped handler
+// writes its response, and into the response trailers once it returns. Values
+// recorded by the handler with Put, which the request context holds a box for,
+// are injected too.
+// The values of streamed responses changed after the first flush are sent in
+// the response trailers, as described by ResponseWriter.
+func Handler(next http.Handler, opts ...HandlerOption) http.Handler {
+	return defaultRegistry.Handler(next, opts...)
+}
+
+// Handler returns a middleware extracting the context values from the request
+// headers into the request context, using the propagators of the registry.
+// The values are injected into the response headers when the wrapped handler
+// writes its response, and into the response trailers once it returns. Values
+// recorded by the handler with Put, which the request context holds a box for,
+// are injected too.
+// The values of streamed responses changed after the first flush are sent in
+// the response trailers, as described by ResponseWriter.
+func (r *Registry) Handler(next http.Handler, opts ...HandlerOption) http.Handler {
+	h := &handler{registry: r, next: next}
+	h.extractError = h.badRequest
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// badRequest logs the given extract error and replies with a 400 Bad Request
+// error.
+func (h *handler) badRequest(w http.ResponseWriter, req *http.Request, err error) {
+	logf := log.Printf
+	if h.errorLog != nil {
+		logf = h.errorLog.Printf
+	}
+	logf("ctxwire: %s %s: %v", req.Method, req.URL.Path, err)
+	http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	ctx, err := h.registry.ExtractRequest(req.Context(), req.Header)
+	if err != nil {
+		h.extractError(w, req, err)
+		return
+	}
+	if h.forward {
+		ctx = h.registry.captureForwarded(ctx, req.Header, h.forwardNames)
+	}
+	ctx = WithBox(ctx)
+	req = req.WithContext(ctx)
+	rw := h.registry.NewResponseWriter(ctx, w)
+	h.next.ServeHTTP(rw, req)
+	if err := rw.Close(); err != nil && h.injectError != nil {
+		h.injectError(req, err)
+	}
+}
@@ -0,0 +1,124 @@
+package ctxwire_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestHandler(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", keyStr, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("count", keyInt, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+
+	h := r.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "alice", req.Context().Value(keyStr))
+		require.True(t, ctxwire.Put(req.Context(), keyInt, 42))
+		_, _ = io.WriteString(w, "hello")
+		// Values recorded once the headers are written are not injected.
+		ctxwire.Put(req.Context(), keyInt, 43)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-ctxwire-user", "alice")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "hello", w.Body.String())
+	require.Equal(t, "42", w.Header().Get("x-ctxwire-count"))
+	require.Empty(t, w.Header().Get("x-ctxwire-user"))
+
+	// Responses without body get the values too.
+	h = r.Handler(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		ctxwire.Put(req.Context(), keyInt, 1)
+	}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, "1", w.Header().Get("x-ctxwire-count"))
+}
+
+func TestHandlerTrailers(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("total", keyInt, ctxwire.WithTrailer())))
+
+	srv := httptest.NewServer(r.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		http.NewResponseController(w).Flush()
+		ctxwire.Put(req.Context(), keyInt, 7)
+	})))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.Equal(t, "7", resp.Trailer.Get("x-ctxwire-total"))
+}
+
+func TestHandlerErrors(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewStringPropagator("user", keyInt, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		called = true
+		ctxwire.Put(req.Context(), keyInt, 42)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-ctxwire-str", "%%%")
+	var logs strings.Builder
+	w := httptest.NewRecorder()
+	r.Handler(next, ctxwire.WithErrorLog(log.New(&logs, "", 0))).ServeHTTP(w, req)
+	require.False(t, called)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, "Bad Request\n", w.Body.String())
+	require.Equal(t, "ctxwire: GET /: decode header value: illegal base64 data at input byte 0\n", logs.String())
+
+	var extractErr, injectErr error
+	h := r.Handler(next,
+		ctxwire.WithExtractErrorHandler(func(w http.ResponseWriter, _ *http.Request, err error) {
+			extractErr = err
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}),
+		ctxwire.WithInjectErrorHandler(func(_ *http.Request, err error) { injectErr = err }),
+	)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Error(t, extractErr)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.True(t, called)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	var e *ctxwire.Error
+	require.True(t, errors.As(injectErr, &e))
+}
+
+func TestDefaultHandler(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewStringPropagator("user", keyStr))
+
+	h := ctxwire.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctxwire.Put(req.Context(), keyStr, req.Context().Value(keyStr).(string)+"!")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(context.Background())
+	req.Header.Set("x-ctxwire-user", "alice")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	require.Equal(t, "alice!", w.Header().Get("x-ctxwire-user"))
+}
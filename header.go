@@ -0,0 +1,173 @@
+package ctxwire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultHeaderPrefix is the prefix of the headers used by the propagators,
+// unless configured otherwise with WithHeaderPrefix.
+const DefaultHeaderPrefix = "x-ctxwire-"
+
+// HeaderNamer returns the header key of the propagated value with the given
+// name. It allows interoperating with services whose headers don't follow the
+// ctxwire naming scheme.
+type HeaderNamer func(name string) string
+
+// headerNaming defines how propagators name and encode their headers.
+// A nil *headerNaming uses the DefaultHeaderPrefix and Base64.
+type headerNaming struct {
+	prefix   string
+	namer    HeaderNamer
+	encoding ByteEncoding
+}
+
+// key returns the header key of the propagated value with the given name.
+func (n *headerNaming) key(name string) string {
+	switch {
+	case n == nil:
+		return DefaultHeaderPrefix + name
+	case n.namer != nil:
+		return n.namer(name)
+	}
+	return n.prefix + name
+}
+
+// headerPrefix returns the header prefix of the naming.
+func (n *headerNaming) headerPrefix() string {
+	if n == nil {
+		return DefaultHeaderPrefix
+	}
+	return n.prefix
+}
+
+// byteEncoding returns the byte encoding of the naming, or nil for Base64.
+func (n *headerNaming) byteEncoding() ByteEncoding {
+	if n == nil {
+		return nil
+	}
+	return n.encoding
+}
+
+// headerNamingBinder is implemented by propagators whose header naming can be
+// configured by their registry.
+type headerNamingBinder interface {
+	// withHeaderNaming returns a copy of the propagator using the given naming.
+	withHeaderNaming(naming *headerNaming) Propagator
+}
+
+// setValue sets the given encoded value in the carrier with the given key,
+// using the given byte encoding. Empty values are not set.
+func setValue(c Carrier, enc ByteEncoding, key string, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	v, err := encodeValue(enc, data)
+	if err != nil {
+		return newError(OpEncode, key, "encode context value", err)
+	}
+	c.Set(key, v)
+	return nil
+}
+
+// addValue adds the given encoded value to the values of the carrier with the
+// given key, using the given byte encoding. Empty values are not added.
+// The value replaces the existing one if the carrier is not a
+// MultiValueCarrier.
+func addValue(c Carrier, enc ByteEncoding, key string, data []byte) error {
+	mc, ok := c.(MultiValueCarrier)
+	if !ok || len(data) == 0 {
+		return setValue(c, enc, key, data)
+	}
+	v, err := encodeValue(enc, data)
+	if err != nil {
+		return newError(OpEncode, key, "encode context value", err)
+	}
+	mc.Add(key, v)
+	return nil
+}
+
+// getValue returns the encoded value found in the carrier with the given key,
+// decoded with the given byte encoding, or nil if the key is not set.
+func getValue(c Carrier, enc ByteEncoding, key string) ([]byte, error) {
+	vStr := c.Get(key)
+	if vStr == "" {
+		return nil, nil
+	}
+	v, err := decodeValue(enc, vStr)
+	if err != nil {
+		return nil, newError(OpDecode, key, "decode header value", err)
+	}
+	return v, nil
+}
+
+// maxChunks is the maximum number of chunks a value can be split into.
+const maxChunks = 32
+
+// chunkKey returns the key of the chunk i (starting at 1) of the value with the
+// given key.
+func chunkKey(key string, i int) string {
+	return key + "-" + strconv.Itoa(i)
+}
+
+// setChunkedValue sets the given encoded value in the carrier with the given
+// key, using the given byte encoding. Header values larger than size are
+// split across the chunk keys of the key. Empty values are not set.
+func setChunkedValue(c Carrier, enc ByteEncoding, key string, data []byte, size int) error {
+	if len(data) == 0 {
+		return nil
+	}
+	v, err := encodeValue(enc, data)
+	if err != nil {
+		return newError(OpEncode, key, "encode context value", err)
+	}
+	if len(v) <= size {
+		c.Set(key, v)
+		return nil
+	}
+	if n := (len(v) + size - 1) / size; n > maxChunks {
+		return newError(OpEncode, key, "encode context value", fmt.Errorf("value needs %d chunks, exceeding the maximum of %d", n, maxChunks))
+	}
+	for i := 1; len(v) > 0; i++ {
+		n := min(size, len(v))
+		c.Set(chunkKey(key, i), v[:n])
+		v = v[n:]
+	}
+	return nil
+}
+
+// getChunkedValue returns the encoded value found in the carrier with the
+// given key, or reassembled from its chunk keys, decoded with the given byte
+// encoding. It returns nil if neither the key nor its first chunk key is set.
+func getChunkedValue(c Carrier, enc ByteEncoding, key string) ([]byte, error) {
+	if c.Get(key) != "" {
+		return getValue(c, enc, key)
+	}
+	var b strings.Builder
+	for i := 1; i <= maxChunks; i++ {
+		chunk := c.Get(chunkKey(key, i))
+		if chunk == "" {
+			break
+		}
+		b.WriteString(chunk)
+	}
+	if b.Len() == 0 {
+		return nil, nil
+	}
+	v, err := decodeValue(enc, b.String())
+	if err != nil {
+		return nil, newError(OpDecode, key, "decode header value", err)
+	}
+	return v, nil
+}
+
+// decodedLen returns the length of the value encoded in the given header value
+// with the given byte encoding, or Base64 if nil.
+func decodedLen(enc ByteEncoding, vStr string) int {
+	if enc == nil || enc == Base64 {
+		return len(vStr)*3/4 - (len(vStr) - len(strings.TrimRight(vStr, "=")))
+	}
+	v, _ := decodeValue(enc, vStr)
+	return len(v)
+}
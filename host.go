@@ -0,0 +1,61 @@
+package ctxwire
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// hostMatcher matches the hosts of an allowlist.
+type hostMatcher struct {
+	exact    map[string]bool
+	suffixes []string
+	prefixes []netip.Prefix
+}
+
+// newHostMatcher returns a matcher of the given patterns, which are exact
+// host names or IP addresses, domain suffixes starting with a dot, or CIDR
+// ranges. Invalid CIDR ranges match no host.
+func newHostMatcher(patterns []string) *hostMatcher {
+	m := &hostMatcher{exact: map[string]bool{}}
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		switch {
+		case strings.HasPrefix(p, "."):
+			m.suffixes = append(m.suffixes, p)
+		case strings.Contains(p, "/"):
+			if prefix, err := netip.ParsePrefix(p); err == nil {
+				m.prefixes = append(m.prefixes, prefix.Masked())
+			}
+		default:
+			m.exact[p] = true
+		}
+	}
+	return m
+}
+
+// matches reports whether the given host, without port, is allowed.
+func (m *hostMatcher) matches(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if m.exact[host] {
+		return true
+	}
+	for _, s := range m.suffixes {
+		if strings.HasSuffix(host, s) {
+			return true
+		}
+	}
+	if len(m.prefixes) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range m.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package ctxwire
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrExpired is returned when decoding a value which is no longer valid, such
+// as an expired JWT.
+var ErrExpired = errors.New("expired")
+
+var errMalformedJWT = errors.New("malformed JWT")
+
+// registeredClaims are the JWT claims set by JWTSigner, which the propagated
+// values can't hold.
+var registeredClaims = []string{"iss", "aud", "exp", "iat", "nbf"}
+
+// JWTOption configures a JWTSigner.
+type JWTOption func(s *JWTSigner)
+
+// WithJWTKeyID sets the id of the key signing the JWTs, sent in their "kid"
+// header. By default, the JWTs are signed with the primary key of the
+// KeyProvider of the signer, which must then be a PrimaryKeyProvider, such as
+// a KeyRing.
+func WithJWTKeyID(id string) JWTOption {
+	return func(s *JWTSigner) { s.keyID = id }
+}
+
+// WithJWTIssuer sets the "iss" claim of the JWTs. JWTs issued by someone else
+// are rejected on Extract.
+func WithJWTIssuer(issuer string) JWTOption {
+	return func(s *JWTSigner) { s.issuer = issuer }
+}
+
+// WithJWTAudience sets the "aud" claim of the JWTs. JWTs intended for another
+// audience are rejected on Extract.
+func WithJWTAudience(audience string) JWTOption {
+	return func(s *JWTSigner) { s.audience = audience }
+}
+
+// WithJWTTTL sets the lifetime of the JWTs, from which their "exp" claim is
+// computed. Expired JWTs are rejected on Extract with ErrExpired. Defaults to
+// 5 minutes.
+func WithJWTTTL(ttl time.Duration) JWTOption {
+	return func(s *JWTSigner) { s.ttl = ttl }
+}
+
+// WithJWTLeeway sets the clock skew tolerated between the services when
+// validating the "exp" and "nbf" claims. Defaults to 0.
+func WithJWTLeeway(leeway time.Duration) JWTOption {
+	return func(s *JWTSigner) { s.leeway = leeway }
+}
+
+// JWTSigner emits context values as JWTs signed with HMAC-SHA256, whose claims
+// are the JSON object encoding the value along with the registered claims set
+// from its options, and validates their signature, issuer, audience and
+// expiry when decoding them, so that values can be consumed by the policy
+// engines already accepting JWTs.
+// Values must encode to JSON objects, without the "iss", "aud", "exp", "iat"
+// and "nbf" members, and are decoded as map[string]any.
+type JWTSigner struct {
+	keys     KeyProvider
+	keyID    string
+	issuer   string
+	audience string
+	ttl      time.Duration
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewJWTSigner returns a new JWTSigner looking up its keys with the given
+// KeyProvider, by the "kid" header of the JWTs when validating them.
+func NewJWTSigner(keys KeyProvider, opts ...JWTOption) *JWTSigner {
+	s := &JWTSigner{keys: keys, ttl: 5 * time.Minute, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Codec returns the codec encoding and decoding the context values as JWTs.
+// JWTs are header-safe, so the codec is meant to be used with the Raw byte
+// encoding.
+func (s *JWTSigner) Codec() Codec {
+	return Codec{Encoder: EncoderFunc(s.encode), Decoder: DecoderFunc(s.decode)}
+}
+
+// NewJWTPropagator returns a new ValuePropagator with the given name
+// propagating the context value as a JWT emitted by the given signer, sent as
+// is in the header.
+func NewJWTPropagator(name string, contextKey any, signer *JWTSigner, opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{WithCodec(signer.Codec()), WithByteEncoding(Raw)}, opts...)
+	return NewPropagator(name, contextKey, opts...)
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+func (s *JWTSigner) encode(ctx context.Context, key any) ([]byte, error) {
+	data, err := encodeJSON(ctx, key)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(data, &claims); err != nil || claims == nil {
+		return nil, fmt.Errorf("JWT claims must be a JSON object, got %s", data)
+	}
+	for _, name := range registeredClaims {
+		if _, ok := claims[name]; ok {
+			return nil, fmt.Errorf("value holds the registered JWT claim %q", name)
+		}
+	}
+	now := s.now()
+	claims["iat"] = json.RawMessage(fmt.Sprint(now.Unix()))
+	claims["exp"] = json.RawMessage(fmt.Sprint(now.Add(s.ttl).Unix()))
+	if s.issuer != "" {
+		claims["iss"], _ = json.Marshal(s.issuer)
+	}
+	if s.audience != "" {
+		claims["aud"], _ = json.Marshal(s.audience)
+	}
+	keyID, secret, err := encodingKey(s.keys, s.keyID)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %q: %w", keyID, err)
+	}
+	header, err := json.Marshal(jwtHeader{Alg: "HS256", Typ: "JWT", Kid: keyID})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.AppendEncode(nil, header)
+	token = append(token, '.')
+	token = base64.RawURLEncoding.AppendEncode(token, payload)
+	mac := computeMAC(secret, token)
+	token = append(token, '.')
+	return base64.RawURLEncoding.AppendEncode(token, mac), nil
+}
+
+func (s *JWTSigner) decode(ctx context.Context, key any, data []byte) (context.Context, error) {
+	signed, mac, ok := cutLast(data, '.')
+	if !ok {
+		return nil, errMalformedJWT
+	}
+	rawHeader, rawPayload, ok := bytes.Cut(signed, []byte("."))
+	if !ok {
+		return nil, errMalformedJWT
+	}
+	var header jwtHeader
+	if err := decodeJWTPart(rawHeader, &header); err != nil {
+		return nil, err
+	}
+	// The algorithm is fixed rather than trusted from the header, so that
+	// unsigned "none" JWTs are rejected.
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", header.Alg)
+	}
+	secret, err := s.keys.Key(header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("verification key %q: %w", header.Kid, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(string(mac))
+	if err != nil || !hmac.Equal(sig, computeMAC(secret, signed)) {
+		return nil, ErrInvalidSignature
+	}
+	var claims map[string]any
+	if err := decodeJWTPart(rawPayload, &claims); err != nil {
+		return nil, err
+	}
+	if err := s.validate(claims); err != nil {
+		return nil, err
+	}
+	for _, name := range registeredClaims {
+		delete(claims, name)
+	}
+	return context.WithValue(ctx, key, claims), nil
+}
+
+// validate validates the registered claims of a JWT.
+func (s *JWTSigner) validate(claims map[string]any) error {
+	now := s.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New(`missing JWT claim "exp"`)
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(s.leeway)) {
+		return fmt.Errorf("JWT %w", ErrExpired)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(s.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("JWT not valid yet")
+	}
+	if s.issuer != "" && claims["iss"] != s.issuer {
+		return fmt.Errorf("unexpected JWT issuer %v", claims["iss"])
+	}
+	if s.audience != "" && !hasAudience(claims["aud"], s.audience) {
+		return fmt.Errorf("unexpected JWT audience %v", claims["aud"])
+	}
+	return nil
+}
+
+// hasAudience reports whether the given "aud" claim, a string or an array of
+// strings, holds the given audience.
+func hasAudience(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		return slices.Contains(aud, any(audience))
+	default:
+		return false
+	}
+}
+
+func decodeJWTPart(part []byte, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(string(part))
+	if err != nil {
+		return errMalformedJWT
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return errMalformedJWT
+	}
+	return nil
+}
+
+// cutLast slices data around the last instance of sep.
+func cutLast(data []byte, sep byte) (before, after []byte, found bool) {
+	i := bytes.LastIndexByte(data, sep)
+	if i < 0 {
+		return data, nil, false
+	}
+	return data[:i], data[i+1:], true
+}
@@ -0,0 +1,96 @@
+package ctxwire_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type keyClaims struct{}
+
+func TestJWTPropagator(t *testing.T) {
+	keys := ctxwire.NewKeyRing("k1", []byte("secret"))
+	r := ctxwire.NewRegistry()
+	signer := ctxwire.NewJWTSigner(keys, ctxwire.WithJWTIssuer("gateway"), ctxwire.WithJWTAudience("api"))
+	require.NoError(t, r.Configure(ctxwire.NewJWTPropagator("claims", keyClaims{}, signer)))
+
+	h := http.Header{}
+	ctx := context.WithValue(context.Background(), keyClaims{}, map[string]any{"sub": "alice", "roles": []string{"admin"}})
+	require.NoError(t, r.Inject(ctx, h))
+
+	parts := strings.Split(h.Get("x-ctxwire-claims"), ".")
+	require.Len(t, parts, 3)
+	var header, claims map[string]any
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &header))
+	require.Equal(t, map[string]any{"alg": "HS256", "typ": "JWT", "kid": "k1"}, header)
+	data, err = base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &claims))
+	require.Equal(t, "alice", claims["sub"])
+	require.Equal(t, "gateway", claims["iss"])
+	require.Equal(t, "api", claims["aud"])
+	require.InDelta(t, time.Now().Add(5*time.Minute).Unix(), claims["exp"], 5)
+
+	ctx, err = r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"sub": "alice", "roles": []any{"admin"}}, ctx.Value(keyClaims{}))
+
+	claims["sub"] = "root"
+	data, err = json.Marshal(claims)
+	require.NoError(t, err)
+	parts[1] = base64.RawURLEncoding.EncodeToString(data)
+	h.Set("x-ctxwire-claims", strings.Join(parts, "."))
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorIs(t, err, ctxwire.ErrInvalidSignature)
+
+	parts[0] = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	h.Set("x-ctxwire-claims", strings.Join(parts[:2], ".")+".")
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorContains(t, err, `unsupported JWT algorithm "none"`)
+}
+
+func TestJWTValidation(t *testing.T) {
+	keys := ctxwire.NewKeyRing("k1", []byte("secret"))
+	ctx := context.WithValue(context.Background(), keyClaims{}, map[string]any{"sub": "alice"})
+	token := func(opts ...ctxwire.JWTOption) []byte {
+		data, err := ctxwire.NewJWTSigner(keys, opts...).Codec().Encoder.Encode(ctx, keyClaims{})
+		require.NoError(t, err)
+		return data
+	}
+	dec := ctxwire.NewJWTSigner(keys, ctxwire.WithJWTIssuer("gateway"), ctxwire.WithJWTAudience("api")).Codec().Decoder
+
+	_, err := dec.Decode(context.Background(), keyClaims{}, token(ctxwire.WithJWTIssuer("gateway"), ctxwire.WithJWTAudience("api")))
+	require.NoError(t, err)
+
+	_, err = dec.Decode(context.Background(), keyClaims{}, token(
+		ctxwire.WithJWTIssuer("gateway"), ctxwire.WithJWTAudience("api"), ctxwire.WithJWTTTL(-time.Minute)))
+	require.ErrorIs(t, err, ctxwire.ErrExpired)
+
+	_, err = dec.Decode(context.Background(), keyClaims{}, token(ctxwire.WithJWTIssuer("gateway"), ctxwire.WithJWTAudience("billing")))
+	require.EqualError(t, err, "unexpected JWT audience billing")
+
+	_, err = dec.Decode(context.Background(), keyClaims{}, token(ctxwire.WithJWTIssuer("other"), ctxwire.WithJWTAudience("api")))
+	require.EqualError(t, err, "unexpected JWT issuer other")
+
+	lenient := ctxwire.NewJWTSigner(keys, ctxwire.WithJWTLeeway(2*time.Minute)).Codec().Decoder
+	_, err = lenient.Decode(context.Background(), keyClaims{}, token(ctxwire.WithJWTTTL(-time.Minute)))
+	require.NoError(t, err)
+
+	_, err = dec.Decode(context.Background(), keyClaims{}, []byte("not-a-jwt"))
+	require.EqualError(t, err, "malformed JWT")
+
+	enc := ctxwire.NewJWTSigner(keys).Codec().Encoder
+	_, err = enc.Encode(context.WithValue(context.Background(), keyClaims{}, "alice"), keyClaims{})
+	require.EqualError(t, err, `JWT claims must be a JSON object, got "alice"`)
+	_, err = enc.Encode(context.WithValue(context.Background(), keyClaims{}, map[string]any{"exp": 0}), keyClaims{})
+	require.EqualError(t, err, `value holds the registered JWT claim "exp"`)
+}
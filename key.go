@@ -0,0 +1,95 @@
+package ctxwire
+
+import (
+	"context"
+	"fmt"
+)
+
+// Key is a typed context key whose value is propagated over the wire as JSON.
+// It removes the boilerplate of defining a key type, configuring a propagator
+// and type-asserting the context value.
+type Key[T any] struct {
+	name string
+}
+
+// NewKey returns a new key with the given name and registers a propagator for
+// it in the default registry.
+func NewKey[T any](name string) *Key[T] {
+	return RegisterKey[T](defaultRegistry, name)
+}
+
+// RegisterKey returns a new key with the given name and registers a propagator
+// for it in the given registry.
+// It panics if the propagator cannot be registered, which only happens when
+// the registry rejects duplicate names.
+func RegisterKey[T any](r *Registry, name string) *Key[T] {
+	k := &Key[T]{name: name}
+	if err := r.Configure(NewTypedJSONPropagator[T](name, k)); err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// Name returns the name of the key.
+func (k *Key[T]) Name() string { return k.name }
+
+// WithValue returns a copy of the given context in which the key is associated
+// with the given value.
+func (k *Key[T]) WithValue(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Value returns the value associated with the key in the given context, and
+// whether it was found.
+func (k *Key[T]) Value(ctx context.Context) (T, bool) {
+	v, ok := ctx.Value(k).(T)
+	return v, ok
+}
+
+// Set returns a copy of the given context in which the value propagated with
+// the given name is v.
+// The key and propagator of the name are managed internally and registered in
+// the default registry on first use.
+// It panics if the name is already used with another type.
+func Set[T any](ctx context.Context, name string, v T) context.Context {
+	k, ok := namedKey[T](defaultRegistry, name)
+	if !ok {
+		panic(fmt.Sprintf("ctxwire: Set: name %q is already used with another type", name))
+	}
+	return k.WithValue(ctx, v)
+}
+
+// Get returns the value propagated with the given name in the given context,
+// and whether it was found with type T.
+// Like Set, it registers the key and propagator of the name in the default
+// registry on first use. Receivers must use the name with Set or Get before
+// extracting the values, for example in an init function.
+func Get[T any](ctx context.Context, name string) (T, bool) {
+	k, ok := namedKey[T](defaultRegistry, name)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	return k.Value(ctx)
+}
+
+// namedKey returns the key registered with the given name in the registry,
+// registering it if needed. It reports false if the name is already used with
+// another type.
+func namedKey[T any](r *Registry, name string) (*Key[T], bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if k, ok := r.keys[name]; ok {
+		k, ok := k.(*Key[T])
+		return k, ok
+	}
+	k := &Key[T]{name: name}
+	if err := r.configure(NewTypedJSONPropagator[T](name, k)); err != nil {
+		return nil, false
+	}
+	if r.keys == nil {
+		r.keys = make(map[string]any)
+	}
+	r.keys[name] = k
+	return k, true
+}
@@ -0,0 +1,59 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestKey(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	requestID := ctxwire.RegisterKey[string](r, "request-id")
+	userKey := ctxwire.RegisterKey[user](r, "user")
+
+	_, ok := requestID.Value(context.Background())
+	require.False(t, ok)
+
+	ctx := requestID.WithValue(context.Background(), "abc")
+	ctx = userKey.WithValue(ctx, user{ID: 2, Name: "bob"})
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.NotEmpty(t, h.Get("x-ctxwire-request-id"))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	id, ok := requestID.Value(ctx)
+	require.True(t, ok)
+	require.Equal(t, "abc", id)
+	u, ok := userKey.Value(ctx)
+	require.True(t, ok)
+	require.Equal(t, user{ID: 2, Name: "bob"}, u)
+}
+
+func TestSetGet(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+
+	_, ok := ctxwire.Get[string](context.Background(), "request-id")
+	require.False(t, ok)
+
+	ctx := ctxwire.Set(context.Background(), "request-id", "abc")
+	ctx = ctxwire.Set(ctx, "attempt", 2)
+	id, ok := ctxwire.Get[string](ctx, "request-id")
+	require.True(t, ok)
+	require.Equal(t, "abc", id)
+
+	h := http.Header{}
+	require.NoError(t, ctxwire.Inject(ctx, h))
+	ctx, err := ctxwire.Extract(context.Background(), h)
+	require.NoError(t, err)
+	attempt, ok := ctxwire.Get[int](ctx, "attempt")
+	require.True(t, ok)
+	require.Equal(t, 2, attempt)
+
+	_, ok = ctxwire.Get[int](ctx, "request-id")
+	require.False(t, ok)
+	require.Panics(t, func() { ctxwire.Set(ctx, "request-id", 42) })
+}
@@ -0,0 +1,112 @@
+package ctxwire
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// ErrUnknownKey is returned by KeyRing.Key for the ids of the keys it doesn't
+// hold, which also fails the decoding of the payloads signed or encrypted with
+// them.
+var ErrUnknownKey = errors.New("unknown key")
+
+// PrimaryKeyProvider is a KeyProvider holding a primary key, which can change
+// over time. Sign, Encrypt and their middlewares called with an empty key id
+// and a PrimaryKeyProvider sign and encrypt the payloads with its current
+// primary key.
+type PrimaryKeyProvider interface {
+	KeyProvider
+	// PrimaryKey returns the id and the value of the primary key.
+	PrimaryKey() (id string, key []byte)
+}
+
+// KeyRing is a PrimaryKeyProvider holding a set of keys by id, so that keys can
+// rotate without breaking in-flight traffic: the primary key signs and
+// encrypts the payloads while all the keys of the ring verify and decrypt
+// them. To rotate keys across services, add the new key to all the rings,
+// then make it the primary key, and remove the previous key once the payloads
+// it signed or encrypted are no longer in flight.
+// A KeyRing is safe for concurrent use.
+type KeyRing struct {
+	mu      sync.RWMutex
+	keys    map[string][]byte
+	primary string
+}
+
+var _ PrimaryKeyProvider = (*KeyRing)(nil)
+
+// NewKeyRing returns a new KeyRing whose primary key is the given key.
+func NewKeyRing(primaryID string, primary []byte) *KeyRing {
+	if len(primaryID) > maxKeyIDLen {
+		panic(fmt.Sprintf("ctxwire: NewKeyRing: key id %q exceeds %d bytes", primaryID, maxKeyIDLen))
+	}
+	return &KeyRing{keys: map[string][]byte{primaryID: slices.Clone(primary)}, primary: primaryID}
+}
+
+// Add adds a copy of the given key to the ring, replacing the key with the same
+// id, if any. Key ids are made of at most 255 bytes.
+func (r *KeyRing) Add(id string, key []byte) {
+	if len(id) > maxKeyIDLen {
+		panic(fmt.Sprintf("ctxwire: KeyRing.Add: key id %q exceeds %d bytes", id, maxKeyIDLen))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[id] = slices.Clone(key)
+}
+
+// SetPrimary makes the key with the given id the primary key. It returns an
+// error wrapping ErrUnknownKey if the ring doesn't hold it.
+func (r *KeyRing) SetPrimary(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.keys[id]; !ok {
+		return fmt.Errorf("key %q: %w", id, ErrUnknownKey)
+	}
+	r.primary = id
+	return nil
+}
+
+// Remove removes the key with the given id from the ring. It returns an error
+// if it is the primary key.
+func (r *KeyRing) Remove(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == r.primary {
+		return fmt.Errorf("key %q is the primary key", id)
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// Key implements the KeyProvider interface. It returns a copy of the key.
+func (r *KeyRing) Key(id string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return slices.Clone(key), nil
+}
+
+// PrimaryKey implements the PrimaryKeyProvider interface. It returns a copy of
+// the key.
+func (r *KeyRing) PrimaryKey() (id string, key []byte) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.primary, slices.Clone(r.keys[r.primary])
+}
+
+// encodingKey returns the id and the value of the key to sign or encrypt with:
+// the key of the given id, or the primary key of keys if the id is empty and
+// keys is a PrimaryKeyProvider.
+func encodingKey(keys KeyProvider, id string) (string, []byte, error) {
+	if p, ok := keys.(PrimaryKeyProvider); ok && id == "" {
+		id, key := p.PrimaryKey()
+		return id, key, nil
+	}
+	key, err := keys.Key(id)
+	return id, key, err
+}
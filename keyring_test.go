@@ -0,0 +1,93 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestKeyRing(t *testing.T) {
+	ring := ctxwire.NewKeyRing("k1", []byte("secret1"))
+	id, key := ring.PrimaryKey()
+	require.Equal(t, "k1", id)
+	require.Equal(t, []byte("secret1"), key)
+
+	_, err := ring.Key("k2")
+	require.ErrorIs(t, err, ctxwire.ErrUnknownKey)
+	require.ErrorIs(t, ring.SetPrimary("k2"), ctxwire.ErrUnknownKey)
+
+	ring.Add("k2", []byte("secret2"))
+	require.NoError(t, ring.SetPrimary("k2"))
+	id, _ = ring.PrimaryKey()
+	require.Equal(t, "k2", id)
+	key, err = ring.Key("k1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret1"), key)
+
+	require.EqualError(t, ring.Remove("k2"), `key "k2" is the primary key`)
+	require.NoError(t, ring.Remove("k1"))
+	_, err = ring.Key("k1")
+	require.ErrorIs(t, err, ctxwire.ErrUnknownKey)
+}
+
+func TestKeyRingCopiesKeys(t *testing.T) {
+	secret := []byte("secret1")
+	ring := ctxwire.NewKeyRing("k1", secret)
+	secret[0] = 'X'
+	key, err := ring.Key("k1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret1"), key)
+
+	key[0] = 'X'
+	_, primary := ring.PrimaryKey()
+	require.Equal(t, []byte("secret1"), primary)
+	primary[0] = 'X'
+
+	added := []byte("secret2")
+	ring.Add("k2", added)
+	added[0] = 'X'
+	for id, want := range map[string]string{"k1": "secret1", "k2": "secret2"} {
+		key, err := ring.Key(id)
+		require.NoError(t, err)
+		require.Equal(t, []byte(want), key)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	for _, m := range []func(string, ctxwire.KeyProvider) ctxwire.CodecMiddleware{
+		ctxwire.SignMiddleware,
+		ctxwire.EncryptMiddleware,
+	} {
+		// The sender rotates its keys while the receiver still holds both.
+		sender := ctxwire.NewKeyRing("k1", []byte(strings.Repeat("1", 32)))
+		receiver := ctxwire.NewKeyRing("k1", []byte(strings.Repeat("1", 32)))
+		receiver.Add("k2", []byte(strings.Repeat("2", 32)))
+		send := ctxwire.NewRegistry()
+		require.NoError(t, send.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithCodec(ctxwire.JSONCodec.Wrap(m("", sender))))))
+		recv := ctxwire.NewRegistry()
+		require.NoError(t, recv.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithCodec(ctxwire.JSONCodec.Wrap(m("", receiver))))))
+
+		inFlight := http.Header{}
+		require.NoError(t, send.Inject(context.WithValue(context.Background(), keyStr, "old"), inFlight))
+
+		sender.Add("k2", []byte(strings.Repeat("2", 32)))
+		require.NoError(t, sender.SetPrimary("k2"))
+		h := http.Header{}
+		require.NoError(t, send.Inject(context.WithValue(context.Background(), keyStr, "new"), h))
+
+		for want, h := range map[string]http.Header{"old": inFlight, "new": h} {
+			ctx, err := recv.Extract(context.Background(), h)
+			require.NoError(t, err)
+			require.Equal(t, want, ctx.Value(keyStr))
+		}
+
+		require.NoError(t, receiver.SetPrimary("k2"))
+		require.NoError(t, receiver.Remove("k1"))
+		_, err := recv.Extract(context.Background(), inFlight)
+		require.ErrorIs(t, err, ctxwire.ErrUnknownKey)
+	}
+}
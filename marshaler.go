@@ -0,0 +1,71 @@
+package ctxwire
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"reflect"
+)
+
+var (
+	binaryMarshalerType   = reflect.TypeFor[encoding.BinaryMarshaler]()
+	binaryUnmarshalerType = reflect.TypeFor[encoding.BinaryUnmarshaler]()
+	textMarshalerType     = reflect.TypeFor[encoding.TextMarshaler]()
+	textUnmarshalerType   = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// NewMarshalerPropagator returns a new ValuePropagator with the given name
+// propagating the T value associated with the given context key using its own
+// marshaling methods.
+// Values are encoded with encoding.BinaryMarshaler and decoded with
+// encoding.BinaryUnmarshaler if T implements them, or with
+// encoding.TextMarshaler and encoding.TextUnmarshaler otherwise. Unmarshalers
+// may be implemented by *T. Extracted values are of type T.
+// It panics if T implements none of the marshaler pairs.
+func NewMarshalerPropagator[T any](name string, contextKey any, opts ...PropagatorOption) *ValuePropagator {
+	t := reflect.TypeFor[T]()
+	target := t
+	if t.Kind() != reflect.Pointer {
+		target = reflect.PointerTo(t)
+	}
+	var (
+		binary = t.Implements(binaryMarshalerType) && target.Implements(binaryUnmarshalerType)
+		text   = t.Implements(textMarshalerType) && target.Implements(textUnmarshalerType)
+	)
+	if !binary && !text {
+		panic(fmt.Sprintf("ctxwire: NewMarshalerPropagator: %s implements no marshaler pair", t))
+	}
+
+	encode := func(ctx context.Context, key any) ([]byte, error) {
+		v, ok := ctx.Value(key).(T)
+		if !ok {
+			return nil, nil
+		}
+		if binary {
+			return any(v).(encoding.BinaryMarshaler).MarshalBinary()
+		}
+		return any(v).(encoding.TextMarshaler).MarshalText()
+	}
+	decode := func(ctx context.Context, key any, data []byte) (context.Context, error) {
+		ptr := reflect.New(target.Elem())
+		var err error
+		if binary {
+			err = ptr.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+		} else {
+			err = ptr.Interface().(encoding.TextUnmarshaler).UnmarshalText(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if t == target {
+			return context.WithValue(ctx, key, ptr.Interface().(T)), nil
+		}
+		return context.WithValue(ctx, key, ptr.Elem().Interface().(T)), nil
+	}
+
+	opts = append([]PropagatorOption{
+		WithEncoder(EncoderFunc(encode)),
+		WithDecoder(DecoderFunc(decode)),
+	}, opts...)
+	return NewPropagator(name, contextKey, opts...)
+}
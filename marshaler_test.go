@@ -0,0 +1,45 @@
+package ctxwire_test
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type (
+	startKey  struct{}
+	addrKey   struct{}
+	amountKey struct{}
+)
+
+func TestMarshalerPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewMarshalerPropagator[time.Time]("start", startKey{}),
+		ctxwire.NewMarshalerPropagator[netip.Addr]("addr", addrKey{}),
+		ctxwire.NewMarshalerPropagator[*big.Int]("amount", amountKey{}),
+	))
+
+	start := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	addr := netip.MustParseAddr("10.0.0.1")
+	amount := big.NewInt(0).Exp(big.NewInt(10), big.NewInt(30), nil)
+	ctx := context.WithValue(context.Background(), startKey{}, start)
+	ctx = context.WithValue(ctx, addrKey{}, addr)
+	ctx = context.WithValue(ctx, amountKey{}, amount)
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, start, ctx.Value(startKey{}))
+	require.Equal(t, addr, ctx.Value(addrKey{}))
+	require.Equal(t, 0, amount.Cmp(ctx.Value(amountKey{}).(*big.Int)))
+
+	require.Panics(t, func() { ctxwire.NewMarshalerPropagator[user]("user", userKey{}) })
+}
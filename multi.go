@@ -0,0 +1,98 @@
+package ctxwire
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+)
+
+// MultiPropagator propagates several context values in a single header.
+// The values are encoded as a JSON array, in the order of their keys, keeping
+// related values atomic on the wire. The positions of the values cleared with
+// Clear are listed in an array appended to the values, and the values are
+// removed from the context on Extract.
+// It implements the Propagator interface.
+type MultiPropagator struct {
+	name   string
+	keys   []any
+	naming *headerNaming
+}
+
+var _ Propagator = (*MultiPropagator)(nil)
+
+// NewMultiPropagator returns a new MultiPropagator with the given name,
+// propagating the context values associated with the given keys.
+// Values are decoded as with NewJSONPropagator.
+func NewMultiPropagator(name string, keys ...any) *MultiPropagator {
+	return &MultiPropagator{name: name, keys: keys}
+}
+
+// Name returns the name of the propagator.
+func (p *MultiPropagator) Name() string { return p.name }
+
+func (p *MultiPropagator) withHeaderNaming(naming *headerNaming) Propagator {
+	c := *p
+	c.naming = naming
+	return &c
+}
+
+func (p *MultiPropagator) headerEncoding() ByteEncoding { return p.naming.byteEncoding() }
+
+// HeaderKeys returns the header keys used by the propagator.
+func (p *MultiPropagator) HeaderKeys() []string { return []string{p.naming.key(p.name)} }
+
+// Inject implements the Propagator interface.
+func (p *MultiPropagator) Inject(ctx context.Context, c Carrier) error {
+	values := make([]any, len(p.keys))
+	var cleared []int
+	for i, key := range p.keys {
+		if Cleared(ctx, key) {
+			cleared = append(cleared, i)
+			continue
+		}
+		values[i] = ctx.Value(key)
+	}
+	if cleared != nil {
+		values = append(values, cleared)
+	} else if !slices.ContainsFunc(values, func(v any) bool { return v != nil }) {
+		return nil
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return newError(OpEncode, p.naming.key(p.name), "encode context value", err)
+	}
+	return setValue(c, p.naming.byteEncoding(), p.naming.key(p.name), data)
+}
+
+// Extract implements the Propagator interface.
+// Keys whose value was not set by the sender are left untouched.
+func (p *MultiPropagator) Extract(ctx context.Context, c Carrier) (context.Context, error) {
+	data, err := getValue(c, p.naming.byteEncoding(), p.naming.key(p.name))
+	if err != nil || data == nil {
+		return ctx, err
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, newError(OpDecode, p.naming.key(p.name), "decode context value", err)
+	}
+	for i, key := range p.keys {
+		if i >= len(values) || string(values[i]) == "null" {
+			continue
+		}
+		if ctx, err = decodeJSON(ctx, key, values[i]); err != nil {
+			return nil, newError(OpDecode, p.naming.key(p.name), "decode context value", err)
+		}
+	}
+	if len(values) > len(p.keys) {
+		var cleared []int
+		if err := json.Unmarshal(values[len(p.keys)], &cleared); err != nil {
+			return nil, newError(OpDecode, p.naming.key(p.name), "decode cleared values", err)
+		}
+		for _, i := range cleared {
+			if i >= 0 && i < len(p.keys) {
+				ctx = context.WithValue(ctx, p.keys[i], nil)
+			}
+		}
+	}
+	return ctx, nil
+}
@@ -0,0 +1,55 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestMultiPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewMultiPropagator("multi", keyStr, keyInt, keyLog)))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.Background(), h))
+	require.Empty(t, h)
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	require.NoError(t, r.Inject(ctx, h))
+	require.Len(t, h, 1)
+
+	ctx, err := r.Extract(context.WithValue(context.Background(), keyLog, "log"), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+	require.Equal(t, float64(42), ctx.Value(keyInt))
+	require.Equal(t, "log", ctx.Value(keyLog))
+
+	h.Set("x-ctxwire-multi", "e30=") // {}
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorContains(t, err, "decode context value")
+}
+
+func TestMultiPropagatorTombstone(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewMultiPropagator("multi", keyStr, keyInt)))
+
+	ctx := ctxwire.Clear(context.WithValue(context.Background(), keyStr, "foo"), keyInt)
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+
+	ctx = context.WithValue(context.Background(), keyInt, 42)
+	ctx, err := r.Extract(ctx, h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+	require.Nil(t, ctx.Value(keyInt))
+
+	h = http.Header{}
+	require.NoError(t, r.Inject(ctxwire.Clear(context.Background(), keyStr), h))
+	ctx, err = r.Extract(context.WithValue(context.Background(), keyStr, "foo"), h)
+	require.NoError(t, err)
+	require.Nil(t, ctx.Value(keyStr))
+}
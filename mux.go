@@ -0,0 +1,51 @@
+package ctxwire
+
+import "net/http"
+
+// ServeMux is an http.ServeMux whose routes propagate the context values of
+// different registries, so that, for instance, administration routes
+// propagate debugging values while public routes propagate nothing.
+// The handlers are wrapped with the Handler middleware of the registry of
+// their route.
+type ServeMux struct {
+	mux      *http.ServeMux
+	registry *Registry
+	opts     []HandlerOption
+}
+
+var _ http.Handler = (*ServeMux)(nil)
+
+// NewServeMux returns a new ServeMux whose routes use the given registry,
+// unless registered with HandleWithRegistry. A nil registry disables the
+// propagation. The options configure the middlewares of all the routes.
+func NewServeMux(r *Registry, opts ...HandlerOption) *ServeMux {
+	return &ServeMux{mux: http.NewServeMux(), registry: r, opts: opts}
+}
+
+// Handle registers the handler for the given pattern, propagating the
+// context values of the registry of the mux. Patterns follow the syntax of
+// http.ServeMux.
+func (m *ServeMux) Handle(pattern string, handler http.Handler) {
+	m.HandleWithRegistry(pattern, m.registry, handler)
+}
+
+// HandleFunc registers the handler function for the given pattern,
+// propagating the context values of the registry of the mux.
+func (m *ServeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// HandleWithRegistry registers the handler for the given pattern, propagating
+// the context values of the given registry. A nil registry disables the
+// propagation for the route.
+func (m *ServeMux) HandleWithRegistry(pattern string, r *Registry, handler http.Handler) {
+	if r != nil {
+		handler = r.Handler(handler, m.opts...)
+	}
+	m.mux.Handle(pattern, handler)
+}
+
+// ServeHTTP dispatches the request to the handler of the route matching it.
+func (m *ServeMux) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	m.mux.ServeHTTP(w, req)
+}
@@ -0,0 +1,70 @@
+package ctxwire_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestServeMux(t *testing.T) {
+	public := ctxwire.NewRegistry()
+	require.NoError(t, public.Configure(ctxwire.NewStringPropagator("user", keyStr)))
+	admin := ctxwire.NewRegistry()
+	require.NoError(t, admin.Configure(
+		ctxwire.NewStringPropagator("user", keyStr),
+		ctxwire.NewIntPropagator("debug", keyInt),
+	))
+
+	echo := func(w http.ResponseWriter, req *http.Request) {
+		if v, ok := req.Context().Value(keyInt).(int); ok {
+			ctxwire.Put(req.Context(), keyInt, v+1)
+		}
+		v, _ := req.Context().Value(keyStr).(string)
+		_, _ = w.Write([]byte(v))
+	}
+	mux := ctxwire.NewServeMux(public)
+	mux.HandleFunc("GET /public", echo)
+	mux.HandleWithRegistry("/admin/", admin, http.HandlerFunc(echo))
+	mux.HandleWithRegistry("/health", nil, http.HandlerFunc(echo))
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("x-ctxwire-user", "alice")
+		req.Header.Set("x-ctxwire-debug", "1")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/public")
+	require.Equal(t, "alice", w.Body.String())
+	require.Equal(t, "alice", w.Header().Get("x-ctxwire-user"))
+	require.Empty(t, w.Header().Get("x-ctxwire-debug"))
+
+	w = serve("/admin/stats")
+	require.Equal(t, "alice", w.Body.String())
+	require.Equal(t, "2", w.Header().Get("x-ctxwire-debug"))
+
+	w = serve("/health")
+	require.Empty(t, w.Body.String())
+	require.Empty(t, w.Header().Get("x-ctxwire-user"))
+
+	require.Equal(t, http.StatusNotFound, serve("/unknown").Code)
+}
+
+func TestServeMuxOptions(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("user", keyStr)))
+	mux := ctxwire.NewServeMux(r, ctxwire.WithExtractErrorHandler(func(w http.ResponseWriter, _ *http.Request, _ error) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	mux.Handle("/", http.NotFoundHandler())
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-ctxwire-user", "%%%")
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusTeapot, w.Code)
+}
@@ -0,0 +1,88 @@
+package ctxwire
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+// knownKeysSetter is implemented by propagators needing the header keys used
+// by the other propagators of their registry.
+type knownKeysSetter interface {
+	setKnownKeys(known map[string]bool)
+}
+
+type passthroughKey struct{}
+
+// PassthroughPropagator forwards the ctxwire headers which are not recognized
+// by any other propagator of its registry.
+// On Extract, it captures all the unrecognized keys with the header prefix
+// of its registry and re-emits them on Inject. This allows intermediate
+// services to forward values they don't understand instead of dropping them.
+// A PassthroughPropagator must be configured in a single registry.
+// It implements the Propagator interface.
+type PassthroughPropagator struct {
+	known  atomic.Pointer[map[string]bool]
+	naming *headerNaming
+}
+
+var _ Propagator = (*PassthroughPropagator)(nil)
+
+// NewPassthroughPropagator returns a new PassthroughPropagator.
+func NewPassthroughPropagator() *PassthroughPropagator {
+	return &PassthroughPropagator{}
+}
+
+// Name returns the name of the propagator.
+func (p *PassthroughPropagator) Name() string { return "passthrough" }
+
+func (p *PassthroughPropagator) withHeaderNaming(naming *headerNaming) Propagator {
+	return &PassthroughPropagator{naming: naming}
+}
+
+func (p *PassthroughPropagator) setKnownKeys(known map[string]bool) {
+	p.known.Store(&known)
+}
+
+func (p *PassthroughPropagator) isKnown(key string) bool {
+	known := p.known.Load()
+	return known != nil && (*known)[strings.ToLower(key)]
+}
+
+// Inject implements the Propagator interface.
+// Keys already set are not overwritten.
+func (p *PassthroughPropagator) Inject(ctx context.Context, c Carrier) error {
+	captured, _ := ctx.Value(passthroughKey{}).(map[string][]string)
+	for key, vs := range captured {
+		if p.isKnown(key) || c.Get(key) != "" {
+			continue
+		}
+		mc, ok := c.(MultiValueCarrier)
+		if !ok {
+			c.Set(key, vs[0])
+			continue
+		}
+		for _, v := range vs {
+			mc.Add(key, v)
+		}
+	}
+	return nil
+}
+
+// Extract implements the Propagator interface.
+func (p *PassthroughPropagator) Extract(ctx context.Context, c Carrier) (context.Context, error) {
+	prefix := strings.ToLower(p.naming.headerPrefix())
+	captured := make(map[string][]string)
+	for _, key := range c.Keys() {
+		if !strings.HasPrefix(strings.ToLower(key), prefix) || p.isKnown(key) {
+			continue
+		}
+		if vs := values(c, key); len(vs) > 0 {
+			captured[key] = vs
+		}
+	}
+	if len(captured) == 0 {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, passthroughKey{}, captured), nil
+}
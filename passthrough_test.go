@@ -0,0 +1,56 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestPassthroughPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewPassthroughPropagator(),
+		ctxwire.NewJSONPropagator("str", keyStr),
+	))
+
+	in := http.Header{}
+	in.Set("x-ctxwire-str", "ImZvbyI=")     // "foo"
+	in.Set("x-ctxwire-unknown", "ImJhciI=") // "bar"
+	in.Set("x-other", "other")
+	ctx, err := r.Extract(context.Background(), in)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+
+	ctx = context.WithValue(ctx, keyStr, "baz")
+	out := http.Header{}
+	require.NoError(t, r.Inject(ctx, out))
+	require.Equal(t, http.Header{
+		"X-Ctxwire-Str":     {"ImJheiI="},
+		"X-Ctxwire-Unknown": {"ImJhciI="},
+	}, out)
+}
+
+func TestPassthroughPropagatorHeaderNamer(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithHeaderNamer(func(name string) string { return "x-legacy-" + name }))
+	require.NoError(t, r.Configure(
+		ctxwire.NewPassthroughPropagator(),
+		ctxwire.NewJSONPropagator("str", keyStr),
+	))
+
+	in := http.Header{}
+	in.Set("x-legacy-str", "ImZvbyI=")      // "foo"
+	in.Set("x-ctxwire-unknown", "ImJhciI=") // "bar"
+	ctx, err := r.Extract(context.Background(), in)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+
+	out := http.Header{}
+	require.NoError(t, r.Inject(ctx, out))
+	require.Equal(t, http.Header{
+		"X-Legacy-Str":      {"ImZvbyI="},
+		"X-Ctxwire-Unknown": {"ImJhciI="},
+	}, out)
+}
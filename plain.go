@@ -0,0 +1,66 @@
+package ctxwire
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// NewStringPropagator returns a new ValuePropagator with the given name
+// propagating a string context value as is, without JSON and base64 encoding.
+// This makes headers smaller and human-readable. Values which are not
+// header-safe are rejected on Inject, as with the Raw byte encoding.
+func NewStringPropagator(name string, contextKey any, opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{
+		WithEncoder(EncoderFunc(encodeString)),
+		WithDecoder(DecoderFunc(decodeString)),
+		WithByteEncoding(Raw),
+	}, opts...)
+	return NewPropagator(name, contextKey, opts...)
+}
+
+func encodeString(ctx context.Context, key any) ([]byte, error) {
+	switch v := ctx.Value(key).(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unexpected value type %T, want string", v)
+	}
+}
+
+func decodeString(ctx context.Context, key any, data []byte) (context.Context, error) {
+	return context.WithValue(ctx, key, string(data)), nil
+}
+
+// NewIntPropagator returns a new ValuePropagator with the given name
+// propagating an int context value in its decimal form, without JSON and
+// base64 encoding.
+func NewIntPropagator(name string, contextKey any, opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{
+		WithEncoder(EncoderFunc(encodeInt)),
+		WithDecoder(DecoderFunc(decodeInt)),
+		WithByteEncoding(Raw),
+	}, opts...)
+	return NewPropagator(name, contextKey, opts...)
+}
+
+func encodeInt(ctx context.Context, key any) ([]byte, error) {
+	switch v := ctx.Value(key).(type) {
+	case nil:
+		return nil, nil
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), nil
+	default:
+		return nil, fmt.Errorf("unexpected value type %T, want int", v)
+	}
+}
+
+func decodeInt(ctx context.Context, key any, data []byte) (context.Context, error) {
+	v, err := strconv.Atoi(string(data))
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, key, v), nil
+}
@@ -0,0 +1,46 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestStringPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("str", keyStr)))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "tenant-42"), h))
+	require.Equal(t, "tenant-42", h.Get("x-ctxwire-str"))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "tenant-42", ctx.Value(keyStr))
+
+	err = r.Inject(context.WithValue(context.Background(), keyStr, "line\nbreak"), http.Header{})
+	require.EqualError(t, err, "encode context value: value is not header-safe")
+
+	err = r.Inject(context.WithValue(context.Background(), keyStr, 42), http.Header{})
+	require.EqualError(t, err, "encode context value: unexpected value type int, want string")
+}
+
+func TestIntPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("int", keyInt)))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyInt, -42), h))
+	require.Equal(t, "-42", h.Get("x-ctxwire-int"))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, -42, ctx.Value(keyInt))
+
+	h.Set("x-ctxwire-int", "forty-two")
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorContains(t, err, "decode context value")
+}
@@ -0,0 +1,361 @@
+package ctxwire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NewPropagator returns a new ValuePropagator with the given name.
+// The context key is used to store the context value in the context.
+// The context value is encoded and decoded as JSON, unless configured
+// otherwise with WithEncoder and WithDecoder.
+func NewPropagator(name string, contextKey any, opts ...PropagatorOption) *ValuePropagator {
+	p := &ValuePropagator{
+		name:       name,
+		contextKey: contextKey,
+		encoder:    EncoderFunc(encodeJSON),
+		decoder:    DecoderFunc(decodeJSON),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// NewValuePropagator returns a new ValuePropagator with the given name.
+// The context key is used to store the context value in the context.
+// The encoder and decoder are used to encode and decode the context value.
+//
+// Deprecated: Use NewPropagator with the WithEncoder and WithDecoder options.
+func NewValuePropagator(name string, contextKey any, encoder Encoder, decoder Decoder, opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{WithEncoder(encoder), WithDecoder(decoder)}, opts...)
+	return NewPropagator(name, contextKey, opts...)
+}
+
+// PropagatorOption configures a ValuePropagator.
+type PropagatorOption func(p *ValuePropagator)
+
+// WithEncoder sets the encoder used to encode the context value.
+// Defaults to JSON encoding.
+func WithEncoder(encoder Encoder) PropagatorOption {
+	return func(p *ValuePropagator) { p.encoder = encoder }
+}
+
+// WithDecoder sets the decoder used to decode the context value.
+// Defaults to JSON decoding into an any value.
+func WithDecoder(decoder Decoder) PropagatorOption {
+	return func(p *ValuePropagator) { p.decoder = decoder }
+}
+
+// WithPriority sets the priority of the propagator. Registries run propagators
+// with a higher priority first, which matters when a propagator reads values
+// extracted by another one. Propagators with the same priority run in
+// registration order. Defaults to 0.
+func WithPriority(priority int) PropagatorOption {
+	return func(p *ValuePropagator) { p.priority = priority }
+}
+
+// Direction defines in which direction a propagator propagates its value.
+type Direction int
+
+const (
+	// Both propagates the value from clients to servers and back.
+	Both Direction = iota
+	// RequestOnly only propagates the value from clients to servers.
+	RequestOnly
+	// ResponseOnly only propagates the value from servers back to clients.
+	ResponseOnly
+)
+
+// WithDirection restricts the propagator to the given direction.
+// Directions are enforced by the InjectRequest, ExtractRequest, InjectResponse
+// and ExtractResponse functions. Defaults to Both.
+func WithDirection(direction Direction) PropagatorOption {
+	return func(p *ValuePropagator) { p.direction = direction }
+}
+
+// WithMerger sets the merger used to merge the extracted value into the value
+// already present in the context.
+// If not set and the decoder implements the Merger interface, the decoder is
+// used as merger. Otherwise, the extracted value replaces the existing one.
+func WithMerger(merger Merger) PropagatorOption {
+	return func(p *ValuePropagator) { p.merger = merger }
+}
+
+// WithNamer sets the function naming the header of the propagator, taking
+// precedence over the header naming of the registry.
+func WithNamer(namer HeaderNamer) PropagatorOption {
+	return func(p *ValuePropagator) { p.namer = namer }
+}
+
+// WithMultiValue makes the propagator add its value to the values already set
+// in the carrier instead of replacing them, and decode all the values found on
+// Extract, in order. Combined with a Merger, it allows accumulating a list of
+// values across hops.
+// Carriers not implementing the MultiValueCarrier interface only hold a single
+// value.
+func WithMultiValue() PropagatorOption {
+	return func(p *ValuePropagator) { p.multiValue = true }
+}
+
+// WithNoOverwrite prevents the propagator from replacing a value already present
+// in the context on Extract, protecting locally-set values from being
+// clobbered by incoming ones. The incoming value is ignored, even if the
+// propagator has a Merger.
+func WithNoOverwrite() PropagatorOption {
+	return func(p *ValuePropagator) { p.noOverwrite = true }
+}
+
+// WithByteEncoding sets the byte encoding of the header values of the
+// propagator, taking precedence over the default byte encoding of the
+// registry. Defaults to Base64.
+func WithByteEncoding(enc ByteEncoding) PropagatorOption {
+	return func(p *ValuePropagator) { p.byteEncoding = enc }
+}
+
+// WithChunkSize splits the header values larger than the given size in bytes
+// across numbered headers, named after the header of the propagator with a
+// "-1" to "-N" suffix, so that large values survive the per-header size limits
+// of proxies. Values are reassembled on Extract. A value can be split into at
+// most 32 chunks.
+// Chunking is not supported by multi-value propagators. Defaults to 0, which
+// disables chunking.
+func WithChunkSize(size int) PropagatorOption {
+	return func(p *ValuePropagator) { p.chunkSize = size }
+}
+
+// ErrValueTooLarge is returned when injecting a value whose encoded form
+// exceeds the size limit set with WithMaxEncodedSize.
+var ErrValueTooLarge = errors.New("value too large")
+
+// SizePolicy defines how a propagator handles values exceeding the size limit
+// set with WithMaxEncodedSize.
+// There is deliberately no policy truncating the oversized values: a truncated
+// JSON, signed or encrypted payload can't be decoded by the receiver, so the
+// values are either rejected or dropped as a whole.
+type SizePolicy int
+
+const (
+	// RejectOversized makes Inject return an ErrValueTooLarge error.
+	RejectOversized SizePolicy = iota
+	// DropOversized silently skips the value, which is not injected, as if it
+	// was not set.
+	DropOversized
+)
+
+// WithMaxEncodedSize limits the size in bytes of the encoded form of the value,
+// as sent on the wire, protecting servers from the oversized headers generated
+// by runaway values. Oversized values are handled according to the
+// SizePolicy of the propagator. Defaults to 0, which disables the limit.
+func WithMaxEncodedSize(size int) PropagatorOption {
+	return func(p *ValuePropagator) { p.maxSize = size }
+}
+
+// WithSizePolicy sets the policy applied to the values exceeding the size
+// limit set with WithMaxEncodedSize. Defaults to RejectOversized.
+func WithSizePolicy(policy SizePolicy) PropagatorOption {
+	return func(p *ValuePropagator) { p.sizePolicy = policy }
+}
+
+// ValuePropagator propagates a single context value between requests and responses.
+// It implements the Propagator interface.
+type ValuePropagator struct {
+	name         string
+	contextKey   any
+	encoder      Encoder
+	decoder      Decoder
+	priority     int
+	direction    Direction
+	merger       Merger
+	naming       *headerNaming
+	namer        HeaderNamer
+	multiValue   bool
+	noOverwrite  bool
+	byteEncoding ByteEncoding
+	chunkSize    int
+	maxSize      int
+	sizePolicy   SizePolicy
+	trailer      bool
+	query        bool
+}
+
+var _ Propagator = (*ValuePropagator)(nil)
+
+// Name returns the name of the propagator.
+func (p *ValuePropagator) Name() string { return p.name }
+
+// Priority returns the priority of the propagator.
+func (p *ValuePropagator) Priority() int { return p.priority }
+
+// Direction returns the direction in which the propagator propagates its value.
+func (p *ValuePropagator) Direction() Direction { return p.direction }
+
+func (p *ValuePropagator) withHeaderNaming(naming *headerNaming) Propagator {
+	c := *p
+	c.naming = naming
+	return &c
+}
+
+// headerEncoding returns the byte encoding of the propagator, or the one of
+// its registry if not set.
+func (p *ValuePropagator) headerEncoding() ByteEncoding {
+	if p.byteEncoding != nil {
+		return p.byteEncoding
+	}
+	return p.naming.byteEncoding()
+}
+
+// HeaderKeys returns the header keys used by the propagator.
+// Chunked propagators also use the keys of the chunks.
+func (p *ValuePropagator) HeaderKeys() []string {
+	key := p.headerKey()
+	if !p.chunked() {
+		return []string{key}
+	}
+	keys := make([]string, 0, 1+maxChunks)
+	keys = append(keys, key)
+	for i := 1; i <= maxChunks; i++ {
+		keys = append(keys, chunkKey(key, i))
+	}
+	return keys
+}
+
+func (p *ValuePropagator) multiValued() bool { return p.multiValue }
+
+func (p *ValuePropagator) chunked() bool { return p.chunkSize > 0 && !p.multiValue }
+
+func (p *ValuePropagator) headerKey() string {
+	if p.namer != nil {
+		return p.namer(p.name)
+	}
+	return p.naming.key(p.name)
+}
+
+// Inject implements the Propagator interface.
+// Values cleared with Clear are injected as tombstones.
+func (p *ValuePropagator) Inject(ctx context.Context, c Carrier) error {
+	if Cleared(ctx, p.contextKey) {
+		if mc, ok := c.(MultiValueCarrier); ok && p.multiValue {
+			mc.Add(p.headerKey(), TombstoneValue)
+		} else {
+			c.Set(p.headerKey(), TombstoneValue)
+		}
+		return nil
+	}
+	data, err := p.encoder.Encode(withHeaderKey(ctx, p.headerKey()), p.contextKey)
+	if err != nil {
+		return newError(OpEncode, p.headerKey(), "encode context value", err)
+	}
+	if p.oversized(data) {
+		if p.sizePolicy == DropOversized {
+			return nil
+		}
+		return newError(OpEncode, p.headerKey(), "encode context value",
+			fmt.Errorf("%w: exceeds the limit of %d bytes", ErrValueTooLarge, p.maxSize))
+	}
+	if p.multiValue {
+		return addValue(c, p.headerEncoding(), p.headerKey(), data)
+	}
+	if p.chunked() {
+		return setChunkedValue(c, p.headerEncoding(), p.headerKey(), data, p.chunkSize)
+	}
+	return setValue(c, p.headerEncoding(), p.headerKey(), data)
+}
+
+// oversized reports whether the encoded form of the given data exceeds the
+// size limit of the propagator.
+func (p *ValuePropagator) oversized(data []byte) bool {
+	if p.maxSize <= 0 || len(data) == 0 {
+		return false
+	}
+	enc := p.headerEncoding()
+	if enc == nil {
+		enc = Base64
+	}
+	return len(enc.EncodeToString(data)) > p.maxSize
+}
+
+// Extract implements the Propagator interface.
+// Tombstones remove the value from the context, unless the propagator doesn't
+// overwrite values.
+func (p *ValuePropagator) Extract(ctx context.Context, c Carrier) (context.Context, error) {
+	if p.noOverwrite && ctx.Value(p.contextKey) != nil {
+		return ctx, nil
+	}
+	if !p.multiValue {
+		if c.Get(p.headerKey()) == TombstoneValue {
+			return context.WithValue(ctx, p.contextKey, nil), nil
+		}
+		var v []byte
+		var err error
+		if p.chunked() {
+			v, err = getChunkedValue(c, p.headerEncoding(), p.headerKey())
+		} else {
+			v, err = getValue(c, p.headerEncoding(), p.headerKey())
+		}
+		if err != nil || v == nil {
+			return ctx, err
+		}
+		return p.decode(ctx, v)
+	}
+	for _, vStr := range values(c, p.headerKey()) {
+		if vStr == TombstoneValue {
+			ctx = context.WithValue(ctx, p.contextKey, nil)
+			continue
+		}
+		v, err := decodeValue(p.headerEncoding(), vStr)
+		if err != nil {
+			return nil, newError(OpDecode, p.headerKey(), "decode header value", err)
+		}
+		if ctx, err = p.decode(ctx, v); err != nil {
+			return nil, err
+		}
+	}
+	return ctx, nil
+}
+
+func (p *ValuePropagator) decode(ctx context.Context, v []byte) (context.Context, error) {
+	newCtx, err := p.decoder.Decode(withHeaderKey(ctx, p.headerKey()), p.contextKey, v)
+	if err != nil {
+		return nil, newError(OpDecode, p.headerKey(), "decode context value", err)
+	}
+	return p.merge(ctx, context.WithValue(newCtx, headerKeyKey{}, nil))
+}
+
+type headerKeyKey struct{}
+
+// withHeaderKey returns a copy of the given context holding the header key of
+// the value being encoded or decoded, to which signed and encrypted payloads
+// are bound.
+func withHeaderKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, headerKeyKey{}, strings.ToLower(key))
+}
+
+// headerKeyFrom returns the header key of the value being encoded or decoded,
+// or an empty string when the codec is not called by a ValuePropagator.
+func headerKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(headerKeyKey{}).(string)
+	return key
+}
+
+// merge merges the value extracted into newCtx with the value of ctx, if any.
+func (p *ValuePropagator) merge(ctx, newCtx context.Context) (context.Context, error) {
+	merger := p.merger
+	if merger == nil {
+		merger, _ = p.decoder.(Merger)
+	}
+	if merger == nil {
+		return newCtx, nil
+	}
+	existing, incoming := ctx.Value(p.contextKey), newCtx.Value(p.contextKey)
+	if existing == nil || incoming == nil {
+		return newCtx, nil
+	}
+	merged, err := merger.Merge(existing, incoming)
+	if err != nil {
+		return nil, newError(OpMerge, p.headerKey(), "merge context value", err)
+	}
+	return context.WithValue(newCtx, p.contextKey, merged), nil
+}
@@ -0,0 +1,69 @@
+package ctxwire
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// QueryCarrier adapts url.Values to the MultiValueCarrier interface, to
+// propagate values in URL query parameters where headers are unavailable,
+// such as webhooks behind strict gateways or signed URLs.
+// Since URLs are commonly logged, only the propagators explicitly allowed with
+// WithQueryParam run with a QueryCarrier. Keys are case-insensitive.
+type QueryCarrier url.Values
+
+var _ MultiValueCarrier = QueryCarrier(nil)
+
+// Get implements the Carrier interface.
+func (c QueryCarrier) Get(key string) string {
+	return url.Values(c).Get(strings.ToLower(key))
+}
+
+// Set implements the Carrier interface.
+func (c QueryCarrier) Set(key, value string) {
+	url.Values(c).Set(strings.ToLower(key), value)
+}
+
+// Keys implements the Carrier interface.
+func (c QueryCarrier) Keys() []string {
+	return slices.Collect(maps.Keys(c))
+}
+
+// Values implements the MultiValueCarrier interface.
+func (c QueryCarrier) Values(key string) []string {
+	return url.Values(c)[strings.ToLower(key)]
+}
+
+// Add implements the MultiValueCarrier interface.
+func (c QueryCarrier) Add(key, value string) {
+	url.Values(c).Add(strings.ToLower(key), value)
+}
+
+func isQueryCarrier(c Carrier) bool {
+	_, ok := c.(QueryCarrier)
+	return ok
+}
+
+// queryAllower is implemented by propagators which may be allowed to propagate
+// their value in query parameters.
+type queryAllower interface {
+	AllowsQuery() bool
+}
+
+func allowsQuery(p Propagator) bool {
+	q, ok := p.(queryAllower)
+	return ok && q.AllowsQuery()
+}
+
+// WithQueryParam allows the propagator to run with a QueryCarrier. Values
+// propagated in query parameters are likely to be logged by servers and
+// proxies, so sensitive values must not be allowed.
+func WithQueryParam() PropagatorOption {
+	return func(p *ValuePropagator) { p.query = true }
+}
+
+// AllowsQuery reports whether the propagator is allowed to propagate its value
+// in query parameters.
+func (p *ValuePropagator) AllowsQuery() bool { return p.query }
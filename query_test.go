@@ -0,0 +1,62 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestQueryCarrier(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithQueryParam()),
+		ctxwire.NewJSONPropagator("int", keyInt),
+	))
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	u, err := url.Parse("https://example.com/hook?id=1")
+	require.NoError(t, err)
+	q := u.Query()
+	require.NoError(t, r.InjectCarrier(ctx, ctxwire.QueryCarrier(q), ctxwire.RequestOnly))
+	u.RawQuery = q.Encode()
+	// Only the propagators allowed in query parameters are injected.
+	require.Equal(t, "https://example.com/hook?id=1&x-ctxwire-str=ImZvbyI%3D", u.String())
+
+	q = u.Query()
+	q.Set("x-ctxwire-int", "NDI=")
+	ctx, err = r.ExtractCarrier(context.Background(), ctxwire.QueryCarrier(q), ctxwire.RequestOnly)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+	require.Nil(t, ctx.Value(keyInt))
+
+	// Keys are case-insensitive.
+	c := ctxwire.QueryCarrier(url.Values{})
+	c.Set("X-Ctxwire-Str", "a")
+	c.Add("X-Ctxwire-Str", "b")
+	require.Equal(t, "a", c.Get("x-ctxwire-str"))
+	require.Equal(t, []string{"a", "b"}, c.Values("X-CTXWIRE-STR"))
+	require.Equal(t, []string{"x-ctxwire-str"}, c.Keys())
+}
+
+func TestQueryCarrierEnvelope(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithEnvelope())
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithQueryParam()),
+		ctxwire.NewJSONPropagator("int", keyInt),
+	))
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	q := url.Values{}
+	require.NoError(t, r.InjectCarrier(ctx, ctxwire.QueryCarrier(q), ctxwire.Both))
+	require.Equal(t, []string{"x-ctxwire"}, ctxwire.QueryCarrier(q).Keys())
+	require.NotContains(t, q.Get("x-ctxwire"), "int")
+
+	ctx, err := r.ExtractCarrier(context.Background(), ctxwire.QueryCarrier(q), ctxwire.Both)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+}
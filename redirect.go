@@ -0,0 +1,59 @@
+package ctxwire
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RedirectPolicy defines whether the values are propagated to the requests
+// following the redirects of the http.Client.
+type RedirectPolicy int
+
+const (
+	// SameDomainRedirects propagates the values to the redirected requests
+	// sent to the host of the initial request or to one of its subdomains,
+	// as net/http does for the Authorization header.
+	SameDomainRedirects RedirectPolicy = iota
+	// AllRedirects propagates the values to all the redirected requests.
+	AllRedirects
+	// NoRedirects never propagates the values to the redirected requests.
+	NoRedirects
+)
+
+// WithRedirectPolicy sets the policy applied to the requests following
+// redirects. The headers of the registry are removed from the requests the
+// values are not propagated to, including the ones set by the caller and
+// copied by the http.Client. Defaults to SameDomainRedirects.
+func WithRedirectPolicy(policy RedirectPolicy) TransportOption {
+	return func(t *Transport) { t.redirects = policy }
+}
+
+// followsRedirect reports whether the values are propagated to the given
+// request, according to the redirect policy of the transport.
+func (t *Transport) followsRedirect(req *http.Request) bool {
+	if req.Response == nil || t.redirects == AllRedirects {
+		return true
+	}
+	if t.redirects == NoRedirects {
+		return false
+	}
+	initial := req
+	for initial.Response != nil && initial.Response.Request != nil {
+		initial = initial.Response.Request
+	}
+	return isDomainOrSubdomain(req.URL.Hostname(), initial.URL.Hostname())
+}
+
+// isDomainOrSubdomain reports whether sub is the same host as parent, or one
+// of its subdomains.
+func isDomainOrSubdomain(sub, parent string) bool {
+	sub, parent = strings.ToLower(sub), strings.ToLower(parent)
+	if sub == parent {
+		return true
+	}
+	// IPv6 addresses have no subdomains.
+	if strings.Contains(sub, ":") {
+		return false
+	}
+	return strings.HasSuffix(sub, "."+parent)
+}
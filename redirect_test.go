@@ -0,0 +1,69 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	r := newTransportRegistry(t)
+	var received http.Header
+	other := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		received = req.Header
+	}))
+	defer other.Close()
+	// The other server is reached with another host name.
+	otherURL := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/same":
+			http.Redirect(w, req, "/final", http.StatusFound)
+		case "/cross":
+			http.Redirect(w, req, otherURL, http.StatusFound)
+		default:
+			received = req.Header
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.WithValue(context.Background(), keyStr, "alice")
+	get := func(policy *ctxwire.RedirectPolicy, path string) http.Header {
+		t.Helper()
+		opts := []ctxwire.TransportOption{ctxwire.WithTransportRegistry(r)}
+		if policy != nil {
+			opts = append(opts, ctxwire.WithRedirectPolicy(*policy))
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("x-ctxwire-manual", "1")
+		received = nil
+		resp, err := ctxwire.NewClient(nil, opts...).Do(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.NotNil(t, received)
+		return received
+	}
+
+	h := get(nil, "/same")
+	require.Equal(t, "alice", h.Get("x-ctxwire-user"))
+	h = get(nil, "/cross")
+	require.Empty(t, h.Get("x-ctxwire-user"))
+	require.Empty(t, h.Get("x-ctxwire-manual"))
+
+	all := ctxwire.AllRedirects
+	h = get(&all, "/cross")
+	require.Equal(t, "alice", h.Get("x-ctxwire-user"))
+
+	none := ctxwire.NoRedirects
+	h = get(&none, "/same")
+	require.Empty(t, h.Get("x-ctxwire-user"))
+	require.Empty(t, h.Get("x-ctxwire-manual"))
+	h = get(&none, "/final")
+	require.Equal(t, "alice", h.Get("x-ctxwire-user"))
+}
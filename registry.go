@@ -0,0 +1,496 @@
+package ctxwire
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds a set of propagators used to propagate context values between
+// requests and responses.
+// Registries are isolated from each other, which allows libraries and
+// multi-tenant servers to maintain their own set of propagators.
+// The zero value is an empty registry ready to use.
+type Registry struct {
+	// mu serializes the modifications of the registry. Inject and Extract read
+	// an immutable snapshot of the propagators without locking.
+	mu              sync.Mutex
+	propagators     atomic.Pointer[[]Propagator]
+	duplicatePolicy DuplicatePolicy
+	naming          *headerNaming
+	errorPolicy     ErrorPolicy
+	envelope        envelopeFormat
+	versionHeader   bool
+	delta           bool
+	capabilities    bool
+	acceptedTags    []string
+	// keys are the keys registered by name with Set and Get.
+	keys map[string]any
+}
+
+// named is implemented by propagators exposing their name.
+type named interface {
+	Name() string
+}
+
+// prioritized is implemented by propagators exposing their priority.
+type prioritized interface {
+	Priority() int
+}
+
+func priorityOf(p Propagator) int {
+	if p, ok := p.(prioritized); ok {
+		return p.Priority()
+	}
+	return 0
+}
+
+// directed is implemented by propagators restricted to a direction.
+type directed interface {
+	Direction() Direction
+}
+
+// propagates reports whether p propagates its value in the given direction.
+func propagates(p Propagator, dir Direction) bool {
+	d, ok := p.(directed)
+	return !ok || dir == Both || d.Direction() == Both || d.Direction() == dir
+}
+
+// headerKeyer is implemented by propagators exposing the header keys they use.
+type headerKeyer interface {
+	HeaderKeys() []string
+}
+
+// defaultRegistry is the registry used by the package-level functions.
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry used by the package-level functions,
+// for the integrations using it by default.
+func DefaultRegistry() *Registry { return defaultRegistry }
+
+// ErrDuplicatePropagator is returned when configuring a propagator whose name is
+// already used by another propagator of the registry.
+var ErrDuplicatePropagator = errors.New("duplicate propagator")
+
+// DuplicatePolicy defines how a registry handles propagators configured with a
+// name already in use.
+type DuplicatePolicy int
+
+const (
+	// ReplaceDuplicates replaces the previously configured propagator with the
+	// new one, keeping its position in the registry.
+	ReplaceDuplicates DuplicatePolicy = iota
+	// RejectDuplicates makes Configure return an ErrDuplicatePropagator error.
+	RejectDuplicates
+)
+
+// RegistryOption configures a Registry.
+type RegistryOption func(r *Registry)
+
+// WithDuplicatePolicy sets the policy applied when configuring propagators
+// with a name already in use. Defaults to ReplaceDuplicates.
+func WithDuplicatePolicy(policy DuplicatePolicy) RegistryOption {
+	return func(r *Registry) { r.duplicatePolicy = policy }
+}
+
+// ErrorPolicy defines how a registry handles the errors of its propagators
+// while injecting or extracting context values.
+type ErrorPolicy int
+
+const (
+	// FailFast stops at the first failing propagator and returns its error.
+	// Extract returns a nil context.
+	FailFast ErrorPolicy = iota
+	// SkipErrors ignores the failing propagators and runs the others.
+	// No error is returned.
+	SkipErrors
+	// CollectErrors runs all the propagators and returns the errors of the
+	// failing ones joined together with errors.Join, each error identifying its
+	// propagator by name. Extract returns the context with the values
+	// extracted by the other propagators along with the error.
+	CollectErrors
+)
+
+// WithErrorPolicy sets the policy applied when propagators fail to inject or
+// extract context values. Defaults to FailFast.
+func WithErrorPolicy(policy ErrorPolicy) RegistryOption {
+	return func(r *Registry) { r.errorPolicy = policy }
+}
+
+// WithHeaderPrefix sets the prefix of the headers used by the propagators of
+// the registry. Defaults to DefaultHeaderPrefix.
+// Registries using different prefixes can run side by side without
+// interfering with each other.
+func WithHeaderPrefix(prefix string) RegistryOption {
+	return func(r *Registry) { r.headerNaming().prefix = prefix }
+}
+
+// WithHeaderNamer sets the function naming the headers used by the propagators
+// of the registry, taking precedence over the header prefix.
+// The PassthroughPropagator keeps forwarding the headers with the header prefix.
+func WithHeaderNamer(namer HeaderNamer) RegistryOption {
+	return func(r *Registry) { r.headerNaming().namer = namer }
+}
+
+// WithDefaultByteEncoding sets the byte encoding of the header values of the
+// propagators of the registry, such as base32 or z85 encodings required by
+// some infrastructures. Propagators configured with WithByteEncoding keep
+// their own. Defaults to Base64.
+func WithDefaultByteEncoding(enc ByteEncoding) RegistryOption {
+	return func(r *Registry) { r.headerNaming().encoding = enc }
+}
+
+func (r *Registry) headerNaming() *headerNaming {
+	if r.naming == nil {
+		r.naming = &headerNaming{prefix: DefaultHeaderPrefix}
+	}
+	return r.naming
+}
+
+// NewRegistry returns a new empty registry configured with the given options.
+func NewRegistry(opts ...RegistryOption) *Registry {
+	r := &Registry{}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Configure adds the given propagators to the registry.
+// The built-in propagators are copied to use the header naming of the registry.
+// Propagators exposing their priority with a Priority() int method run by
+// decreasing priority; the others have a priority of 0.
+// Propagators exposing their name with a Name() string method are checked for
+// duplicates according to the registry DuplicatePolicy. Under the
+// RejectDuplicates policy, no propagator is added if any of them is a duplicate.
+func (r *Registry) Configure(propagators ...Propagator) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.configure(propagators...)
+}
+
+// configure implements Configure. The caller must hold r.mu.
+func (r *Registry) configure(propagators ...Propagator) error {
+	newPropagators := slices.Clone(r.load())
+	for _, p := range propagators {
+		if b, ok := p.(headerNamingBinder); ok && r.naming != nil {
+			p = b.withHeaderNaming(r.naming)
+		}
+		i := indexOf(newPropagators, p)
+		switch {
+		case i < 0:
+			newPropagators = append(newPropagators, p)
+		case r.duplicatePolicy == RejectDuplicates:
+			return newError(OpConfigure, "", "configure propagators",
+				fmt.Errorf("%w: %s", ErrDuplicatePropagator, p.(named).Name()))
+		default:
+			newPropagators[i] = p
+		}
+	}
+	slices.SortStableFunc(newPropagators, func(a, b Propagator) int {
+		return cmp.Compare(priorityOf(b), priorityOf(a))
+	})
+	r.setPropagators(newPropagators)
+	return nil
+}
+
+// indexOf returns the index of the propagator having the same name as p, or -1.
+func indexOf(propagators []Propagator, p Propagator) int {
+	n, ok := p.(named)
+	if !ok {
+		return -1
+	}
+	return slices.IndexFunc(propagators, func(other Propagator) bool {
+		o, ok := other.(named)
+		return ok && o.Name() == n.Name()
+	})
+}
+
+// Unregister removes the propagators with the given name from the registry.
+// Only propagators exposing their name with a Name() string method, such as
+// ValuePropagator, can be unregistered.
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setPropagators(slices.DeleteFunc(slices.Clone(r.load()), func(p Propagator) bool {
+		n, ok := p.(named)
+		return ok && n.Name() == name
+	}))
+	delete(r.keys, name)
+}
+
+// PropagatorInfo describes a propagator configured in a registry.
+type PropagatorInfo struct {
+	// Name is the name of the propagator, if it exposes one.
+	Name string
+	// HeaderKeys are the header keys used by the propagator, if it exposes them.
+	HeaderKeys []string
+	// Priority is the priority of the propagator.
+	Priority int
+	// Direction is the direction in which the propagator propagates values.
+	Direction Direction
+}
+
+// Propagators returns a description of the propagators of the registry, in
+// the order they run.
+func (r *Registry) Propagators() []PropagatorInfo {
+	propagators := r.load()
+	infos := make([]PropagatorInfo, len(propagators))
+	for i, p := range propagators {
+		infos[i].Priority = priorityOf(p)
+		if n, ok := p.(named); ok {
+			infos[i].Name = n.Name()
+		}
+		if k, ok := p.(headerKeyer); ok {
+			infos[i].HeaderKeys = k.HeaderKeys()
+		}
+		if d, ok := p.(directed); ok {
+			infos[i].Direction = d.Direction()
+		}
+	}
+	return infos
+}
+
+// Reset removes all the propagators from the registry.
+func (r *Registry) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setPropagators(nil)
+	r.keys = nil
+}
+
+// setPropagators sets the propagators of the registry and notifies the
+// propagators interested in the header keys of the others.
+// The caller must hold r.mu.
+// The given slice must not be modified afterwards.
+func (r *Registry) setPropagators(propagators []Propagator) {
+	known := map[string]bool{
+		strings.ToLower(r.versionKey()):    true,
+		strings.ToLower(r.acceptKey()):     true,
+		strings.ToLower(r.acceptTagsKey()): true,
+	}
+	for _, p := range propagators {
+		if k, ok := p.(headerKeyer); ok {
+			for _, key := range k.HeaderKeys() {
+				known[strings.ToLower(key)] = true
+			}
+		}
+	}
+	for _, p := range propagators {
+		if k, ok := p.(knownKeysSetter); ok {
+			k.setKnownKeys(known)
+		}
+	}
+	r.propagators.Store(&propagators)
+}
+
+// load returns the current snapshot of the propagators of the registry.
+func (r *Registry) load() []Propagator {
+	if p := r.propagators.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Inject injects the context values into the given headers.
+// Propagators run regardless of their direction.
+func (r *Registry) Inject(ctx context.Context, h http.Header) error {
+	return r.InjectCarrier(ctx, HeaderCarrier(h), Both)
+}
+
+// InjectCarrier injects the context values into the given carrier, skipping
+// the propagators not propagating values in the given direction and the ones
+// configured with WithTrailer.
+// Using Both runs all the propagators.
+// Values recorded in the box of the context, if any, are injected as well.
+func (r *Registry) InjectCarrier(ctx context.Context, c Carrier, dir Direction) error {
+	if r.versionHeader {
+		c.Set(r.versionKey(), strconv.Itoa(int(r.wireVersion())))
+	}
+	if r.capabilities && dir == RequestOnly {
+		r.advertise(c)
+	}
+	s := scope{dir: dir, query: isQueryCarrier(c)}
+	if r.envelope != noEnvelope {
+		return r.injectEnvelope(ctx, c, s)
+	}
+	return r.inject(ctx, c, s)
+}
+
+// scope selects the propagators running for an injection or an extraction.
+type scope struct {
+	// dir is the direction of the propagation.
+	dir Direction
+	// trailers selects the trailer propagators on injection, instead of the
+	// other ones.
+	trailers bool
+	// query selects only the propagators allowed in query parameters.
+	query bool
+}
+
+// runs reports whether p runs in the scope.
+func (s scope) runs(p Propagator) bool {
+	return propagates(p, s.dir) && (!s.query || allowsQuery(p))
+}
+
+// inject runs the propagators of the given scope, injecting their values into
+// the given carrier.
+func (r *Registry) inject(ctx context.Context, c Carrier, s scope) (err error) {
+	trace := ContextTrace(ctx)
+	start := trace.injectStart()
+	defer func() { trace.injectDone(start, err) }()
+	ctx = unbox(ctx)
+	extracted, _ := ctx.Value(deltaKey{r}).(map[string][]string)
+	accepted := r.accepted(ctx, s.dir)
+	suppressed := suppressionFrom(ctx)
+	var errs []error
+	for _, p := range r.load() {
+		if !s.runs(p) || isTrailer(p) != s.trailers || (accepted != nil && !accepted(p)) || suppressed.suppresses(p) {
+			continue
+		}
+		inject := p.Inject
+		if extracted != nil {
+			inject = func(ctx context.Context, c Carrier) error { return injectDelta(ctx, p, c, extracted) }
+		}
+		pStart := trace.propagatorStart()
+		err := inject(ctx, c)
+		trace.propagatorDone(p, OpInject, pStart, err)
+		if err != nil {
+			err = withPropagator(p, newError(OpInject, "", "inject context values", err))
+			if r.errorPolicy == FailFast {
+				return err
+			}
+			errs = append(errs, withPropagatorName(p, err))
+		}
+	}
+	return r.joinErrors(errs)
+}
+
+// Preview returns the headers that Inject would inject for the given context,
+// without modifying any existing headers.
+func (r *Registry) Preview(ctx context.Context) (http.Header, error) {
+	h := http.Header{}
+	if err := r.Inject(ctx, h); err != nil {
+		if r.errorPolicy == FailFast {
+			return nil, err
+		}
+		return h, err
+	}
+	return h, nil
+}
+
+// Extract extracts the context values from the given headers into a copy of
+// the given context.
+// Propagators run regardless of their direction.
+func (r *Registry) Extract(ctx context.Context, h http.Header) (context.Context, error) {
+	return r.ExtractCarrier(ctx, HeaderCarrier(h), Both)
+}
+
+// ExtractCarrier extracts the context values from the given carrier into a copy
+// of the given context, skipping the propagators not propagating values in the
+// given direction.
+// Using Both runs all the propagators.
+func (r *Registry) ExtractCarrier(ctx context.Context, c Carrier, dir Direction) (context.Context, error) {
+	return r.extract(ctx, c, dir, nil)
+}
+
+// ExtractWithReport extracts the context values from the given headers into a
+// copy of the given context, like Extract. It also returns a report describing
+// which propagators found their headers.
+func (r *Registry) ExtractWithReport(ctx context.Context, h http.Header) (context.Context, *ExtractReport, error) {
+	report := &ExtractReport{}
+	newCtx, err := r.extract(ctx, HeaderCarrier(h), Both, report)
+	return newCtx, report, err
+}
+
+// InjectRequest injects the context values into the given request headers,
+// skipping the ResponseOnly propagators.
+func (r *Registry) InjectRequest(ctx context.Context, h http.Header) error {
+	return r.InjectCarrier(ctx, HeaderCarrier(h), RequestOnly)
+}
+
+// ExtractRequest extracts the context values from the given request headers
+// into a copy of the given context, skipping the ResponseOnly propagators.
+func (r *Registry) ExtractRequest(ctx context.Context, h http.Header) (context.Context, error) {
+	return r.ExtractCarrier(ctx, HeaderCarrier(h), RequestOnly)
+}
+
+// InjectResponse injects the context values into the given response headers,
+// skipping the RequestOnly propagators.
+func (r *Registry) InjectResponse(ctx context.Context, h http.Header) error {
+	return r.InjectCarrier(ctx, HeaderCarrier(h), ResponseOnly)
+}
+
+// ExtractResponse extracts the context values from the given response headers
+// into a copy of the given context, skipping the RequestOnly propagators.
+func (r *Registry) ExtractResponse(ctx context.Context, h http.Header) (context.Context, error) {
+	return r.ExtractCarrier(ctx, HeaderCarrier(h), ResponseOnly)
+}
+
+func (r *Registry) extract(ctx context.Context, c Carrier, dir Direction, report *ExtractReport) (_ context.Context, err error) {
+	trace := ContextTrace(ctx)
+	start := trace.extractStart()
+	defer func() { trace.extractDone(start, err) }()
+	s := scope{dir: dir, query: isQueryCarrier(c)}
+	if r.capabilities {
+		ctx = r.recordCapabilities(ctx, c)
+	}
+	c, err = r.openCarrier(c)
+	if err != nil {
+		if r.errorPolicy == FailFast {
+			return nil, err
+		}
+		return ctx, r.joinErrors([]error{err})
+	}
+	suppressed := suppressionFrom(ctx)
+	var errs []error
+	for _, p := range r.load() {
+		if !s.runs(p) || suppressed.suppresses(p) {
+			continue
+		}
+		if report != nil {
+			report.add(p, c)
+		}
+		pStart := trace.propagatorStart()
+		newCtx, err := p.Extract(ctx, c)
+		trace.propagatorDone(p, OpExtract, pStart, err)
+		if err != nil {
+			err = withPropagator(p, newError(OpExtract, "", "extract context values", err))
+			if r.errorPolicy == FailFast {
+				return nil, err
+			}
+			errs = append(errs, withPropagatorName(p, err))
+			continue
+		}
+		ctx = newCtx
+	}
+	if r.delta {
+		ctx = r.recordExtracted(ctx, c)
+	}
+	return ctx, r.joinErrors(errs)
+}
+
+// withPropagatorName prefixes the error of the given propagator with its name,
+// if it exposes one.
+func withPropagatorName(p Propagator, err error) error {
+	if n, ok := p.(named); ok {
+		return fmt.Errorf("propagator %s: %w", n.Name(), err)
+	}
+	return err
+}
+
+// joinErrors returns the errors collected by a propagation according to the
+// error policy of the registry.
+func (r *Registry) joinErrors(errs []error) error {
+	if r.errorPolicy != CollectErrors {
+		return nil
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,334 @@
+package ctxwire_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type tenantKey struct{}
+
+func TestRegistryIsolation(t *testing.T) {
+	tenant := ctxwire.NewRegistry()
+	require.NoError(t, tenant.Configure(ctxwire.NewJSONPropagator("tenant", tenantKey{})))
+	other := ctxwire.NewRegistry()
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+
+	h := http.Header{}
+	require.NoError(t, other.Inject(ctx, h))
+	require.Empty(t, h)
+
+	require.NoError(t, tenant.Inject(ctx, h))
+	require.NotEmpty(t, h.Get("x-ctxwire-tenant"))
+
+	newCtx, err := other.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Nil(t, newCtx.Value(tenantKey{}))
+
+	newCtx, err = tenant.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "acme", newCtx.Value(tenantKey{}))
+
+	// The default registry is not affected by other registries.
+	h = http.Header{}
+	require.NoError(t, ctxwire.Inject(ctx, h))
+	require.Empty(t, h.Get("x-ctxwire-tenant"))
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewJSONPropagator("tenant", tenantKey{}))
+	require.Equal(t, ctxwire.Propagators(), ctxwire.DefaultRegistry().Propagators())
+
+	// The package-level functions use the default registry.
+	h := http.Header{}
+	require.NoError(t, ctxwire.DefaultRegistry().Inject(context.WithValue(context.Background(), tenantKey{}, "acme"), h))
+	ctx, err := ctxwire.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "acme", ctx.Value(tenantKey{}))
+}
+
+func TestRegistryUnregisterAndReset(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("int", keyInt),
+	))
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+
+	r.Unregister("str")
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Empty(t, h.Get("x-ctxwire-str"))
+	require.NotEmpty(t, h.Get("x-ctxwire-int"))
+
+	r.Reset()
+	h = http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Empty(t, h)
+}
+
+func TestRegistryDuplicates(t *testing.T) {
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("value", keyStr)))
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("value", keyInt)))
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Equal(t, []string{"NDI="}, h.Values("x-ctxwire-value"))
+
+	r = ctxwire.NewRegistry(ctxwire.WithDuplicatePolicy(ctxwire.RejectDuplicates))
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("value", keyStr)))
+	err := r.Configure(
+		ctxwire.NewJSONPropagator("other", keyStr),
+		ctxwire.NewJSONPropagator("value", keyInt),
+	)
+	require.ErrorIs(t, err, ctxwire.ErrDuplicatePropagator)
+	require.EqualError(t, err, "configure propagators: duplicate propagator: value")
+	h = http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Equal(t, http.Header{"X-Ctxwire-Value": {"ImZvbyI="}}, h)
+}
+
+type greetingKey struct{}
+
+func TestRegistryPriority(t *testing.T) {
+	// The greeting decoder reads the name extracted by the name propagator,
+	// which must run first.
+	greeting := ctxwire.NewPropagator("greeting", greetingKey{},
+		ctxwire.WithDecoder(ctxwire.DecoderFunc(func(ctx context.Context, key any, data []byte) (context.Context, error) {
+			name, _ := ctx.Value(keyStr).(string)
+			return context.WithValue(ctx, key, string(data)+" "+name), nil
+		})),
+	)
+	name := ctxwire.NewJSONPropagator("name", keyStr, ctxwire.WithPriority(10))
+
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(greeting, name))
+
+	h := http.Header{}
+	h.Set("x-ctxwire-greeting", "aGVsbG8=") // hello
+	h.Set("x-ctxwire-name", "ImJvYiI=")     // "bob"
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "hello bob", ctx.Value(greetingKey{}))
+}
+
+func TestRegistryDirection(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("both", keyStr),
+		ctxwire.NewJSONPropagator("request", keyInt, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewJSONPropagator("response", keyLog, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	ctx = context.WithValue(ctx, keyLog, "log")
+
+	h := http.Header{}
+	require.NoError(t, r.InjectRequest(ctx, h))
+	require.NotEmpty(t, h.Get("x-ctxwire-both"))
+	require.NotEmpty(t, h.Get("x-ctxwire-request"))
+	require.Empty(t, h.Get("x-ctxwire-response"))
+
+	h = http.Header{}
+	require.NoError(t, r.InjectResponse(ctx, h))
+	require.NotEmpty(t, h.Get("x-ctxwire-both"))
+	require.Empty(t, h.Get("x-ctxwire-request"))
+	require.NotEmpty(t, h.Get("x-ctxwire-response"))
+
+	h = http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Len(t, h, 3)
+
+	newCtx, err := r.ExtractResponse(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", newCtx.Value(keyStr))
+	require.Nil(t, newCtx.Value(keyInt))
+	require.Equal(t, "log", newCtx.Value(keyLog))
+
+	newCtx, err = r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", newCtx.Value(keyStr))
+	require.Equal(t, float64(42), newCtx.Value(keyInt))
+	require.Nil(t, newCtx.Value(keyLog))
+}
+
+func TestRegistryPreview(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr)))
+
+	h, err := r.Preview(context.WithValue(context.Background(), keyStr, "foo"))
+	require.NoError(t, err)
+	require.Equal(t, http.Header{"X-Ctxwire-Str": {"ImZvbyI="}}, h)
+
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("encode", keyEncode,
+		ctxwire.WithEncoder(ctxwire.EncoderFunc(errEncoder)))))
+	_, err = r.Preview(context.WithValue(context.Background(), keyEncode, "foo"))
+	require.EqualError(t, err, "encode context value: failed!")
+}
+
+func TestRegistryPropagators(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewStructPropagator[session]("session", sessionKey{}),
+		ctxwire.NewJSONPropagator("int", keyInt, ctxwire.WithPriority(1)),
+	))
+	require.Equal(t, []ctxwire.PropagatorInfo{
+		{Name: "int", HeaderKeys: []string{"x-ctxwire-int"}, Priority: 1},
+		{Name: "str", HeaderKeys: []string{"x-ctxwire-str"}, Direction: ctxwire.RequestOnly},
+		{Name: "session", HeaderKeys: []string{"x-ctxwire-user-id", "x-ctxwire-locale", "x-ctxwire-scopes"}},
+	}, r.Propagators())
+}
+
+func TestRegistryHeaderPrefix(t *testing.T) {
+	acme := ctxwire.NewRegistry(ctxwire.WithHeaderPrefix("x-acme-ctx-"))
+	require.NoError(t, acme.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr),
+		ctxwire.NewPassthroughPropagator(),
+	))
+	def := ctxwire.NewRegistry()
+	require.NoError(t, def.Configure(ctxwire.NewJSONPropagator("str", keyInt)))
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	h := http.Header{}
+	require.NoError(t, acme.Inject(ctx, h))
+	require.NoError(t, def.Inject(ctx, h))
+	require.Equal(t, http.Header{
+		"X-Acme-Ctx-Str": {"ImZvbyI="},
+		"X-Ctxwire-Str":  {"NDI="},
+	}, h)
+	require.Equal(t, []string{"x-acme-ctx-str"}, acme.Propagators()[0].HeaderKeys)
+
+	h.Set("x-acme-ctx-unknown", "e30=")
+	newCtx, err := acme.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", newCtx.Value(keyStr))
+	out := http.Header{}
+	require.NoError(t, acme.Inject(newCtx, out))
+	require.Equal(t, http.Header{
+		"X-Acme-Ctx-Str":     {"ImZvbyI="},
+		"X-Acme-Ctx-Unknown": {"e30="},
+	}, out)
+}
+
+func TestRegistryHeaderNamer(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithHeaderNamer(func(name string) string { return "x-legacy-" + name + "-ctx" }))
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("int", keyInt, ctxwire.WithNamer(func(string) string { return "x-request-id" })),
+	))
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+	h, err := r.Preview(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.Header{
+		"X-Legacy-Str-Ctx": {"ImZvbyI="},
+		"X-Request-Id":     {"NDI="},
+	}, h)
+
+	newCtx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", newCtx.Value(keyStr))
+	require.Equal(t, float64(42), newCtx.Value(keyInt))
+}
+
+func TestRegistryConcurrency(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr)))
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if i == 0 {
+					_ = r.Configure(ctxwire.NewJSONPropagator("int", keyInt))
+					r.Unregister("int")
+					continue
+				}
+				h := http.Header{}
+				_ = r.Inject(ctx, h)
+				_, _ = r.Extract(context.Background(), h)
+			}
+		}()
+	}
+	wg.Wait()
+	require.Len(t, r.Propagators(), 1)
+}
+
+func TestRegistryErrorPolicy(t *testing.T) {
+	propagators := []ctxwire.Propagator{
+		ctxwire.NewPropagator("encode", keyEncode, ctxwire.WithEncoder(ctxwire.EncoderFunc(errEncoder))),
+		ctxwire.NewPropagator("decode", keyDecode, ctxwire.WithDecoder(ctxwire.DecoderFunc(errDecoder))),
+		ctxwire.NewJSONPropagator("str", keyStr),
+	}
+	ctx := context.WithValue(context.Background(), keyEncode, "foo")
+	ctx = context.WithValue(ctx, keyDecode, "bar")
+	ctx = context.WithValue(ctx, keyStr, "baz")
+
+	for _, tc := range []struct {
+		policy     ctxwire.ErrorPolicy
+		injectErr  string
+		extractErr string
+	}{
+		{ctxwire.FailFast, "encode context value: failed!", "decode context value: failed!"},
+		{ctxwire.SkipErrors, "", ""},
+		{ctxwire.CollectErrors, "propagator encode: encode context value: failed!", "propagator decode: decode context value: failed!"},
+	} {
+		r := ctxwire.NewRegistry(ctxwire.WithErrorPolicy(tc.policy))
+		require.NoError(t, r.Configure(propagators...))
+
+		h := http.Header{}
+		err := r.Inject(ctx, h)
+		if tc.injectErr == "" {
+			require.NoError(t, err)
+		} else {
+			require.EqualError(t, err, tc.injectErr)
+		}
+		if tc.policy == ctxwire.FailFast {
+			continue
+		}
+		require.NotEmpty(t, h.Get("x-ctxwire-decode"))
+		require.NotEmpty(t, h.Get("x-ctxwire-str"))
+
+		newCtx, err := r.Extract(context.Background(), h)
+		if tc.extractErr == "" {
+			require.NoError(t, err)
+		} else {
+			require.EqualError(t, err, tc.extractErr)
+		}
+		require.Equal(t, "baz", newCtx.Value(keyStr))
+	}
+}
+
+func TestRegistryJoinedErrors(t *testing.T) {
+	errBoom := errors.New("boom!")
+	r := ctxwire.NewRegistry(ctxwire.WithErrorPolicy(ctxwire.CollectErrors))
+	require.NoError(t, r.Configure(
+		ctxwire.NewPropagator("a", keyStr, ctxwire.WithEncoder(ctxwire.EncoderFunc(errEncoder))),
+		ctxwire.NewPropagator("b", keyInt, ctxwire.WithEncoder(ctxwire.EncoderFunc(
+			func(context.Context, any) ([]byte, error) { return nil, errBoom },
+		))),
+	))
+
+	err := r.Inject(context.WithValue(context.Background(), keyStr, "foo"), http.Header{})
+	require.EqualError(t, err, "propagator a: encode context value: failed!\npropagator b: encode context value: boom!")
+	require.ErrorIs(t, err, errBoom)
+	var ctxwireErr *ctxwire.Error
+	require.ErrorAs(t, err, &ctxwireErr)
+}
@@ -0,0 +1,166 @@
+package ctxwire
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrReplayed is returned when decoding a payload whose nonce was already seen,
+// such as a captured header sent again.
+var ErrReplayed = errors.New("replayed value")
+
+// Timestamp flags prefixing timestamped payloads.
+const (
+	flagTimestamp byte = iota
+	flagTimestampNonce
+)
+
+const nonceSize = 16
+
+// NonceStore records the nonces of the timestamped payloads received, to
+// reject the payloads sent again before they expire.
+type NonceStore interface {
+	// Add records the given nonce until the given expiry time, and reports
+	// whether it was not recorded yet.
+	Add(nonce []byte, expiry time.Time) bool
+}
+
+// MemoryNonceStore is a NonceStore keeping the nonces in memory, for the
+// services running a single instance or reached through sticky sessions.
+// A MemoryNonceStore is safe for concurrent use.
+type MemoryNonceStore struct {
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	sweep  time.Time
+}
+
+var _ NonceStore = (*MemoryNonceStore)(nil)
+
+// NewMemoryNonceStore returns a new empty MemoryNonceStore.
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{nonces: map[string]time.Time{}}
+}
+
+// Add implements the NonceStore interface. The expired nonces are forgotten.
+func (s *MemoryNonceStore) Add(nonce []byte, expiry time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.After(s.sweep) {
+		for n, exp := range s.nonces {
+			if now.After(exp) {
+				delete(s.nonces, n)
+			}
+		}
+		s.sweep = now.Add(time.Second)
+	}
+	if exp, ok := s.nonces[string(nonce)]; ok && !now.After(exp) {
+		return false
+	}
+	s.nonces[string(nonce)] = expiry
+	return true
+}
+
+// TimestampOption configures the timestamping of Timestamp.
+type TimestampOption func(s *stamper)
+
+// WithNonces adds a random nonce to the timestamped payloads, and rejects the
+// payloads whose nonce the given store already recorded with ErrReplayed, so
+// that captured headers can't be replayed at all, even before they expire.
+// Receivers without a store only check the timestamps.
+func WithNonces(store NonceStore) TimestampOption {
+	return func(s *stamper) { s.nonces = store }
+}
+
+type stamper struct {
+	enc    Encoder
+	dec    Decoder
+	maxAge time.Duration
+	nonces NonceStore
+	now    func() time.Time
+}
+
+// Timestamp returns an encoder and a decoder wrapping the given ones to stamp
+// the encoded payloads with their creation time, and reject on decode the
+// payloads older than the given maximum age with ErrExpired, limiting the
+// replay of captured headers. Payloads stamped more than the maximum age in
+// the future are rejected too.
+// Timestamps are not authenticated: apply a signing or encryption layer after
+// the timestamping, such as with
+//
+//	JSONCodec.Wrap(TimestampMiddleware(time.Minute), SignMiddleware("", ring))
+//
+// so that they can't be forged.
+// Mergers implemented by the given decoder must be set explicitly with
+// WithMerger.
+func Timestamp(enc Encoder, dec Decoder, maxAge time.Duration, opts ...TimestampOption) (Encoder, Decoder) {
+	s := &stamper{enc: enc, dec: dec, maxAge: maxAge, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return EncoderFunc(s.encode), DecoderFunc(s.decode)
+}
+
+// TimestampMiddleware returns a middleware timestamping the payloads as
+// Timestamp does.
+func TimestampMiddleware(maxAge time.Duration, opts ...TimestampOption) CodecMiddleware {
+	return func(c Codec) Codec {
+		enc, dec := Timestamp(c.Encoder, c.Decoder, maxAge, opts...)
+		return Codec{Encoder: enc, Decoder: dec}
+	}
+}
+
+// Timestamped payloads are made of a flag byte, the creation time in
+// nanoseconds since the Unix epoch on 8 big-endian bytes, the nonce, if
+// flagged, and the payload.
+func (s *stamper) encode(ctx context.Context, key any) ([]byte, error) {
+	data, err := s.enc.Encode(ctx, key)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	out := make([]byte, 9, 9+nonceSize+len(data))
+	out[0] = flagTimestamp
+	binary.BigEndian.PutUint64(out[1:], uint64(s.now().UnixNano()))
+	if s.nonces != nil {
+		out[0] = flagTimestampNonce
+		out = out[:9+nonceSize]
+		if _, err := rand.Read(out[9:]); err != nil {
+			return nil, err
+		}
+	}
+	return append(out, data...), nil
+}
+
+func (s *stamper) decode(ctx context.Context, key any, data []byte) (context.Context, error) {
+	if len(data) < 9 {
+		return nil, errors.New("missing timestamp")
+	}
+	flag, created := data[0], time.Unix(0, int64(binary.BigEndian.Uint64(data[1:9])))
+	if age := s.now().Sub(created); age > s.maxAge || age < -s.maxAge {
+		return nil, fmt.Errorf("value created at %s: %w", created.UTC().Format(time.RFC3339), ErrExpired)
+	}
+	payload := data[9:]
+	switch flag {
+	case flagTimestamp:
+		if s.nonces != nil {
+			return nil, errors.New("missing nonce")
+		}
+	case flagTimestampNonce:
+		if len(payload) < nonceSize {
+			return nil, errors.New("missing nonce")
+		}
+		nonce := payload[:nonceSize]
+		payload = payload[nonceSize:]
+		if s.nonces != nil && !s.nonces.Add(nonce, created.Add(s.maxAge)) {
+			return nil, ErrReplayed
+		}
+	default:
+		return nil, fmt.Errorf("unknown timestamp flag %#x", flag)
+	}
+	return s.dec.Decode(ctx, key, payload)
+}
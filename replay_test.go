@@ -0,0 +1,73 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestTimestamp(t *testing.T) {
+	ring := ctxwire.NewKeyRing("k1", []byte("secret"))
+	newRegistry := func(maxAge time.Duration) *ctxwire.Registry {
+		r := ctxwire.NewRegistry()
+		codec := ctxwire.JSONCodec.Wrap(ctxwire.TimestampMiddleware(maxAge), ctxwire.SignMiddleware("", ring))
+		require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithCodec(codec))))
+		return r
+	}
+	send, recv := newRegistry(time.Minute), newRegistry(50*time.Millisecond)
+
+	h := http.Header{}
+	require.NoError(t, send.Inject(context.WithValue(context.Background(), keyStr, "v"), h))
+	ctx, err := recv.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "v", ctx.Value(keyStr))
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = recv.Extract(context.Background(), h)
+	require.ErrorIs(t, err, ctxwire.ErrExpired)
+	_, err = send.Extract(context.Background(), h)
+	require.NoError(t, err)
+}
+
+func TestTimestampNonces(t *testing.T) {
+	enc, dec := ctxwire.Timestamp(ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON),
+		time.Minute, ctxwire.WithNonces(ctxwire.NewMemoryNonceStore()))
+	ctx := context.WithValue(context.Background(), keyStr, "v")
+
+	first, err := enc.Encode(ctx, keyStr)
+	require.NoError(t, err)
+	second, err := enc.Encode(ctx, keyStr)
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+
+	for _, data := range [][]byte{first, second} {
+		got, err := dec.Decode(context.Background(), keyStr, data)
+		require.NoError(t, err)
+		require.Equal(t, "v", got.Value(keyStr))
+	}
+	_, err = dec.Decode(context.Background(), keyStr, first)
+	require.ErrorIs(t, err, ctxwire.ErrReplayed)
+
+	// Receivers without a nonce store only check the timestamps.
+	_, lenient := ctxwire.Timestamp(ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON), time.Minute)
+	_, err = lenient.Decode(context.Background(), keyStr, first)
+	require.NoError(t, err)
+
+	plain, _ := ctxwire.Timestamp(ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON), time.Minute)
+	data, err := plain.Encode(ctx, keyStr)
+	require.NoError(t, err)
+	_, err = dec.Decode(context.Background(), keyStr, data)
+	require.EqualError(t, err, "missing nonce")
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	s := ctxwire.NewMemoryNonceStore()
+	require.True(t, s.Add([]byte("n1"), time.Now().Add(time.Minute)))
+	require.False(t, s.Add([]byte("n1"), time.Now().Add(time.Minute)))
+	require.True(t, s.Add([]byte("n2"), time.Now().Add(-time.Second)))
+	require.True(t, s.Add([]byte("n2"), time.Now().Add(time.Minute)))
+}
@@ -0,0 +1,63 @@
+package ctxwire
+
+// ExtractReport describes the outcome of an extraction for each propagator of
+// a registry, in the order they ran.
+type ExtractReport struct {
+	Propagators []PropagatorReport
+}
+
+// PropagatorReport describes the outcome of an extraction for a single
+// propagator.
+// Only propagators exposing their header keys with a HeaderKeys() []string
+// method, such as ValuePropagator, can report the headers they found.
+type PropagatorReport struct {
+	// Name is the name of the propagator, if it exposes one.
+	Name string
+	// Found reports whether at least one header of the propagator was found.
+	Found bool
+	// Size is the decoded size in bytes of the values found.
+	Size int
+}
+
+// Found returns the names of the propagators which found their headers.
+func (r *ExtractReport) Found() []string {
+	var names []string
+	for _, p := range r.Propagators {
+		if p.Found {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// Missing returns the names of the propagators which were skipped because
+// none of their headers was found.
+func (r *ExtractReport) Missing() []string {
+	var names []string
+	for _, p := range r.Propagators {
+		if !p.Found {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+func (r *ExtractReport) add(p Propagator, c Carrier) {
+	var pr PropagatorReport
+	if n, ok := p.(named); ok {
+		pr.Name = n.Name()
+	}
+	var enc ByteEncoding
+	if e, ok := p.(byteEncoded); ok {
+		enc = e.headerEncoding()
+	}
+	if k, ok := p.(headerKeyer); ok {
+		for _, key := range k.HeaderKeys() {
+			for _, v := range values(c, key) {
+				pr.Found = true
+				pr.Size += decodedLen(enc, v)
+			}
+		}
+	}
+	r.Propagators = append(r.Propagators, pr)
+}
@@ -0,0 +1,32 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestExtractWithReport(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewJSONPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("int", keyInt),
+		ctxwire.NewStructPropagator[session]("session", sessionKey{}),
+	))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "foo"), h))
+	ctx, report, err := r.ExtractWithReport(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+	require.Equal(t, []ctxwire.PropagatorReport{
+		{Name: "str", Found: true, Size: len(`"foo"`)},
+		{Name: "int"},
+		{Name: "session"},
+	}, report.Propagators)
+	require.Equal(t, []string{"str"}, report.Found())
+	require.Equal(t, []string{"int", "session"}, report.Missing())
+}
@@ -0,0 +1,134 @@
+package ctxwire
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+)
+
+// ResponseWriter is an http.ResponseWriter injecting the context values into
+// the response headers right before they are written, so that the values
+// recorded with Put while handling the request are sent back to the client.
+// It passes the http.Flusher and http.Hijacker capabilities of the underlying
+// response writer through, and supports http.ResponseController.
+//
+// Streamed responses, flushed before the handler returns, get their headers
+// injected before the first flush. The values changed afterwards are sent in
+// the response trailers on Close, from which clients extract them with
+// ExtractResponse once the response body is read.
+type ResponseWriter struct {
+	http.ResponseWriter
+	registry *Registry
+	ctx      context.Context
+	injected bool
+	streamed bool
+	hijacked bool
+	// sent are the headers injected before the response was written.
+	sent http.Header
+	err  error
+}
+
+var (
+	_ http.Flusher  = (*ResponseWriter)(nil)
+	_ http.Hijacker = (*ResponseWriter)(nil)
+)
+
+// NewResponseWriter returns a ResponseWriter wrapping w, injecting the values
+// of the given context using the propagators of the default registry.
+// The context should hold a box created with WithBox for the values recorded
+// with Put to be injected.
+func NewResponseWriter(ctx context.Context, w http.ResponseWriter) *ResponseWriter {
+	return defaultRegistry.NewResponseWriter(ctx, w)
+}
+
+// NewResponseWriter returns a ResponseWriter wrapping w, injecting the values
+// of the given context using the propagators of the registry.
+// The context should hold a box created with WithBox for the values recorded
+// with Put to be injected.
+func (r *Registry) NewResponseWriter(ctx context.Context, w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w, registry: r, ctx: ctx}
+}
+
+func (w *ResponseWriter) inject() {
+	if w.injected {
+		return
+	}
+	w.injected = true
+	w.sent = http.Header{}
+	w.err = w.registry.InjectResponse(w.ctx, w.sent)
+	for key, vs := range w.sent {
+		w.ResponseWriter.Header()[key] = vs
+	}
+}
+
+// WriteHeader implements the http.ResponseWriter interface. The values are
+// injected unless the response is an informational one, except 101 Switching
+// Protocols.
+func (w *ResponseWriter) WriteHeader(code int) {
+	if code >= http.StatusOK || code == http.StatusSwitchingProtocols {
+		w.inject()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	w.inject()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements the http.Flusher interface. It does nothing if the
+// underlying response writer doesn't support flushing.
+func (w *ResponseWriter) Flush() {
+	w.inject()
+	w.streamed = true
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements the http.Hijacker interface. The values are not injected
+// into hijacked connections. It returns an error wrapping
+// http.ErrNotSupported if the underlying response writer doesn't support
+// hijacking.
+func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying response writer, for http.ResponseController.
+func (w *ResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Close injects the values into the response headers if the response wasn't
+// written yet, and the values of the trailer propagators into the response
+// trailers, along with the values changed since the first flush of streamed
+// responses. It must be called once the request is handled, and returns the
+// errors which occurred injecting the values.
+func (w *ResponseWriter) Close() error {
+	if w.hijacked {
+		return w.err
+	}
+	w.inject()
+	err := w.err
+	if w.streamed {
+		err = errors.Join(err, w.injectChanged())
+	}
+	return errors.Join(err, w.registry.InjectTrailer(w.ctx, w.ResponseWriter))
+}
+
+// injectChanged injects the values changed since the headers were sent into
+// the response trailers.
+func (w *ResponseWriter) injectChanged() error {
+	h := http.Header{}
+	err := w.registry.InjectResponse(w.ctx, h)
+	for key, vs := range h {
+		if !slices.Equal(vs, w.sent[key]) {
+			w.ResponseWriter.Header()[http.TrailerPrefix+key] = vs
+		}
+	}
+	return err
+}
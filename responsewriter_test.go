@@ -0,0 +1,132 @@
+package ctxwire_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestResponseWriter(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("count", keyInt)))
+
+	ctx := ctxwire.WithBox(context.Background())
+	rec := httptest.NewRecorder()
+	w := r.NewResponseWriter(ctx, rec)
+	ctxwire.Put(ctx, keyInt, 2)
+	w.WriteHeader(http.StatusCreated)
+	ctxwire.Put(ctx, keyInt, 3)
+	_, err := w.Write([]byte("ok"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "2", rec.Header().Get("x-ctxwire-count"))
+
+	// Flushing writes the headers.
+	rec = httptest.NewRecorder()
+	w = r.NewResponseWriter(context.WithValue(context.Background(), keyInt, 4), rec)
+	http.NewResponseController(w).Flush()
+	require.True(t, rec.Flushed)
+	require.Equal(t, "4", rec.Header().Get("x-ctxwire-count"))
+	require.Same(t, rec, w.Unwrap())
+}
+
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (h *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return h.conn, nil, nil
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewIntPropagator("count", keyInt)))
+	ctx := context.WithValue(context.Background(), keyInt, 1)
+
+	server, client := net.Pipe()
+	defer client.Close()
+	rec := &hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}
+	w := r.NewResponseWriter(ctx, rec)
+	conn, _, err := http.NewResponseController(w).Hijack()
+	require.NoError(t, err)
+	require.Same(t, server, conn)
+	require.NoError(t, w.Close())
+	require.Empty(t, rec.Header().Get("x-ctxwire-count"))
+
+	w = r.NewResponseWriter(ctx, httptest.NewRecorder())
+	_, _, err = w.Hijack()
+	require.True(t, errors.Is(err, http.ErrNotSupported))
+}
+
+func TestResponseWriterServer(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewStringPropagator("user", keyStr))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := ctxwire.WithBox(req.Context())
+		rw := ctxwire.NewResponseWriter(ctx, w)
+		defer rw.Close()
+		ctxwire.Put(ctx, keyStr, "alice")
+		_, _ = io.WriteString(rw, "hello")
+	}))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "alice", resp.Header.Get("x-ctxwire-user"))
+}
+
+func TestResponseWriterStreaming(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewIntPropagator("count", keyInt),
+		ctxwire.NewStringPropagator("user", keyStr),
+	))
+
+	srv := httptest.NewServer(r.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctxwire.Put(req.Context(), keyInt, 1)
+		ctxwire.Put(req.Context(), keyStr, "alice")
+		_, _ = io.WriteString(w, "chunk1")
+		http.NewResponseController(w).Flush()
+		ctxwire.Put(req.Context(), keyInt, 2)
+		_, _ = io.WriteString(w, "chunk2")
+	})))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "1", resp.Header.Get("x-ctxwire-count"))
+	require.Equal(t, "alice", resp.Header.Get("x-ctxwire-user"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "chunk1chunk2", string(body))
+	require.Equal(t, http.Header{"X-Ctxwire-Count": {"2"}}, resp.Trailer)
+
+	ctx, err := r.ExtractResponse(context.Background(), resp.Header)
+	require.NoError(t, err)
+	ctx, err = r.ExtractResponse(ctx, resp.Trailer)
+	require.NoError(t, err)
+	require.Equal(t, 2, ctx.Value(keyInt))
+	require.Equal(t, "alice", ctx.Value(keyStr))
+
+	// Responses not flushed before completion don't get trailers.
+	rec := httptest.NewRecorder()
+	bctx := ctxwire.WithBox(context.Background())
+	w := r.NewResponseWriter(bctx, rec)
+	ctxwire.Put(bctx, keyInt, 1)
+	_, _ = io.WriteString(w, "body")
+	ctxwire.Put(bctx, keyInt, 2)
+	require.NoError(t, w.Close())
+	require.Equal(t, "1", rec.Header().Get("x-ctxwire-count"))
+	require.Empty(t, rec.Header().Get(http.TrailerPrefix+"x-ctxwire-count"))
+}
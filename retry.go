@@ -0,0 +1,73 @@
+package ctxwire
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Reinject removes the headers of the propagators of the default registry
+// from the given request headers and injects the context values into them,
+// skipping the ResponseOnly propagators. Retry layers call it before
+// re-sending a request, so that the headers reflect the current context
+// instead of the one of the first attempt.
+func Reinject(ctx context.Context, h http.Header) error {
+	return defaultRegistry.Reinject(ctx, h)
+}
+
+// Reinject removes the headers of the propagators of the registry, and its
+// envelope header if it uses one, from the given request headers and injects
+// the context values into them, skipping the ResponseOnly propagators. Retry
+// layers call it before re-sending a request, so that the headers reflect the
+// current context instead of the one of the first attempt.
+// The other headers with the header prefix of the registry, such as the ones
+// forwarded by a PassthroughPropagator, are kept.
+func (r *Registry) Reinject(ctx context.Context, h http.Header) error {
+	for _, info := range r.Propagators() {
+		for _, key := range info.HeaderKeys {
+			h.Del(key)
+		}
+	}
+	if r.envelope != noEnvelope {
+		h.Del(r.envelopeKey())
+	}
+	return r.InjectRequest(ctx, h)
+}
+
+type attemptsKey struct{}
+
+// attempts holds the values extracted from the attempts of a request.
+type attempts struct {
+	mu  sync.Mutex
+	ctx context.Context
+}
+
+// TrackAttempts returns a copy of the given context in which the Transport
+// accumulates the values extracted from the responses of all the attempts of
+// a request, such as the ones made by a retry layer. The values are extracted
+// from each response into the context holding the values of the previous
+// attempts, so that they are merged by the propagators with a Merger, and
+// ContextFromResponse returns the values of all the attempts.
+func TrackAttempts(ctx context.Context) context.Context {
+	return context.WithValue(ctx, attemptsKey{}, &attempts{})
+}
+
+// extractAttempt extracts the values of a response with the given
+// function, into the context holding the values of the previous attempts of
+// the request, if tracked, or else into the given context.
+func extractAttempt(ctx context.Context, extract func(ctx context.Context) (context.Context, error)) (context.Context, error) {
+	a, ok := ctx.Value(attemptsKey{}).(*attempts)
+	if !ok {
+		return extract(ctx)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.ctx != nil {
+		ctx = a.ctx
+	}
+	newCtx, err := extract(ctx)
+	if newCtx != nil {
+		a.ctx = newCtx
+	}
+	return newCtx, err
+}
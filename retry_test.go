@@ -0,0 +1,85 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestReinject(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("hops", keyStr, ctxwire.WithMultiValue()),
+		ctxwire.NewIntPropagator("int", keyInt, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	ctx := context.WithValue(context.Background(), keyStr, "a")
+	// Headers with the prefix which no propagator owns, such as forwarded
+	// ones, are kept.
+	h := http.Header{"X-Other": {"kept"}, "X-Ctxwire-Forwarded": {"1"}}
+	require.NoError(t, r.InjectRequest(ctx, h))
+	require.NoError(t, r.Reinject(context.WithValue(ctx, keyStr, "b"), h))
+	require.Equal(t, http.Header{"X-Other": {"kept"}, "X-Ctxwire-Forwarded": {"1"}, "X-Ctxwire-Hops": {"b"}}, h)
+
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewStringPropagator("str", keyStr))
+	h = http.Header{"X-Ctxwire-Str": {"stale"}}
+	require.NoError(t, ctxwire.Reinject(context.Background(), h))
+	require.Empty(t, h)
+}
+
+func TestReinjectEnvelope(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithEnvelope())
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("str", keyStr)))
+	h := http.Header{}
+	require.NoError(t, r.InjectRequest(context.WithValue(context.Background(), keyStr, "a"), h))
+	require.NotEmpty(t, h.Get("x-ctxwire"))
+	require.NoError(t, r.Reinject(context.Background(), h))
+	require.Empty(t, h)
+}
+
+func TestTransportRetries(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("hops", keyStr, ctxwire.WithMultiValue(), ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewStringPropagator("logs", logsKey{}, ctxwire.WithMerger(mergeLogs), ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	var sent []http.Header
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = append(sent, req.Header)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Ctxwire-Logs": {"attempt" + strconv.Itoa(len(sent))}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	transport := ctxwire.NewTransport(base, ctxwire.WithTransportRegistry(r))
+
+	ctx := ctxwire.TrackAttempts(context.WithValue(context.Background(), keyStr, "a"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	// The request carries the headers of a previous injection.
+	require.NoError(t, r.InjectRequest(ctx, req.Header))
+
+	var resp *http.Response
+	for range 3 {
+		resp, err = transport.RoundTrip(req)
+		require.NoError(t, err)
+	}
+	for _, h := range sent {
+		require.Equal(t, []string{"a"}, h.Values("x-ctxwire-hops"))
+	}
+	require.Equal(t, "attempt1,attempt2,attempt3", ctxwire.ContextFromResponse(resp).Value(logsKey{}))
+
+	// Without tracking, each attempt is extracted on its own.
+	req = req.WithContext(context.WithValue(context.Background(), keyStr, "a"))
+	_, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	resp, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, "attempt5", ctxwire.ContextFromResponse(resp).Value(logsKey{}))
+}
@@ -0,0 +1,114 @@
+package ctxwire
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SanitizeOption configures the middleware returned by Sanitize.
+type SanitizeOption func(s *sanitizer)
+
+// WithAllowedPropagators lets the headers of the propagators with the given
+// names through, for the values external clients are allowed to set.
+func WithAllowedPropagators(names ...string) SanitizeOption {
+	return func(s *sanitizer) {
+		for _, name := range names {
+			s.allowedNames[name] = true
+		}
+	}
+}
+
+// WithAllowedHeaders lets the headers with the given keys through. Keys are
+// case-insensitive.
+func WithAllowedHeaders(keys ...string) SanitizeOption {
+	return func(s *sanitizer) {
+		for _, key := range keys {
+			s.allowedKeys[http.CanonicalHeaderKey(key)] = true
+		}
+	}
+}
+
+// WithTrustedRequests sets the function reporting whether a request comes
+// from a trusted client, such as an internal service, in which case its
+// headers are kept. By default, no request is trusted.
+func WithTrustedRequests(trusted func(req *http.Request) bool) SanitizeOption {
+	return func(s *sanitizer) { s.trusted = trusted }
+}
+
+type sanitizer struct {
+	registry     *Registry
+	next         http.Handler
+	allowedNames map[string]bool
+	allowedKeys  map[string]bool
+	trusted      func(req *http.Request) bool
+}
+
+// Sanitize returns a middleware removing the ctxwire headers from the requests
+// of untrusted clients, so that the values internal to a system can't be
+// spoofed from the outside. It is meant to run at the edge, such as in public
+// gateways.
+// The removed headers are the ones with the default header prefix, the
+// envelope header and the headers of the propagators of the default registry,
+// unless allowed with WithAllowedPropagators or WithAllowedHeaders.
+func Sanitize(next http.Handler, opts ...SanitizeOption) http.Handler {
+	return defaultRegistry.Sanitize(next, opts...)
+}
+
+// Sanitize returns a middleware removing the ctxwire headers from the requests
+// of untrusted clients, so that the values internal to a system can't be
+// spoofed from the outside. It is meant to run at the edge, such as in public
+// gateways.
+// The removed headers are the ones with the header prefix of the registry, its
+// envelope header and the headers of its propagators, unless allowed with
+// WithAllowedPropagators or WithAllowedHeaders.
+func (r *Registry) Sanitize(next http.Handler, opts ...SanitizeOption) http.Handler {
+	s := &sanitizer{
+		registry:     r,
+		next:         next,
+		allowedNames: map[string]bool{},
+		allowedKeys:  map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *sanitizer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if s.trusted == nil || !s.trusted(req) {
+		s.strip(req.Header)
+	}
+	s.next.ServeHTTP(w, req)
+}
+
+// strip removes the headers of the registry from h, except the allowed ones.
+func (s *sanitizer) strip(h http.Header) {
+	s.registry.removeHeaders(h, func(name, key string) bool {
+		return s.allowedKeys[key] || (name != "" && s.allowedNames[name])
+	})
+}
+
+// removeHeaders removes the headers of the registry from h: the headers with
+// its header prefix, its envelope header and the headers of its propagators.
+// The headers for which keep returns true are kept. It is called with the
+// canonical key of the header, and the name of the propagator using it, if
+// any.
+func (r *Registry) removeHeaders(h http.Header, keep func(name, key string) bool) {
+	owners := map[string]string{http.CanonicalHeaderKey(r.envelopeKey()): ""}
+	for _, info := range r.Propagators() {
+		for _, key := range info.HeaderKeys {
+			owners[http.CanonicalHeaderKey(key)] = info.Name
+		}
+	}
+	prefix := strings.ToLower(r.naming.headerPrefix())
+	for key := range h {
+		ck := http.CanonicalHeaderKey(key)
+		name, owned := owners[ck]
+		if !owned && (prefix == "" || !strings.HasPrefix(strings.ToLower(key), prefix)) {
+			continue
+		}
+		if keep == nil || !keep(name, ck) {
+			delete(h, key)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ctxwire_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestSanitize(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", keyStr),
+		ctxwire.NewStringPropagator("locale", keyInt),
+		ctxwire.NewTraceParentPropagator(),
+	))
+
+	var got http.Header
+	next := http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) { got = req.Header.Clone() })
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("x-ctxwire-user", "admin")
+		req.Header.Set("x-ctxwire-locale", "fr")
+		req.Header.Set("x-ctxwire-unknown", "1")
+		req.Header.Set("x-ctxwire", "{}")
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		req.Header.Set("x-other", "kept")
+		req.Header["x-ctxwire-raw"] = []string{"1"}
+		return req
+	}
+
+	r.Sanitize(next).ServeHTTP(httptest.NewRecorder(), newRequest())
+	require.Equal(t, http.Header{"X-Other": {"kept"}}, got)
+
+	r.Sanitize(next,
+		ctxwire.WithAllowedPropagators("locale"),
+		ctxwire.WithAllowedHeaders("TraceParent"),
+	).ServeHTTP(httptest.NewRecorder(), newRequest())
+	require.Equal(t, http.Header{
+		"X-Other":          {"kept"},
+		"X-Ctxwire-Locale": {"fr"},
+		"Traceparent":      {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+	}, got)
+
+	trusted := r.Sanitize(next, ctxwire.WithTrustedRequests(func(req *http.Request) bool {
+		return req.Header.Get("x-internal") == "1"
+	}))
+	req := newRequest()
+	req.Header.Set("x-internal", "1")
+	trusted.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, "admin", got.Get("x-ctxwire-user"))
+	trusted.ServeHTTP(httptest.NewRecorder(), newRequest())
+	require.Empty(t, got.Get("x-ctxwire-user"))
+}
+
+func TestSanitizeHeaderPrefix(t *testing.T) {
+	r := ctxwire.NewRegistry(ctxwire.WithHeaderPrefix("x-acme-"))
+	var got http.Header
+	h := r.Sanitize(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) { got = req.Header }))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-acme-user", "admin")
+	req.Header.Set("x-acme", "{}")
+	req.Header.Set("x-ctxwire-user", "admin")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.Equal(t, http.Header{"X-Ctxwire-User": {"admin"}}, got)
+}
+
+func TestDefaultSanitize(t *testing.T) {
+	var got http.Header
+	h := ctxwire.Sanitize(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) { got = req.Header }))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("x-ctxwire-user", "admin")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	require.Empty(t, got)
+}
@@ -0,0 +1,122 @@
+package ctxwire
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidSignature is returned when decoding a signed payload whose
+// signature doesn't match, such as a value tampered with on the wire.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// maxKeyIDLen is the maximum length of a key id.
+const maxKeyIDLen = 255
+
+// KeyProvider looks up the secret keys used to sign and verify payloads by
+// key id, so that keys can be stored in a secret manager and rotated.
+type KeyProvider interface {
+	// Key returns the key with the given id, or an error if there is none.
+	Key(id string) ([]byte, error)
+}
+
+// KeyProviderFunc is an adapter to use ordinary functions as KeyProviders.
+type KeyProviderFunc func(id string) ([]byte, error)
+
+// Key calls f(id).
+func (f KeyProviderFunc) Key(id string) ([]byte, error) { return f(id) }
+
+type signer struct {
+	enc   Encoder
+	dec   Decoder
+	keyID string
+	keys  KeyProvider
+}
+
+// Sign returns an encoder and a decoder wrapping the given ones to sign the
+// encoded payloads with HMAC-SHA256. The encoder appends the given key id and
+// the signature of the payload, computed with the key of that id, and the
+// decoder verifies the signature with the key of the id it carries, rejecting
+// tampered payloads with ErrInvalidSignature. Signatures cover the header key
+// of the values, so that a payload signed for one propagator is rejected by
+// the others sharing its key. Key ids are made of at most 255 bytes. With an
+// empty key id and a PrimaryKeyProvider, such as a KeyRing, the payloads are
+// signed with its current primary key, so that keys can rotate.
+// Signing doesn't hide the payloads; combine it with encryption for sensitive
+// values.
+// Mergers implemented by the given decoder must be set explicitly with
+// WithMerger.
+func Sign(enc Encoder, dec Decoder, keyID string, keys KeyProvider) (Encoder, Decoder) {
+	if len(keyID) > maxKeyIDLen {
+		panic(fmt.Sprintf("ctxwire: Sign: key id %q exceeds %d bytes", keyID, maxKeyIDLen))
+	}
+	s := &signer{enc: enc, dec: dec, keyID: keyID, keys: keys}
+	return EncoderFunc(s.encode), DecoderFunc(s.decode)
+}
+
+// SignMiddleware returns a middleware signing the payloads as Sign does.
+func SignMiddleware(keyID string, keys KeyProvider) CodecMiddleware {
+	return func(c Codec) Codec {
+		enc, dec := Sign(c.Encoder, c.Decoder, keyID, keys)
+		return Codec{Encoder: enc, Decoder: dec}
+	}
+}
+
+// Signed payloads are made of the payload, the key id, the length of the key
+// id on one byte and the HMAC-SHA256 of the header key of the value and all
+// the preceding bytes.
+func (s *signer) encode(ctx context.Context, key any) ([]byte, error) {
+	data, err := s.enc.Encode(ctx, key)
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+	keyID, secret, err := encodingKey(s.keys, s.keyID)
+	if err != nil {
+		return nil, fmt.Errorf("signing key %q: %w", keyID, err)
+	}
+	out := make([]byte, 0, len(data)+len(keyID)+1+sha256.Size)
+	out = append(out, data...)
+	out = append(out, keyID...)
+	out = append(out, byte(len(keyID)))
+	return append(out, signMAC(secret, headerKeyFrom(ctx), out)...), nil
+}
+
+func (s *signer) decode(ctx context.Context, key any, data []byte) (context.Context, error) {
+	if len(data) < sha256.Size+1 {
+		return nil, ErrInvalidSignature
+	}
+	signed, mac := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	n := int(signed[len(signed)-1])
+	if len(signed) < n+1 {
+		return nil, ErrInvalidSignature
+	}
+	payload := signed[:len(signed)-n-1]
+	keyID := string(signed[len(payload) : len(signed)-1])
+	secret, err := s.keys.Key(keyID)
+	if err != nil {
+		return nil, fmt.Errorf("verification key %q: %w", keyID, err)
+	}
+	if !hmac.Equal(mac, signMAC(secret, headerKeyFrom(ctx), signed)) {
+		return nil, ErrInvalidSignature
+	}
+	return s.dec.Decode(ctx, key, payload)
+}
+
+// signMAC returns the HMAC-SHA256 of the given signed bytes bound to the given
+// header key, so that signed payloads can't be moved to the header of another
+// propagator sharing the key.
+func signMAC(secret []byte, headerKey string, signed []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte(headerKey))
+	m.Write([]byte{0})
+	m.Write(signed)
+	return m.Sum(nil)
+}
+
+func computeMAC(secret, data []byte) []byte {
+	m := hmac.New(sha256.New, secret)
+	m.Write(data)
+	return m.Sum(nil)
+}
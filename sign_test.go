@@ -0,0 +1,100 @@
+package ctxwire_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+var errUnknownKey = errors.New("unknown key")
+
+func keyProvider(keys map[string]string) ctxwire.KeyProvider {
+	return ctxwire.KeyProviderFunc(func(id string) ([]byte, error) {
+		key, ok := keys[id]
+		if !ok {
+			return nil, errUnknownKey
+		}
+		return []byte(key), nil
+	})
+}
+
+func TestSign(t *testing.T) {
+	keys := keyProvider(map[string]string{"k1": "secret1", "k2": "secret2"})
+	r := ctxwire.NewRegistry()
+	codec := ctxwire.JSONCodec.Wrap(ctxwire.SignMiddleware("k1", keys))
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr, ctxwire.WithCodec(codec))))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "admin"), h))
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, "admin", ctx.Value(keyStr))
+
+	data, err := base64.StdEncoding.DecodeString(h.Get("x-ctxwire-str"))
+	require.NoError(t, err)
+	tampered := append([]byte(`"root"`), data[len(`"admin"`):]...)
+	h.Set("x-ctxwire-str", base64.StdEncoding.EncodeToString(tampered))
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorIs(t, err, ctxwire.ErrInvalidSignature)
+
+	h.Set("x-ctxwire-str", base64.StdEncoding.EncodeToString([]byte(`"root"`)))
+	_, err = r.Extract(context.Background(), h)
+	require.ErrorIs(t, err, ctxwire.ErrInvalidSignature)
+}
+
+func TestSignKeyLookup(t *testing.T) {
+	enc, _ := ctxwire.Sign(ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON),
+		"k2", keyProvider(map[string]string{"k2": "secret2"}))
+	_, dec := ctxwire.Sign(ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON),
+		"k1", keyProvider(map[string]string{"k1": "secret1", "k2": "secret2"}))
+
+	data, err := enc.Encode(context.WithValue(context.Background(), keyStr, "v"), keyStr)
+	require.NoError(t, err)
+	ctx, err := dec.Decode(context.Background(), keyStr, data)
+	require.NoError(t, err)
+	require.Equal(t, "v", ctx.Value(keyStr))
+
+	_, dec = ctxwire.Sign(ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON),
+		"k2", keyProvider(map[string]string{"k2": "other"}))
+	_, err = dec.Decode(context.Background(), keyStr, data)
+	require.ErrorIs(t, err, ctxwire.ErrInvalidSignature)
+
+	_, dec = ctxwire.Sign(ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON),
+		"k1", keyProvider(map[string]string{"k1": "secret1"}))
+	_, err = dec.Decode(context.Background(), keyStr, data)
+	require.ErrorIs(t, err, errUnknownKey)
+	require.EqualError(t, err, `verification key "k2": unknown key`)
+}
+
+func TestSignHeaderBinding(t *testing.T) {
+	keys := keyProvider(map[string]string{"k1": "secret1"})
+	codec := ctxwire.JSONCodec.Wrap(ctxwire.SignMiddleware("k1", keys))
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewPropagator("user", keyStr, ctxwire.WithCodec(codec)),
+		ctxwire.NewPropagator("admin", keyInt, ctxwire.WithCodec(codec)),
+	))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), keyStr, "alice"), h))
+	h.Set("x-ctxwire-admin", h.Get("x-ctxwire-user"))
+	_, err := r.Extract(context.Background(), h)
+	require.ErrorIs(t, err, ctxwire.ErrInvalidSignature)
+}
+
+func TestSignCopiesPayload(t *testing.T) {
+	buf := make([]byte, 0, 64)
+	inner := ctxwire.EncoderFunc(func(context.Context, any) ([]byte, error) {
+		return append(buf[:0], `"v"`...), nil
+	})
+	enc, _ := ctxwire.Sign(inner, ctxwire.DecoderFunc(ctxwire.DecodeJSON), "k1", keyProvider(map[string]string{"k1": "secret1"}))
+	_, err := enc.Encode(context.Background(), keyStr)
+	require.NoError(t, err)
+	require.Equal(t, `"v"`, string(buf[:3]))
+	require.Equal(t, make([]byte, 61), buf[3:64])
+}
@@ -0,0 +1,149 @@
+package ctxwire
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// StructPropagator propagates the fields of a struct context value, each field
+// in its own header.
+// Fields are selected with the ctxwire struct tag, whose value is the name of
+// the field on the wire:
+//
+//	type Session struct {
+//		UserID string `ctxwire:"user-id"`
+//		Locale string `ctxwire:"locale,omitempty"`
+//		Secret string `ctxwire:"-"`
+//	}
+//
+// Fields without tag or tagged with "-" are not propagated. The omitempty
+// option skips the field when it holds its zero value.
+// Fields are encoded as JSON.
+// It implements the Propagator interface.
+type StructPropagator[T any] struct {
+	name       string
+	contextKey any
+	fields     []structField
+	naming     *headerNaming
+}
+
+var _ Propagator = (*StructPropagator[struct{}])(nil)
+
+type structField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// NewStructPropagator returns a new StructPropagator with the given name,
+// propagating the T value associated with the given context key.
+// It panics if T is not a struct type.
+func NewStructPropagator[T any](name string, contextKey any) *StructPropagator[T] {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("ctxwire: NewStructPropagator: %s is not a struct type", t))
+	}
+	p := &StructPropagator[T]{name: name, contextKey: contextKey}
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag, ok := f.Tag.Lookup("ctxwire")
+		if !ok || tag == "-" || !f.IsExported() {
+			continue
+		}
+		fieldName, opts, _ := strings.Cut(tag, ",")
+		if fieldName == "" {
+			fieldName = strings.ToLower(f.Name)
+		}
+		p.fields = append(p.fields, structField{
+			index:     i,
+			name:      fieldName,
+			omitEmpty: opts == "omitempty",
+		})
+	}
+	return p
+}
+
+// Name returns the name of the propagator.
+func (p *StructPropagator[T]) Name() string { return p.name }
+
+func (p *StructPropagator[T]) withHeaderNaming(naming *headerNaming) Propagator {
+	c := *p
+	c.naming = naming
+	return &c
+}
+
+func (p *StructPropagator[T]) headerEncoding() ByteEncoding { return p.naming.byteEncoding() }
+
+// HeaderKeys returns the header keys used by the propagator.
+func (p *StructPropagator[T]) HeaderKeys() []string {
+	keys := make([]string, len(p.fields))
+	for i, f := range p.fields {
+		keys[i] = p.naming.key(f.name)
+	}
+	return keys
+}
+
+// Inject implements the Propagator interface.
+// Values cleared with Clear are injected as tombstones in the headers of all
+// the fields.
+func (p *StructPropagator[T]) Inject(ctx context.Context, c Carrier) error {
+	if Cleared(ctx, p.contextKey) {
+		for _, f := range p.fields {
+			c.Set(p.naming.key(f.name), TombstoneValue)
+		}
+		return nil
+	}
+	v, ok := ctx.Value(p.contextKey).(T)
+	if !ok {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	for _, f := range p.fields {
+		fv := rv.Field(f.index)
+		if f.omitEmpty && fv.IsZero() {
+			continue
+		}
+		data, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return newError(OpEncode, p.naming.key(f.name), "encode context value", err)
+		}
+		if err := setValue(c, p.naming.byteEncoding(), p.naming.key(f.name), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Extract implements the Propagator interface.
+// Fields not found in the carrier keep the value they have in the context, if
+// any. Tombstones remove the value from the context.
+func (p *StructPropagator[T]) Extract(ctx context.Context, c Carrier) (context.Context, error) {
+	for _, f := range p.fields {
+		if c.Get(p.naming.key(f.name)) == TombstoneValue {
+			return context.WithValue(ctx, p.contextKey, nil), nil
+		}
+	}
+	v, _ := ctx.Value(p.contextKey).(T)
+	rv := reflect.ValueOf(&v).Elem()
+	found := false
+	for _, f := range p.fields {
+		data, err := getValue(c, p.naming.byteEncoding(), p.naming.key(f.name))
+		if err != nil {
+			return nil, err
+		}
+		if data == nil {
+			continue
+		}
+		if err := json.Unmarshal(data, rv.Field(f.index).Addr().Interface()); err != nil {
+			return nil, newError(OpDecode, p.naming.key(f.name), "decode context value", err)
+		}
+		found = true
+	}
+	if !found {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, p.contextKey, v), nil
+}
@@ -0,0 +1,63 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type (
+	sessionKey struct{}
+	session    struct {
+		UserID string   `ctxwire:"user-id"`
+		Locale string   `ctxwire:"locale,omitempty"`
+		Scopes []string `ctxwire:"scopes"`
+		Secret string   `ctxwire:"-"`
+		Local  string
+	}
+)
+
+func TestStructPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStructPropagator[session]("session", sessionKey{})))
+
+	ctx := context.WithValue(context.Background(), sessionKey{}, session{
+		UserID: "42",
+		Scopes: []string{"read"},
+		Secret: "s3cr3t",
+		Local:  "local",
+	})
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.NotEmpty(t, h.Get("x-ctxwire-user-id"))
+	require.NotEmpty(t, h.Get("x-ctxwire-scopes"))
+	require.Len(t, h, 2)
+
+	ctx = context.WithValue(context.Background(), sessionKey{}, session{Locale: "fr", Local: "mine"})
+	ctx, err := r.Extract(ctx, h)
+	require.NoError(t, err)
+	require.Equal(t, session{
+		UserID: "42",
+		Locale: "fr",
+		Scopes: []string{"read"},
+		Local:  "mine",
+	}, ctx.Value(sessionKey{}))
+
+	require.Panics(t, func() { ctxwire.NewStructPropagator[string]("str", keyStr) })
+}
+
+func TestStructPropagatorTombstone(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStructPropagator[session]("session", sessionKey{})))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctxwire.Clear(context.Background(), sessionKey{}), h))
+	require.Equal(t, ctxwire.TombstoneValue, h.Get("x-ctxwire-user-id"))
+
+	ctx, err := r.Extract(context.WithValue(context.Background(), sessionKey{}, session{UserID: "42"}), h)
+	require.NoError(t, err)
+	require.Nil(t, ctx.Value(sessionKey{}))
+}
@@ -0,0 +1,388 @@
+package ctxwire
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// NewStructuredFieldPropagator returns a new ValuePropagator with the given
+// name propagating a T context value as an RFC 8941 HTTP Structured Field,
+// instead of base64 JSON. Headers are then standards-compliant, compact and
+// readable by proxies.
+// T is a string, a boolean, an integer, a float or a []byte, serialized as
+// a Structured Field item, or a map from string keys to such values,
+// serialized as a Structured Field dictionary. Dictionary values are decoded
+// as string, bool, int64, float64 or []byte values, tokens being decoded as
+// strings. Parameters and inner lists are not supported and rejected on
+// Extract.
+func NewStructuredFieldPropagator[T any](name string, contextKey any, opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{
+		WithEncoder(EncoderFunc(encodeStructuredField)),
+		WithDecoder(DecoderFunc(decodeStructuredField[T])),
+		WithByteEncoding(Raw),
+	}, opts...)
+	return NewPropagator(name, contextKey, opts...)
+}
+
+func encodeStructuredField(ctx context.Context, key any) ([]byte, error) {
+	v := ctx.Value(key)
+	if v == nil {
+		return nil, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map {
+		return appendSFItem(nil, rv)
+	}
+	if rv.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("unsupported structured field dictionary type %T", v)
+	}
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	slices.Sort(keys)
+	var b []byte
+	for i, k := range keys {
+		if !validSFKey(k) {
+			return nil, fmt.Errorf("invalid structured field key %q", k)
+		}
+		if i > 0 {
+			b = append(b, ", "...)
+		}
+		b = append(b, k...)
+		mv := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))
+		if mv.Kind() == reflect.Interface {
+			mv = mv.Elem()
+		}
+		if !mv.IsValid() {
+			return nil, fmt.Errorf("missing structured field value for key %q", k)
+		}
+		if mv.Kind() == reflect.Bool && mv.Bool() {
+			continue
+		}
+		var err error
+		if b, err = appendSFItem(append(b, '='), mv); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// appendSFItem appends the given value serialized as a Structured Field bare
+// item to b.
+func appendSFItem(b []byte, v reflect.Value) ([]byte, error) {
+	switch v.Kind() {
+	case reflect.String:
+		s := v.String()
+		b = append(b, '"')
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			if c < 0x20 || c > 0x7e {
+				return nil, fmt.Errorf("invalid character %q in structured field string", c)
+			}
+			if c == '"' || c == '\\' {
+				b = append(b, '\\')
+			}
+			b = append(b, c)
+		}
+		return append(b, '"'), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, "?1"...), nil
+		}
+		return append(b, "?0"...), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendSFInteger(b, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, errors.New("structured field integer out of range")
+		}
+		return appendSFInteger(b, int64(v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		f := math.Round(v.Float()*1000) / 1000
+		if math.IsNaN(f) || math.Abs(f) >= 1e12 {
+			return nil, errors.New("structured field decimal out of range")
+		}
+		b = strconv.AppendFloat(b, f, 'f', -1, 64)
+		if f == math.Trunc(f) {
+			b = append(b, ".0"...)
+		}
+		return b, nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b = append(b, ':')
+			b = base64.StdEncoding.AppendEncode(b, v.Bytes())
+			return append(b, ':'), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported structured field item type %s", v.Type())
+}
+
+func appendSFInteger(b []byte, n int64) ([]byte, error) {
+	if n > 999_999_999_999_999 || n < -999_999_999_999_999 {
+		return nil, errors.New("structured field integer out of range")
+	}
+	return strconv.AppendInt(b, n, 10), nil
+}
+
+func validSFKey(k string) bool {
+	if k == "" || !(k[0] == '*' || (k[0] >= 'a' && k[0] <= 'z')) {
+		return false
+	}
+	for i := 1; i < len(k); i++ {
+		c := k[i]
+		if !(c >= 'a' && c <= 'z') && !(c >= '0' && c <= '9') && !strings.ContainsRune("_-.*", rune(c)) {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeStructuredField[T any](ctx context.Context, key any, data []byte) (context.Context, error) {
+	t := reflect.TypeFor[T]()
+	p := &sfParser{s: strings.TrimLeft(string(data), " ")}
+	var v reflect.Value
+	if t.Kind() == reflect.Map {
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported structured field dictionary type %s", t)
+		}
+		dict, err := p.parseDictionary()
+		if err != nil {
+			return nil, err
+		}
+		v = reflect.MakeMapWithSize(t, len(dict))
+		for k, item := range dict {
+			mv, err := convertSFItem(item, t.Elem())
+			if err != nil {
+				return nil, err
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), mv)
+		}
+	} else {
+		item, err := p.parseItem()
+		if err != nil {
+			return nil, err
+		}
+		if v, err = convertSFItem(item, t); err != nil {
+			return nil, err
+		}
+	}
+	if p.s = strings.TrimLeft(p.s, " "); p.s != "" {
+		return nil, fmt.Errorf("unexpected trailing characters %q in structured field", p.s)
+	}
+	return context.WithValue(ctx, key, v.Interface()), nil
+}
+
+// convertSFItem converts the given parsed bare item to the type t.
+func convertSFItem(item any, t reflect.Type) (reflect.Value, error) {
+	v := reflect.ValueOf(item)
+	switch {
+	case v.Type().AssignableTo(t):
+		rv := reflect.New(t).Elem()
+		rv.Set(v)
+		return rv, nil
+	case v.Kind() == reflect.Int64 && (t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64 || t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64),
+		v.Kind() == reflect.Float64 && (t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64),
+		v.Kind() == t.Kind() && v.Kind() != reflect.Slice:
+		cv := v.Convert(t)
+		unsigned := t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uintptr
+		if v.Kind() == reflect.Int64 && (unsigned && v.Int() < 0 || cv.Convert(v.Type()).Int() != v.Int()) {
+			return reflect.Value{}, fmt.Errorf("structured field integer %v overflows %s", item, t)
+		}
+		return cv, nil
+	}
+	return reflect.Value{}, fmt.Errorf("cannot decode structured field item %v into %s", item, t)
+}
+
+// sfParser parses the Structured Fields of RFC 8941.
+type sfParser struct {
+	s string
+}
+
+func (p *sfParser) parseDictionary() (map[string]any, error) {
+	dict := make(map[string]any)
+	for p.s != "" {
+		k, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var v any = true
+		if strings.HasPrefix(p.s, "=") {
+			p.s = p.s[1:]
+			if v, err = p.parseItem(); err != nil {
+				return nil, err
+			}
+		} else if err := p.parseParameters(); err != nil {
+			return nil, err
+		}
+		dict[k] = v
+		p.s = strings.TrimLeft(p.s, " \t")
+		if p.s == "" {
+			break
+		}
+		if p.s[0] != ',' {
+			return nil, fmt.Errorf("unexpected character %q in structured field dictionary", p.s[0])
+		}
+		p.s = strings.TrimLeft(p.s[1:], " \t")
+		if p.s == "" {
+			return nil, errors.New("trailing comma in structured field dictionary")
+		}
+	}
+	return dict, nil
+}
+
+func (p *sfParser) parseKey() (string, error) {
+	i := 0
+	for i < len(p.s) && ((p.s[i] >= 'a' && p.s[i] <= 'z') || p.s[i] == '*' ||
+		(i > 0 && ((p.s[i] >= '0' && p.s[i] <= '9') || strings.IndexByte("_-.", p.s[i]) >= 0))) {
+		i++
+	}
+	if i == 0 {
+		return "", errors.New("invalid structured field key")
+	}
+	k := p.s[:i]
+	p.s = p.s[i:]
+	return k, nil
+}
+
+func (p *sfParser) parseItem() (any, error) {
+	v, err := p.parseBareItem()
+	if err != nil {
+		return nil, err
+	}
+	return v, p.parseParameters()
+}
+
+// parseParameters rejects the parameters of an item, which are not supported.
+func (p *sfParser) parseParameters() error {
+	if strings.HasPrefix(p.s, ";") {
+		return errors.New("structured field parameters are not supported")
+	}
+	return nil
+}
+
+func (p *sfParser) parseBareItem() (any, error) {
+	if p.s == "" {
+		return nil, errors.New("missing structured field item")
+	}
+	switch c := p.s[0]; {
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case c == '"':
+		return p.parseString()
+	case c == '*' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return p.parseToken(), nil
+	case c == ':':
+		return p.parseByteSequence()
+	case c == '?':
+		if len(p.s) < 2 || (p.s[1] != '0' && p.s[1] != '1') {
+			return nil, errors.New("invalid structured field boolean")
+		}
+		b := p.s[1] == '1'
+		p.s = p.s[2:]
+		return b, nil
+	case c == '(':
+		return nil, errors.New("structured field inner lists are not supported")
+	default:
+		return nil, fmt.Errorf("unexpected character %q in structured field item", c)
+	}
+}
+
+// parseNumber parses an integer or a decimal, rejecting the numbers exceeding
+// the limits of RFC 8941: 15 integer digits, or 12 integer and 3 fractional
+// digits for decimals.
+func (p *sfParser) parseNumber() (any, error) {
+	i := 0
+	if p.s[0] == '-' {
+		i++
+	}
+	digits, dot := 0, -1
+	for ; i < len(p.s); i++ {
+		c := p.s[i]
+		if c == '.' && dot < 0 {
+			dot = digits
+			continue
+		}
+		if c < '0' || c > '9' {
+			break
+		}
+		digits++
+	}
+	num := p.s[:i]
+	p.s = p.s[i:]
+	if dot >= 0 {
+		if dot == 0 || dot > 12 || digits == dot || digits-dot > 3 {
+			return nil, fmt.Errorf("invalid structured field decimal %q", num)
+		}
+		f, err := strconv.ParseFloat(num, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid structured field decimal %q", num)
+		}
+		return f, nil
+	}
+	if digits == 0 || digits > 15 {
+		return nil, fmt.Errorf("invalid structured field integer %q", num)
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid structured field integer %q", num)
+	}
+	return n, nil
+}
+
+func (p *sfParser) parseString() (string, error) {
+	var b strings.Builder
+	for i := 1; i < len(p.s); i++ {
+		switch c := p.s[i]; {
+		case c == '\\':
+			i++
+			if i == len(p.s) || (p.s[i] != '"' && p.s[i] != '\\') {
+				return "", errors.New("invalid escape in structured field string")
+			}
+			b.WriteByte(p.s[i])
+		case c == '"':
+			p.s = p.s[i+1:]
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", fmt.Errorf("invalid character %q in structured field string", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated structured field string")
+}
+
+func (p *sfParser) parseToken() string {
+	i := 1
+	for i < len(p.s) && (isTChar(p.s[i]) || p.s[i] == ':' || p.s[i] == '/') {
+		i++
+	}
+	t := p.s[:i]
+	p.s = p.s[i:]
+	return t
+}
+
+func isTChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+func (p *sfParser) parseByteSequence() ([]byte, error) {
+	end := strings.IndexByte(p.s[1:], ':')
+	if end < 0 {
+		return nil, errors.New("unterminated structured field byte sequence")
+	}
+	b, err := base64.StdEncoding.DecodeString(p.s[1 : end+1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid structured field byte sequence: %w", err)
+	}
+	p.s = p.s[end+2:]
+	return b, nil
+}
@@ -0,0 +1,100 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type sfKey struct{}
+
+func testStructuredField[T any](t *testing.T, v T, want string) {
+	t.Helper()
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStructuredFieldPropagator[T]("sf", sfKey{})))
+
+	h, err := r.Preview(context.WithValue(context.Background(), sfKey{}, v))
+	require.NoError(t, err)
+	require.Equal(t, want, h.Get("x-ctxwire-sf"))
+
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, v, ctx.Value(sfKey{}))
+}
+
+func TestStructuredField(t *testing.T) {
+	testStructuredField(t, `say "hi"\`, `"say \"hi\"\\"`)
+	testStructuredField(t, -42, "-42")
+	testStructuredField(t, uint16(42), "42")
+	testStructuredField(t, true, "?1")
+	testStructuredField(t, 1.5, "1.5")
+	testStructuredField(t, 2.0, "2.0")
+	testStructuredField(t, []byte("hello"), ":aGVsbG8=:")
+	testStructuredField(t, map[string]any{
+		"tenant": "acme",
+		"tier":   int64(2),
+		"beta":   true,
+		"debug":  false,
+		"ratio":  0.25,
+	}, `beta, debug=?0, ratio=0.25, tenant="acme", tier=2`)
+	testStructuredField(t, map[string]int64{"a": 1, "b": 2}, "a=1, b=2")
+}
+
+func TestStructuredFieldExtract(t *testing.T) {
+	extract := func(r *ctxwire.Registry, v string) (any, error) {
+		ctx, err := r.Extract(context.Background(), http.Header{"X-Ctxwire-Sf": {v}})
+		if err != nil {
+			return nil, err
+		}
+		return ctx.Value(sfKey{}), nil
+	}
+	items := ctxwire.NewRegistry()
+	require.NoError(t, items.Configure(ctxwire.NewStructuredFieldPropagator[any]("sf", sfKey{})))
+	for v, want := range map[string]any{
+		"token/x:1":        "token/x:1",
+		"-1.125":           -1.125,
+		"7":                int64(7),
+		"?0":               false,
+		"-999999999999999": int64(-999_999_999_999_999),
+		"123456789012.125": 123456789012.125,
+	} {
+		got, err := extract(items, v)
+		require.NoError(t, err, v)
+		require.Equal(t, want, got, v)
+	}
+	for _, v := range []string{`"unterminated`, "1;a=2", "(1 2)", "?2", "1.", "1 2", ":!:", "1000000000000000",
+		"0000000000000001", "1234567890123.5", "1.2345", "-", "-.5", ".5"} {
+		_, err := extract(items, v)
+		require.Error(t, err, v)
+	}
+
+	dicts := ctxwire.NewRegistry()
+	require.NoError(t, dicts.Configure(ctxwire.NewStructuredFieldPropagator[map[string]any]("sf", sfKey{})))
+	got, err := extract(dicts, `a=1,b ,  c="x"`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"a": int64(1), "b": true, "c": "x"}, got)
+	for _, v := range []string{"a=1,", "A=1", "a=1;b", "a=1 b=2"} {
+		_, err := extract(dicts, v)
+		require.Error(t, err, v)
+	}
+
+	ints := ctxwire.NewRegistry()
+	require.NoError(t, ints.Configure(ctxwire.NewStructuredFieldPropagator[int8]("sf", sfKey{})))
+	_, err = extract(ints, "300")
+	require.ErrorContains(t, err, "overflows int8")
+	_, err = extract(ints, `"x"`)
+	require.ErrorContains(t, err, "cannot decode structured field item x into int8")
+
+	uints := ctxwire.NewRegistry()
+	require.NoError(t, uints.Configure(ctxwire.NewStructuredFieldPropagator[uint64]("sf", sfKey{})))
+	_, err = extract(uints, "-1")
+	require.ErrorContains(t, err, "overflows uint64")
+
+	err = items.Inject(context.WithValue(context.Background(), sfKey{}, "café"), http.Header{})
+	require.Error(t, err)
+	err = dicts.Inject(context.WithValue(context.Background(), sfKey{}, map[string]any{"A": 1}), http.Header{})
+	require.EqualError(t, err, `encode context value: invalid structured field key "A"`)
+}
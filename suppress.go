@@ -0,0 +1,56 @@
+package ctxwire
+
+import "context"
+
+type suppressKey struct{}
+
+// suppression lists the propagators suppressed for a request.
+type suppression struct {
+	all   bool
+	names map[string]bool
+}
+
+// Suppress returns a copy of the given context for which the propagators with
+// the given names don't run, or none of them if no name is given, such as for
+// health checks, third-party calls or requests built from untrusted input.
+// Suppressions accumulate with the ones of the parent context.
+// Registries skip the suppressed propagators when injecting or extracting a
+// context, and the Transport sends the requests whose context suppresses all
+// the propagators untouched.
+func Suppress(ctx context.Context, names ...string) context.Context {
+	s := &suppression{all: len(names) == 0, names: map[string]bool{}}
+	if parent := suppressionFrom(ctx); parent != nil {
+		s.all = s.all || parent.all
+		for name := range parent.names {
+			s.names[name] = true
+		}
+	}
+	for _, name := range names {
+		s.names[name] = true
+	}
+	return context.WithValue(ctx, suppressKey{}, s)
+}
+
+// Suppressed reports whether the given context suppresses all the
+// propagators.
+func Suppressed(ctx context.Context) bool {
+	s := suppressionFrom(ctx)
+	return s != nil && s.all
+}
+
+func suppressionFrom(ctx context.Context) *suppression {
+	s, _ := ctx.Value(suppressKey{}).(*suppression)
+	return s
+}
+
+// suppresses reports whether p is suppressed.
+func (s *suppression) suppresses(p Propagator) bool {
+	if s == nil {
+		return false
+	}
+	if s.all {
+		return true
+	}
+	n, ok := p.(named)
+	return ok && s.names[n.Name()]
+}
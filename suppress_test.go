@@ -0,0 +1,42 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestSuppress(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("str", keyStr),
+		ctxwire.NewIntPropagator("int", keyInt),
+	))
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	ctx = context.WithValue(ctx, keyInt, 42)
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctxwire.Suppress(ctx, "int"), h))
+	require.Equal(t, http.Header{"X-Ctxwire-Str": {"foo"}}, h)
+
+	h = http.Header{}
+	require.NoError(t, r.Inject(ctxwire.Suppress(ctx), h))
+	require.Empty(t, h)
+
+	// Suppressions accumulate.
+	h = http.Header{}
+	require.NoError(t, r.Inject(ctxwire.Suppress(ctxwire.Suppress(ctx, "int"), "str"), h))
+	require.Empty(t, h)
+	require.False(t, ctxwire.Suppressed(ctxwire.Suppress(ctx, "int")))
+	require.True(t, ctxwire.Suppressed(ctxwire.Suppress(ctxwire.Suppress(ctx), "int")))
+	require.False(t, ctxwire.Suppressed(ctx))
+
+	h = http.Header{"X-Ctxwire-Str": {"bar"}, "X-Ctxwire-Int": {"1"}}
+	got, err := r.Extract(ctxwire.Suppress(context.Background(), "str"), h)
+	require.NoError(t, err)
+	require.Nil(t, got.Value(keyStr))
+	require.Equal(t, 1, got.Value(keyInt))
+}
@@ -0,0 +1,75 @@
+package ctxwire
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// maxTagLen is the maximum length of a content tag.
+const maxTagLen = 16
+
+// TagEncoder returns an encoder prefixing the payloads encoded by enc with the
+// given content tag and a colon, such as "j:" for JSON, "pb:" for protobuf or
+// "gz+j:" for gzipped JSON. Tags are made of at most 16 lowercase letters,
+// digits and '+' characters.
+// Tagged payloads are self-describing, so that receivers configured with a
+// TagDecoder can decode them even when the senders use different encoders,
+// enabling gradual encoder migrations.
+func TagEncoder(tag string, enc Encoder) Encoder {
+	if !validTag(tag) {
+		panic(fmt.Sprintf("ctxwire: TagEncoder: invalid content tag %q", tag))
+	}
+	prefix := tag + ":"
+	return EncoderFunc(func(ctx context.Context, key any) ([]byte, error) {
+		data, err := enc.Encode(ctx, key)
+		if err != nil || len(data) == 0 {
+			return data, err
+		}
+		return append([]byte(prefix), data...), nil
+	})
+}
+
+// TagDecoder returns a decoder decoding the payloads tagged by a TagEncoder
+// with the decoder associated with their tag. Untagged payloads, sent by
+// senders not tagging their payloads yet, are decoded with the decoder
+// associated with the empty tag, if any.
+// Mergers implemented by the given decoders must be set explicitly with
+// WithMerger.
+func TagDecoder(decoders map[string]Decoder) Decoder {
+	return DecoderFunc(func(ctx context.Context, key any, data []byte) (context.Context, error) {
+		tag, payload := splitTag(data)
+		dec, ok := decoders[tag]
+		if !ok {
+			if tag == "" {
+				return nil, errors.New("missing content tag")
+			}
+			return nil, fmt.Errorf("unknown content tag %q", tag)
+		}
+		return dec.Decode(ctx, key, payload)
+	})
+}
+
+// splitTag returns the content tag of the given payload and the payload
+// without its tag. Payloads not starting with a valid tag are untagged.
+func splitTag(data []byte) (tag string, payload []byte) {
+	i := bytes.IndexByte(data[:min(len(data), maxTagLen+1)], ':')
+	if i <= 0 || !validTag(string(data[:i])) {
+		return "", data
+	}
+	return string(data[:i]), data[i+1:]
+}
+
+func validTag(tag string) bool {
+	if tag == "" || len(tag) > maxTagLen {
+		return false
+	}
+	for i := 0; i < len(tag); i++ {
+		c := tag[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '+' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestTag(t *testing.T) {
+	jsonEnc, jsonDec := ctxwire.EncoderFunc(ctxwire.EncodeJSON), ctxwire.DecoderFunc(ctxwire.DecodeJSON)
+	gzEnc, gzDec := ctxwire.Compress(jsonEnc, jsonDec, ctxwire.WithCompressionThreshold(0))
+
+	newRegistry := func(enc ctxwire.Encoder) *ctxwire.Registry {
+		r := ctxwire.NewRegistry()
+		require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr,
+			ctxwire.WithEncoder(enc),
+			ctxwire.WithDecoder(ctxwire.TagDecoder(map[string]ctxwire.Decoder{
+				"":     jsonDec,
+				"j":    jsonDec,
+				"gz+j": gzDec,
+			})),
+		)))
+		return r
+	}
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	for _, enc := range []ctxwire.Encoder{
+		jsonEnc,
+		ctxwire.TagEncoder("j", jsonEnc),
+		ctxwire.TagEncoder("gz+j", gzEnc),
+	} {
+		h, err := newRegistry(enc).Preview(ctx)
+		require.NoError(t, err)
+		got, err := newRegistry(jsonEnc).Extract(context.Background(), h)
+		require.NoError(t, err)
+		require.Equal(t, "foo", got.Value(keyStr))
+	}
+
+	h, err := newRegistry(ctxwire.TagEncoder("pb", jsonEnc)).Preview(ctx)
+	require.NoError(t, err)
+	_, err = newRegistry(jsonEnc).Extract(context.Background(), h)
+	require.EqualError(t, err, `decode context value: unknown content tag "pb"`)
+
+	h = http.Header{"X-Ctxwire-Str": {"ImZvbyI="}}
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPropagator("str", keyStr,
+		ctxwire.WithDecoder(ctxwire.TagDecoder(map[string]ctxwire.Decoder{"j": jsonDec})))))
+	_, err = r.Extract(context.Background(), h)
+	require.EqualError(t, err, "decode context value: missing content tag")
+
+	require.Panics(t, func() { ctxwire.TagEncoder("J", jsonEnc) })
+}
@@ -0,0 +1,29 @@
+package ctxwire
+
+import "context"
+
+// TombstoneValue is the header value propagating a value explicitly cleared
+// with Clear. Values encoded with the Raw byte encoding can't be equal to it.
+const TombstoneValue = "!"
+
+// tombstone is the context value of the keys cleared with Clear.
+type tombstone struct{}
+
+// Clear returns a copy of the given context in which the value associated with
+// the given key is explicitly cleared.
+// ValuePropagator, MultiPropagator and StructPropagator propagate cleared
+// values as tombstones, removing the value from the context on Extract, and
+// Aggregator clears the values cleared by the responses. Unlike a value which
+// is not set, it allows a service to remove a propagated value for the
+// subsequent hops, or from the context of its client.
+// The value of a cleared key is not nil: use Cleared to test it.
+func Clear(ctx context.Context, key any) context.Context {
+	return context.WithValue(ctx, key, tombstone{})
+}
+
+// Cleared reports whether the value associated with the given key was cleared
+// with Clear.
+func Cleared(ctx context.Context, key any) bool {
+	_, ok := ctx.Value(key).(tombstone)
+	return ok
+}
@@ -0,0 +1,47 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestTombstone(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewJSONPropagator("str", keyStr)))
+
+	ctx := ctxwire.Clear(context.WithValue(context.Background(), keyStr, "foo"), keyStr)
+	require.True(t, ctxwire.Cleared(ctx, keyStr))
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	require.Equal(t, http.Header{"X-Ctxwire-Str": {ctxwire.TombstoneValue}}, h)
+
+	ctx, err := r.Extract(context.WithValue(context.Background(), keyStr, "foo"), h)
+	require.NoError(t, err)
+	require.Nil(t, ctx.Value(keyStr))
+
+	// Raw values can't be mistaken for tombstones.
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("str", keyStr)))
+	err = r.Inject(context.WithValue(context.Background(), keyStr, ctxwire.TombstoneValue), http.Header{})
+	require.EqualError(t, err, "encode context value: value is not header-safe")
+}
+
+func TestTombstoneMultiValue(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewTypedJSONPropagator[[]string]("hops", hopsKey{}, ctxwire.WithMultiValue())))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), hopsKey{}, []string{"gateway"}), h))
+	require.NoError(t, r.Inject(ctxwire.Clear(context.Background(), hopsKey{}), h))
+	ctx, err := r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Nil(t, ctx.Value(hopsKey{}))
+
+	require.NoError(t, r.Inject(context.WithValue(context.Background(), hopsKey{}, []string{"search"}), h))
+	ctx, err = r.Extract(context.Background(), h)
+	require.NoError(t, err)
+	require.Equal(t, []string{"search"}, ctx.Value(hopsKey{}))
+}
@@ -0,0 +1,112 @@
+package ctxwire
+
+import (
+	"context"
+	"time"
+)
+
+// Trace is a set of hooks called by the registries when they inject or
+// extract context values, for instance to attribute the latency of heavily
+// instrumented clients. Any hook may be nil. Hooks are called synchronously,
+// from the goroutine propagating the values.
+// It is the ctxwire counterpart of httptrace.ClientTrace, and can be used
+// along with it.
+type Trace struct {
+	// InjectStart is called before the values are injected into a carrier.
+	InjectStart func()
+	// InjectDone is called once the values are injected, with the total
+	// duration of the injection and its error, if any.
+	InjectDone func(d time.Duration, err error)
+	// ExtractStart is called before the values are extracted from a carrier.
+	ExtractStart func()
+	// ExtractDone is called once the values are extracted, with the total
+	// duration of the extraction and its error, if any.
+	ExtractDone func(d time.Duration, err error)
+	// PropagatorDone is called once each propagator injected or extracted its
+	// values.
+	PropagatorDone func(info PropagatorTrace)
+}
+
+// PropagatorTrace describes the injection or the extraction of the values of
+// a propagator.
+type PropagatorTrace struct {
+	// Name is the name of the propagator, if it exposes one.
+	Name string
+	// Op is OpInject or OpExtract.
+	Op Op
+	// Duration is the time spent by the propagator, including the encoding or
+	// decoding of its values.
+	Duration time.Duration
+	// Err is the error returned by the propagator, if any.
+	Err error
+}
+
+type traceKey struct{}
+
+// WithTrace returns a copy of the given context whose injections and
+// extractions call the hooks of the given trace.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// ContextTrace returns the trace of the given context, or nil if none.
+func ContextTrace(ctx context.Context) *Trace {
+	t, _ := ctx.Value(traceKey{}).(*Trace)
+	return t
+}
+
+// injectStart calls the InjectStart hook and returns the start time of the
+// injection, or the zero time if the context isn't traced.
+func (t *Trace) injectStart() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	if t.InjectStart != nil {
+		t.InjectStart()
+	}
+	return time.Now()
+}
+
+func (t *Trace) injectDone(start time.Time, err error) {
+	if t != nil && t.InjectDone != nil {
+		t.InjectDone(time.Since(start), err)
+	}
+}
+
+// extractStart calls the ExtractStart hook and returns the start time of the
+// extraction, or the zero time if the context isn't traced.
+func (t *Trace) extractStart() time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	if t.ExtractStart != nil {
+		t.ExtractStart()
+	}
+	return time.Now()
+}
+
+func (t *Trace) extractDone(start time.Time, err error) {
+	if t != nil && t.ExtractDone != nil {
+		t.ExtractDone(time.Since(start), err)
+	}
+}
+
+// propagatorStart returns the start time of the propagation of a propagator,
+// or the zero time if its end isn't traced.
+func (t *Trace) propagatorStart() time.Time {
+	if t == nil || t.PropagatorDone == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+func (t *Trace) propagatorDone(p Propagator, op Op, start time.Time, err error) {
+	if t == nil || t.PropagatorDone == nil {
+		return
+	}
+	info := PropagatorTrace{Op: op, Duration: time.Since(start), Err: err}
+	if n, ok := p.(named); ok {
+		info.Name = n.Name()
+	}
+	t.PropagatorDone(info)
+}
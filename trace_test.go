@@ -0,0 +1,61 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestTrace(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("str", keyStr),
+		ctxwire.NewJSONPropagator("json", keyInt),
+	))
+
+	var events []string
+	var propagators []ctxwire.PropagatorTrace
+	trace := &ctxwire.Trace{
+		InjectStart: func() { events = append(events, "inject start") },
+		InjectDone: func(d time.Duration, err error) {
+			require.GreaterOrEqual(t, d, time.Duration(0))
+			require.NoError(t, err)
+			events = append(events, "inject done")
+		},
+		ExtractStart: func() { events = append(events, "extract start") },
+		ExtractDone: func(_ time.Duration, err error) {
+			events = append(events, "extract done")
+			if err != nil {
+				events = append(events, "extract error")
+			}
+		},
+		PropagatorDone: func(info ctxwire.PropagatorTrace) { propagators = append(propagators, info) },
+	}
+	ctx := ctxwire.WithTrace(context.WithValue(context.Background(), keyStr, "foo"), trace)
+	require.Same(t, trace, ctxwire.ContextTrace(ctx))
+	require.Nil(t, ctxwire.ContextTrace(context.Background()))
+
+	h := http.Header{}
+	require.NoError(t, r.Inject(ctx, h))
+	_, err := r.Extract(ctx, http.Header{"X-Ctxwire-Json": {"%%%"}})
+	require.Error(t, err)
+	require.Equal(t, []string{"inject start", "inject done", "extract start", "extract done", "extract error"}, events)
+
+	require.Len(t, propagators, 4)
+	require.Equal(t, "str", propagators[0].Name)
+	require.Equal(t, ctxwire.OpInject, propagators[0].Op)
+	require.Equal(t, "json", propagators[3].Name)
+	require.Equal(t, ctxwire.OpExtract, propagators[3].Op)
+	require.Error(t, propagators[3].Err)
+	require.NoError(t, propagators[2].Err)
+
+	// Partial traces are supported.
+	ctx = ctxwire.WithTrace(ctx, &ctxwire.Trace{})
+	require.NoError(t, r.Inject(ctx, http.Header{}))
+	_, err = r.Extract(ctx, h)
+	require.NoError(t, err)
+}
@@ -0,0 +1,127 @@
+package ctxwire
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// TraceParent holds the identifiers of the W3C Trace Context traceparent
+// header, allowing applications without a tracing SDK to correlate their logs
+// across hops.
+type TraceParent struct {
+	// TraceID identifies the whole trace.
+	TraceID [16]byte
+	// SpanID identifies the span of the caller.
+	SpanID [8]byte
+	// Flags are the trace flags.
+	Flags byte
+}
+
+// Sampled reports whether the sampled flag is set.
+func (tp TraceParent) Sampled() bool { return tp.Flags&1 == 1 }
+
+// String returns the traceparent header value of the identifiers, in version
+// 00 of the format.
+func (tp TraceParent) String() string {
+	return fmt.Sprintf("00-%x-%x-%02x", tp.TraceID, tp.SpanID, tp.Flags)
+}
+
+var errInvalidTraceParent = errors.New("invalid traceparent")
+
+// ParseTraceParent parses the given traceparent header value.
+// Values of future versions of the format are parsed as version 00 values.
+func ParseTraceParent(s string) (TraceParent, error) {
+	var tp TraceParent
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return tp, errInvalidTraceParent
+	}
+	version, ok := decodeLowerHex(s[:2])
+	if !ok || version[0] == 0xff || (version[0] == 0 && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return tp, errInvalidTraceParent
+	}
+	traceID, ok := decodeLowerHex(s[3:35])
+	if !ok || isZero(traceID) {
+		return tp, errInvalidTraceParent
+	}
+	spanID, ok := decodeLowerHex(s[36:52])
+	if !ok || isZero(spanID) {
+		return tp, errInvalidTraceParent
+	}
+	flags, ok := decodeLowerHex(s[53:55])
+	if !ok {
+		return tp, errInvalidTraceParent
+	}
+	copy(tp.TraceID[:], traceID)
+	copy(tp.SpanID[:], spanID)
+	tp.Flags = flags[0]
+	return tp, nil
+}
+
+func decodeLowerHex(s string) ([]byte, bool) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return nil, false
+		}
+	}
+	b, err := hex.DecodeString(s)
+	return b, err == nil
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+type traceParentKey struct{}
+
+// ContextWithTraceParent returns a copy of the given context holding the given
+// trace identifiers.
+func ContextWithTraceParent(ctx context.Context, tp TraceParent) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, tp)
+}
+
+// TraceParentFromContext returns the trace identifiers of the given context,
+// and whether it holds some.
+func TraceParentFromContext(ctx context.Context) (TraceParent, bool) {
+	tp, ok := ctx.Value(traceParentKey{}).(TraceParent)
+	return tp, ok
+}
+
+// NewTraceParentPropagator returns a new ValuePropagator propagating the trace
+// identifiers set with ContextWithTraceParent in the W3C Trace Context
+// "traceparent" header, as is. It doesn't create spans, nor propagate the
+// "tracestate" header.
+// Invalid incoming values are ignored, as required by the specification.
+// Defaults to the RequestOnly direction.
+func NewTraceParentPropagator(opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{
+		WithEncoder(EncoderFunc(encodeTraceParent)),
+		WithDecoder(DecoderFunc(decodeTraceParent)),
+		WithNamer(func(string) string { return "traceparent" }),
+		WithByteEncoding(Raw),
+		WithDirection(RequestOnly),
+	}, opts...)
+	return NewPropagator("traceparent", traceParentKey{}, opts...)
+}
+
+func encodeTraceParent(ctx context.Context, key any) ([]byte, error) {
+	tp, ok := ctx.Value(key).(TraceParent)
+	if !ok {
+		return nil, nil
+	}
+	return []byte(tp.String()), nil
+}
+
+func decodeTraceParent(ctx context.Context, key any, data []byte) (context.Context, error) {
+	tp, err := ParseTraceParent(string(data))
+	if err != nil {
+		return ctx, nil
+	}
+	return context.WithValue(ctx, key, tp), nil
+}
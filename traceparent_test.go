@@ -0,0 +1,65 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tp, err := ctxwire.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	require.Equal(t, byte(0x4b), tp.TraceID[0])
+	require.Equal(t, byte(0xb7), tp.SpanID[7])
+	require.True(t, tp.Sampled())
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", tp.String())
+
+	tp, err = ctxwire.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future")
+	require.NoError(t, err)
+	require.False(t, tp.Sampled())
+
+	for _, s := range []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0g",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01x",
+	} {
+		_, err := ctxwire.ParseTraceParent(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestTraceParentPropagator(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewTraceParentPropagator()))
+
+	h := http.Header{}
+	h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, err := r.ExtractRequest(context.Background(), h)
+	require.NoError(t, err)
+	tp, ok := ctxwire.TraceParentFromContext(ctx)
+	require.True(t, ok)
+	require.True(t, tp.Sampled())
+
+	out := http.Header{}
+	require.NoError(t, r.InjectRequest(ctx, out))
+	require.Equal(t, http.Header{"Traceparent": {tp.String()}}, out)
+	out = http.Header{}
+	require.NoError(t, r.InjectResponse(ctx, out))
+	require.Empty(t, out)
+
+	// Invalid values are ignored.
+	h.Set("traceparent", "garbage")
+	ctx, err = r.ExtractRequest(ctxwire.ContextWithTraceParent(context.Background(), tp), h)
+	require.NoError(t, err)
+	got, ok := ctxwire.TraceParentFromContext(ctx)
+	require.True(t, ok)
+	require.Equal(t, tp, got)
+}
@@ -0,0 +1,43 @@
+package ctxwire
+
+import (
+	"context"
+	"net/http"
+)
+
+// trailered is implemented by propagators which may propagate their value in
+// HTTP trailers.
+type trailered interface {
+	IsTrailer() bool
+}
+
+func isTrailer(p Propagator) bool {
+	t, ok := p.(trailered)
+	return ok && t.IsTrailer()
+}
+
+// WithTrailer makes the propagator send its value in an HTTP trailer instead
+// of a header, so that servers can back-propagate values computed after the
+// response body started streaming, such as latency totals or final log
+// states.
+// Trailer propagators are only injected by InjectTrailer. Receivers extract
+// them with Extract from the trailers of the response, once its body is read.
+func WithTrailer() PropagatorOption {
+	return func(p *ValuePropagator) { p.trailer = true }
+}
+
+// IsTrailer reports whether the propagator sends its value in an HTTP trailer.
+func (p *ValuePropagator) IsTrailer() bool { return p.trailer }
+
+// InjectTrailer injects the context values of the trailer propagators of the
+// registry into the trailers of the given response writer, skipping the
+// RequestOnly propagators. It must be called once the response body is
+// written. Trailers don't need to be announced in the Trailer header.
+func (r *Registry) InjectTrailer(ctx context.Context, w http.ResponseWriter) error {
+	h := http.Header{}
+	err := r.inject(ctx, HeaderCarrier(h), scope{dir: ResponseOnly, trailers: true})
+	for key, vs := range h {
+		w.Header()[http.TrailerPrefix+key] = vs
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package ctxwire_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestTrailer(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("str", keyStr),
+		ctxwire.NewIntPropagator("int", keyInt, ctxwire.WithTrailer()),
+	))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), keyStr, "foo")
+		require.NoError(t, r.Inject(ctx, w.Header()))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("streamed body"))
+		w.(http.Flusher).Flush()
+
+		// The latency is only known once the body is written.
+		ctx = context.WithValue(ctx, keyInt, 42)
+		require.NoError(t, r.InjectTrailer(ctx, w))
+	}))
+	t.Cleanup(server.Close)
+
+	resp, err := server.Client().Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "foo", resp.Header.Get("x-ctxwire-str"))
+	require.Empty(t, resp.Header.Get("x-ctxwire-int"))
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	ctx, err := r.Extract(context.Background(), resp.Header)
+	require.NoError(t, err)
+	ctx, err = r.Extract(ctx, resp.Trailer)
+	require.NoError(t, err)
+	require.Equal(t, "foo", ctx.Value(keyStr))
+	require.Equal(t, 42, ctx.Value(keyInt))
+}
@@ -0,0 +1,248 @@
+package ctxwire
+
+import (
+	"context"
+	"net/http"
+)
+
+// TransportOption configures a Transport.
+type TransportOption func(t *Transport)
+
+// WithTransportRegistry sets the registry whose propagators are used by the
+// transport. Defaults to the default registry.
+func WithTransportRegistry(r *Registry) TransportOption {
+	return func(t *Transport) { t.registry = r }
+}
+
+// WithTransportErrorHandler sets the function handling the propagation errors
+// of the transport. The error it returns fails the request, while a nil error
+// sends the request without the values which couldn't be injected, or returns
+// the response without the values which couldn't be extracted. By default,
+// propagation errors fail the request.
+func WithTransportErrorHandler(fn func(req *http.Request, err error) error) TransportOption {
+	return func(t *Transport) { t.errorHandler = fn }
+}
+
+// WithErrorContext makes the transport extract the values of the responses
+// rejected by the status filter into a dedicated error context, derived from
+// context.Background, so that clients can log the diagnostics back-propagated
+// by failing servers without trusting them as regular values. The error
+// context is attached to the context of resp.Request, from which ErrorContext
+// returns it.
+func WithErrorContext() TransportOption {
+	return func(t *Transport) { t.errorContext = true }
+}
+
+type errorContextKey struct{}
+
+// ErrorContext returns the error context attached to the given context by a
+// Transport configured with WithErrorContext, and reports whether there is
+// one.
+func ErrorContext(ctx context.Context) (context.Context, bool) {
+	errCtx, ok := ctx.Value(errorContextKey{}).(context.Context)
+	return errCtx, ok
+}
+
+// WithAllowedHosts restricts the propagation to the requests sent to the hosts
+// matching one of the given patterns, so that internal values, such as tenant
+// identifiers or authorization claims, don't leak to third-party APIs called
+// through the same client. Patterns are exact host names or IP addresses,
+// domain suffixes starting with a dot, such as ".svc.cluster.local", or CIDR
+// ranges, such as "10.0.0.0/8". The responses of the other hosts are not
+// extracted either. By default, all the hosts are allowed.
+func WithAllowedHosts(patterns ...string) TransportOption {
+	return func(t *Transport) { t.hosts = newHostMatcher(patterns) }
+}
+
+// WithResponseContext sets a function called with each response whose values
+// were extracted and the context holding them, for instance to record them
+// somewhere the caller can reach, such as the box of the request context.
+func WithResponseContext(fn func(resp *http.Response, ctx context.Context)) TransportOption {
+	return func(t *Transport) { t.onResponse = fn }
+}
+
+// WithStatusFilter sets the function reporting whether the values are
+// extracted from the responses with the given status code. By default, they
+// are only extracted from the 2xx responses, since the error responses of
+// proxies and load balancers often carry stale or bogus headers.
+func WithStatusFilter(filter func(code int) bool) TransportOption {
+	return func(t *Transport) { t.statusFilter = filter }
+}
+
+// Transport is an http.RoundTripper propagating the context values of the
+// requests it sends and back-propagating the values of their responses.
+// The header prefix and the error policy of the propagation are the ones of
+// its registry, set with WithTransportRegistry, so that clients propagating
+// different sets of values can run in the same process.
+//
+// The values of the request context are injected into the headers of a copy
+// of the request, skipping the ResponseOnly propagators, replacing the headers
+// of the registry the request may already hold, such as the ones left by a
+// previous attempt. The values of the response headers are extracted into a
+// copy of the request context, skipping the RequestOnly propagators, which
+// becomes the context of resp.Request and is returned by ContextFromResponse.
+// The request of the caller is never modified, so that it can be shared and
+// sent concurrently.
+type Transport struct {
+	base         http.RoundTripper
+	registry     *Registry
+	inject       bool
+	extract      bool
+	errorHandler func(req *http.Request, err error) error
+	onResponse   func(resp *http.Response, ctx context.Context)
+	statusFilter func(code int) bool
+	errorContext bool
+	hosts        *hostMatcher
+	redirects    RedirectPolicy
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// NewTransport returns a new Transport sending the requests with the given
+// transport, or http.DefaultTransport if nil.
+func NewTransport(base http.RoundTripper, opts ...TransportOption) *Transport {
+	return newTransport(base, true, true, opts)
+}
+
+// NewInjectTransport returns a new Transport sending the requests with the
+// given transport, or http.DefaultTransport if nil, which only injects the
+// values into the requests.
+func NewInjectTransport(base http.RoundTripper, opts ...TransportOption) *Transport {
+	return newTransport(base, true, false, opts)
+}
+
+// NewExtractTransport returns a new Transport sending the requests with the
+// given transport, or http.DefaultTransport if nil, which only extracts the
+// values from the responses.
+func NewExtractTransport(base http.RoundTripper, opts ...TransportOption) *Transport {
+	return newTransport(base, false, true, opts)
+}
+
+func newTransport(base http.RoundTripper, inject, extract bool, opts []TransportOption) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		base:         base,
+		registry:     defaultRegistry,
+		inject:       inject,
+		extract:      extract,
+		errorHandler: func(_ *http.Request, err error) error { return err },
+		statusFilter: isSuccess,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// NewClient returns a copy of the given client, or of a zero http.Client if
+// nil, whose transport is wrapped into a Transport configured with the given
+// options.
+func NewClient(base *http.Client, opts ...TransportOption) *http.Client {
+	c := &http.Client{}
+	if base != nil {
+		*c = *base
+	}
+	c.Transport = NewTransport(c.Transport, opts...)
+	return c
+}
+
+func isSuccess(code int) bool { return code >= 200 && code < 300 }
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.followsRedirect(req) {
+		out := req.Clone(req.Context())
+		t.registry.removeHeaders(out.Header, nil)
+		return t.base.RoundTrip(out)
+	}
+	if Suppressed(req.Context()) || (t.hosts != nil && !t.hosts.matches(req.URL.Hostname())) {
+		return t.base.RoundTrip(req)
+	}
+	out := req
+	if t.inject {
+		out = req.Clone(req.Context())
+		if err := t.registry.Reinject(req.Context(), out.Header); err != nil {
+			if err = t.errorHandler(req, err); err != nil {
+				closeBody(req)
+				return nil, err
+			}
+		}
+		injectForwarded(req.Context(), out.Header)
+	}
+	resp, err := t.base.RoundTrip(out)
+	if err != nil || !t.extract {
+		return resp, err
+	}
+	if !t.statusFilter(resp.StatusCode) {
+		if !t.errorContext {
+			return resp, nil
+		}
+		return t.extractError(req, resp)
+	}
+	if a := accumulatorFrom(req.Context()); a != nil {
+		a.Add(resp.Header)
+	}
+	ctx, err := extractAttempt(req.Context(), func(ctx context.Context) (context.Context, error) {
+		return t.registry.ExtractResponse(ctx, resp.Header)
+	})
+	if err != nil {
+		if err = t.errorHandler(req, err); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	if ctx == nil {
+		return resp, nil
+	}
+	return t.attach(resp, out, ctx), nil
+}
+
+// extractError extracts the values of the given error response into an error
+// context attached to the context of its request.
+func (t *Transport) extractError(req *http.Request, resp *http.Response) (*http.Response, error) {
+	errCtx, err := t.registry.ExtractResponse(context.Background(), resp.Header)
+	if err != nil {
+		if err = t.errorHandler(req, err); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	}
+	if errCtx == nil {
+		return resp, nil
+	}
+	ctx := context.WithValue(req.Context(), errorContextKey{}, errCtx)
+	return t.attach(resp, req, ctx), nil
+}
+
+// attach sets the given context as the context of the request of the
+// response, or of the given request if the response has none.
+func (t *Transport) attach(resp *http.Response, req *http.Request, ctx context.Context) *http.Response {
+	if resp.Request == nil {
+		resp.Request = req
+	}
+	resp.Request = resp.Request.WithContext(ctx)
+	if t.onResponse != nil {
+		t.onResponse(resp, ctx)
+	}
+	return resp
+}
+
+// ContextFromResponse returns the context of the request of the given
+// response, which holds the values extracted from the response by a
+// Transport. It returns context.Background if the response has no request.
+func ContextFromResponse(resp *http.Response) context.Context {
+	if resp == nil || resp.Request == nil {
+		return context.Background()
+	}
+	return resp.Request.Context()
+}
+
+// closeBody closes the body of the request, which RoundTrip must do even on
+// errors.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
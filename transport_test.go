@@ -0,0 +1,329 @@
+package ctxwire_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func newTransportRegistry(t *testing.T) *ctxwire.Registry {
+	t.Helper()
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", keyStr, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewIntPropagator("count", keyInt, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	return r
+}
+
+func TestClient(t *testing.T) {
+	r := newTransportRegistry(t)
+	srv := httptest.NewServer(r.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "alice", req.Context().Value(keyStr))
+		ctxwire.Put(req.Context(), keyInt, 42)
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer srv.Close()
+
+	base := &http.Client{}
+	client := ctxwire.NewClient(base, ctxwire.WithTransportRegistry(r))
+	require.NotSame(t, base, client)
+	require.Nil(t, base.Transport)
+
+	ctx := context.WithValue(context.Background(), keyStr, "alice")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, 42, resp.Request.Context().Value(keyInt))
+	require.Equal(t, "alice", resp.Request.Context().Value(keyStr))
+	// The request of the caller is left untouched.
+	require.Empty(t, req.Header)
+	require.Nil(t, req.Context().Value(keyInt))
+}
+
+func TestTransportStatusFilter(t *testing.T) {
+	r := newTransportRegistry(t)
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		code := http.StatusOK
+		if req.URL.Path == "/error" {
+			code = http.StatusBadGateway
+		}
+		return &http.Response{
+			StatusCode: code,
+			Header:     http.Header{"X-Ctxwire-Count": {"1"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	client := &http.Client{Transport: ctxwire.NewTransport(base,
+		ctxwire.WithTransportRegistry(r),
+		ctxwire.WithStatusFilter(func(code int) bool { return code < 500 }),
+	)}
+
+	resp, err := client.Get("http://example.com/ok")
+	require.NoError(t, err)
+	require.Equal(t, 1, resp.Request.Context().Value(keyInt))
+	resp, err = client.Get("http://example.com/error")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	require.Nil(t, resp.Request.Context().Value(keyInt))
+
+	// Only the 2xx responses are extracted by default.
+	client = &http.Client{Transport: ctxwire.NewTransport(base, ctxwire.WithTransportRegistry(r))}
+	resp, err = client.Get("http://example.com/ok")
+	require.NoError(t, err)
+	require.Equal(t, 1, resp.Request.Context().Value(keyInt))
+	resp, err = client.Get("http://example.com/error")
+	require.NoError(t, err)
+	require.Nil(t, ctxwire.ContextFromResponse(resp).Value(keyInt))
+}
+
+func TestTransportErrors(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewStringPropagator("user", keyStr)))
+	called := false
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		called = true
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	transport := ctxwire.NewTransport(base, ctxwire.WithTransportRegistry(r))
+
+	ctx := context.WithValue(context.Background(), keyStr, 1)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	_, err = transport.RoundTrip(req)
+	var e *ctxwire.Error
+	require.True(t, errors.As(err, &e))
+	require.False(t, called)
+
+	failing := roundTripFunc(func(*http.Request) (*http.Response, error) { return nil, errors.New("failed!") })
+	_, err = ctxwire.NewTransport(failing).RoundTrip(httptest.NewRequest(http.MethodGet, "/", nil))
+	require.EqualError(t, err, "failed!")
+}
+
+func TestDefaultTransport(t *testing.T) {
+	t.Cleanup(ctxwire.Reset)
+	ctxwire.Configure(ctxwire.NewStringPropagator("user", keyStr))
+	srv := httptest.NewServer(ctxwire.Handler(http.HandlerFunc(func(_ http.ResponseWriter, req *http.Request) {
+		ctxwire.Put(req.Context(), keyStr, req.Context().Value(keyStr).(string)+"!")
+	})))
+	defer srv.Close()
+
+	ctx := context.WithValue(context.Background(), keyStr, "bob")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	resp, err := ctxwire.NewClient(nil).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, "bob!", resp.Request.Context().Value(keyStr))
+}
+
+func TestTransportDirections(t *testing.T) {
+	r := newTransportRegistry(t)
+	var sent http.Header
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req.Header
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Ctxwire-Count": {"1"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	ctx := context.WithValue(context.Background(), keyStr, "alice")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+
+	resp, err := ctxwire.NewInjectTransport(base, ctxwire.WithTransportRegistry(r)).RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, "alice", sent.Get("x-ctxwire-user"))
+	require.Nil(t, resp.Request.Context().Value(keyInt))
+
+	resp, err = ctxwire.NewExtractTransport(base, ctxwire.WithTransportRegistry(r)).RoundTrip(req)
+	require.NoError(t, err)
+	require.Empty(t, sent.Get("x-ctxwire-user"))
+	require.Equal(t, 1, resp.Request.Context().Value(keyInt))
+}
+
+func TestTransportErrorHandler(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(
+		ctxwire.NewStringPropagator("user", keyStr, ctxwire.WithDirection(ctxwire.RequestOnly)),
+		ctxwire.NewJSONPropagator("json", keyInt, ctxwire.WithDirection(ctxwire.ResponseOnly)),
+	))
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Ctxwire-Json": {"%%%"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	var errs []error
+	transport := ctxwire.NewTransport(base,
+		ctxwire.WithTransportRegistry(r),
+		ctxwire.WithTransportErrorHandler(func(_ *http.Request, err error) error {
+			errs = append(errs, err)
+			return nil
+		}),
+	)
+	ctx := context.WithValue(context.Background(), keyStr, 1)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Len(t, errs, 2)
+
+	_, err = ctxwire.NewExtractTransport(base, ctxwire.WithTransportRegistry(r)).RoundTrip(req)
+	require.Error(t, err)
+}
+
+func TestContextFromResponse(t *testing.T) {
+	r := newTransportRegistry(t)
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Ctxwire-Count": {"3"}},
+			Body:       http.NoBody,
+		}, nil
+	})
+	var got context.Context
+	transport := ctxwire.NewTransport(base,
+		ctxwire.WithTransportRegistry(r),
+		ctxwire.WithResponseContext(func(_ *http.Response, ctx context.Context) { got = ctx }),
+	)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.Equal(t, 3, ctxwire.ContextFromResponse(resp).Value(keyInt))
+	require.Equal(t, 3, got.Value(keyInt))
+	require.NotSame(t, req, resp.Request)
+	require.Nil(t, req.Context().Value(keyInt))
+
+	require.Equal(t, context.Background(), ctxwire.ContextFromResponse(nil))
+	require.Equal(t, context.Background(), ctxwire.ContextFromResponse(&http.Response{}))
+}
+
+func TestTransportErrorContext(t *testing.T) {
+	r := newTransportRegistry(t)
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusInternalServerError,
+			Header:     http.Header{"X-Ctxwire-Count": {"5"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	ctx := context.WithValue(context.Background(), keyStr, "alice")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+
+	resp, err := ctxwire.NewTransport(base, ctxwire.WithTransportRegistry(r), ctxwire.WithErrorContext()).RoundTrip(req)
+	require.NoError(t, err)
+	respCtx := ctxwire.ContextFromResponse(resp)
+	require.Nil(t, respCtx.Value(keyInt))
+	require.Equal(t, "alice", respCtx.Value(keyStr))
+	errCtx, ok := ctxwire.ErrorContext(respCtx)
+	require.True(t, ok)
+	require.Equal(t, 5, errCtx.Value(keyInt))
+	require.Nil(t, errCtx.Value(keyStr))
+
+	resp, err = ctxwire.NewTransport(base, ctxwire.WithTransportRegistry(r)).RoundTrip(req)
+	require.NoError(t, err)
+	_, ok = ctxwire.ErrorContext(ctxwire.ContextFromResponse(resp))
+	require.False(t, ok)
+}
+
+func TestTransportAllowedHosts(t *testing.T) {
+	r := newTransportRegistry(t)
+	var sent http.Header
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req.Header
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Ctxwire-Count": {"1"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	transport := ctxwire.NewTransport(base,
+		ctxwire.WithTransportRegistry(r),
+		ctxwire.WithAllowedHosts("api.example.com", ".svc.cluster.local", "10.0.0.0/8", "fd00::/8", "bad/cidr"),
+	)
+	ctx := context.WithValue(context.Background(), keyStr, "alice")
+
+	for url, allowed := range map[string]bool{
+		"http://api.example.com/v1":           true,
+		"http://API.example.com.:8080":        true,
+		"http://users.svc.cluster.local":      true,
+		"http://svc.cluster.local":            false,
+		"http://10.1.2.3:9000":                true,
+		"http://[::ffff:10.1.2.3]":            true,
+		"http://[fd00::1]":                    true,
+		"http://11.0.0.1":                     false,
+		"http://third-party.com":              false,
+		"http://api.example.com.evil.com":     false,
+		"http://evilapi.example.com":          false,
+		"http://users.svc.cluster.local:8443": true,
+	} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		require.NoError(t, err)
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		if allowed {
+			require.Equal(t, "alice", sent.Get("x-ctxwire-user"), url)
+			require.Equal(t, 1, ctxwire.ContextFromResponse(resp).Value(keyInt), url)
+		} else {
+			require.Empty(t, sent.Get("x-ctxwire-user"), url)
+			require.Nil(t, ctxwire.ContextFromResponse(resp).Value(keyInt), url)
+		}
+	}
+}
+
+func TestTransportSuppress(t *testing.T) {
+	r := newTransportRegistry(t)
+	var sent *http.Request
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		sent = req
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"X-Ctxwire-Count": {"1"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	})
+	transport := ctxwire.NewTransport(base, ctxwire.WithTransportRegistry(r))
+	ctx := ctxwire.Suppress(context.WithValue(context.Background(), keyStr, "alice"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.Same(t, req, sent)
+	require.Empty(t, sent.Header)
+	require.Nil(t, ctxwire.ContextFromResponse(resp).Value(keyInt))
+
+	ctx = ctxwire.Suppress(context.WithValue(context.Background(), keyStr, "alice"), "user")
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, "http://example.com", nil)
+	require.NoError(t, err)
+	resp, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	require.Empty(t, sent.Header.Get("x-ctxwire-user"))
+	require.Equal(t, 1, ctxwire.ContextFromResponse(resp).Value(keyInt))
+}
@@ -0,0 +1,115 @@
+package ctxwire
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// TypeRegistry maps names to concrete Go types, allowing the propagation of
+// context values whose type varies from one request to another.
+// The same names must be registered on both sides.
+// The zero value is an empty registry ready to use.
+type TypeRegistry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// NewTypeRegistry returns a new empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{}
+}
+
+// RegisterType registers the type T with the given name in the given type
+// registry.
+// It panics if the name or the type is already registered with another type
+// or name.
+func RegisterType[T any](r *TypeRegistry, name string) {
+	t := reflect.TypeFor[T]()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if other, ok := r.types[name]; ok && other != t {
+		panic(fmt.Sprintf("ctxwire: RegisterType: name %q is already used by %s", name, other))
+	}
+	if other, ok := r.names[t]; ok && other != name {
+		panic(fmt.Sprintf("ctxwire: RegisterType: %s is already registered as %q", t, other))
+	}
+	if r.types == nil {
+		r.types = make(map[string]reflect.Type)
+		r.names = make(map[reflect.Type]string)
+	}
+	r.types[name] = t
+	r.names[t] = name
+}
+
+func (r *TypeRegistry) name(t reflect.Type) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.names[t]
+	return name, ok
+}
+
+func (r *TypeRegistry) typ(name string) (reflect.Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// typedValue is the JSON representation of a context value along with the
+// name of its type.
+type typedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Codec returns a codec encoding the context values as JSON along with the
+// name of their type, and decoding them into a value of the registered type.
+// Values whose type is not registered fail to be encoded.
+func (r *TypeRegistry) Codec() Codec {
+	return Codec{Encoder: EncoderFunc(r.encode), Decoder: DecoderFunc(r.decode)}
+}
+
+func (r *TypeRegistry) encode(ctx context.Context, key any) ([]byte, error) {
+	v := ctx.Value(key)
+	if v == nil {
+		return nil, nil
+	}
+	name, ok := r.name(reflect.TypeOf(v))
+	if !ok {
+		return nil, fmt.Errorf("type %T is not registered", v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(typedValue{Type: name, Value: data})
+}
+
+func (r *TypeRegistry) decode(ctx context.Context, key any, data []byte) (context.Context, error) {
+	var tv typedValue
+	if err := json.Unmarshal(data, &tv); err != nil {
+		return nil, err
+	}
+	t, ok := r.typ(tv.Type)
+	if !ok {
+		return nil, fmt.Errorf("unknown type name %q", tv.Type)
+	}
+	v := reflect.New(t)
+	if err := json.Unmarshal(tv.Value, v.Interface()); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, key, v.Elem().Interface()), nil
+}
+
+// NewPolymorphicPropagator returns a new ValuePropagator with the given name
+// configured to propagate context values of any of the types registered in
+// the given type registry. Extracted values have the exact registered type
+// rather than the generic values decoded by NewJSONPropagator.
+func NewPolymorphicPropagator(name string, contextKey any, types *TypeRegistry, opts ...PropagatorOption) *ValuePropagator {
+	opts = append([]PropagatorOption{WithCodec(types.Codec())}, opts...)
+	return NewPropagator(name, contextKey, opts...)
+}
@@ -0,0 +1,48 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+type (
+	eventKey   struct{}
+	clickEvent struct {
+		X, Y int
+	}
+	searchEvent struct {
+		Query string
+	}
+)
+
+func TestPolymorphicPropagator(t *testing.T) {
+	types := ctxwire.NewTypeRegistry()
+	ctxwire.RegisterType[clickEvent](types, "click")
+	ctxwire.RegisterType[*searchEvent](types, "search")
+	ctxwire.RegisterType[clickEvent](types, "click")
+
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPolymorphicPropagator("event", eventKey{}, types)))
+
+	for _, v := range []any{clickEvent{X: 1, Y: 2}, &searchEvent{Query: "shoes"}} {
+		h, err := r.Preview(context.WithValue(context.Background(), eventKey{}, v))
+		require.NoError(t, err)
+		ctx, err := r.Extract(context.Background(), h)
+		require.NoError(t, err)
+		require.Equal(t, v, ctx.Value(eventKey{}))
+	}
+
+	err := r.Inject(context.WithValue(context.Background(), eventKey{}, searchEvent{}), http.Header{})
+	require.EqualError(t, err, "encode context value: type ctxwire_test.searchEvent is not registered")
+
+	h := http.Header{"X-Ctxwire-Event": {"eyJ0eXBlIjoidmlldyIsInZhbHVlIjp7fX0="}} // {"type":"view","value":{}}
+	_, err = r.Extract(context.Background(), h)
+	require.EqualError(t, err, `decode context value: unknown type name "view"`)
+
+	require.Panics(t, func() { ctxwire.RegisterType[searchEvent](types, "click") })
+	require.Panics(t, func() { ctxwire.RegisterType[clickEvent](types, "tap") })
+}
@@ -0,0 +1,72 @@
+package ctxwire
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// WireVersion identifies the on-wire format used by a registry.
+type WireVersion int
+
+const (
+	// WireV1 propagates each value in its own header.
+	WireV1 WireVersion = 1
+	// WireV2 propagates all the values in a single envelope header, as
+	// configured with WithEnvelope or WithCompactEnvelope.
+	WireV2 WireVersion = 2
+)
+
+// WithVersionHeader makes the registry emit a version header, named after the
+// header prefix of the registry ("x-ctxwire-version" by default), holding the
+// WireVersion of its format.
+// Registries always honor the version header on Extract, whatever their own
+// format, so that the format can evolve without breaking mixed-version
+// deployments. Carriers without version header are read in the format of the
+// registry.
+func WithVersionHeader() RegistryOption {
+	return func(r *Registry) { r.versionHeader = true }
+}
+
+// versionKey returns the key of the version header of the registry.
+func (r *Registry) versionKey() string {
+	return r.naming.headerPrefix() + "version"
+}
+
+// wireVersion returns the WireVersion of the format of the registry.
+func (r *Registry) wireVersion() WireVersion {
+	if r.envelope != noEnvelope {
+		return WireV2
+	}
+	return WireV1
+}
+
+// carrierVersion returns the WireVersion of the values of the given carrier,
+// or the version of the registry if the carrier has no version header.
+func (r *Registry) carrierVersion(c Carrier) (WireVersion, error) {
+	v := c.Get(r.versionKey())
+	if v == "" {
+		return r.wireVersion(), nil
+	}
+	switch n, _ := strconv.Atoi(v); WireVersion(n) {
+	case WireV1:
+		return WireV1, nil
+	case WireV2:
+		return WireV2, nil
+	default:
+		return 0, newError(OpExtract, r.versionKey(), "read wire format version",
+			fmt.Errorf("unsupported version %q", v))
+	}
+}
+
+// openCarrier returns the carrier holding the values of the given carrier,
+// according to its WireVersion.
+func (r *Registry) openCarrier(c Carrier) (Carrier, error) {
+	version, err := r.carrierVersion(c)
+	if err != nil {
+		return nil, err
+	}
+	if version == WireV2 {
+		return r.openEnvelope(c)
+	}
+	return c, nil
+}
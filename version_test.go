@@ -0,0 +1,54 @@
+package ctxwire_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+func TestVersionHeader(t *testing.T) {
+	newRegistry := func(opts ...ctxwire.RegistryOption) *ctxwire.Registry {
+		r := ctxwire.NewRegistry(opts...)
+		require.NoError(t, r.Configure(ctxwire.NewStringPropagator("str", keyStr)))
+		return r
+	}
+	v1 := newRegistry(ctxwire.WithVersionHeader())
+	v2 := newRegistry(ctxwire.WithVersionHeader(), ctxwire.WithEnvelope())
+
+	ctx := context.WithValue(context.Background(), keyStr, "foo")
+	h1, err := v1.Preview(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.Header{"X-Ctxwire-Version": {"1"}, "X-Ctxwire-Str": {"foo"}}, h1)
+	h2, err := v2.Preview(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.Header{"X-Ctxwire-Version": {"2"}, "X-Ctxwire": {`{"str":["foo"]}`}}, h2)
+
+	// Registries read the format given by the version header, whatever their
+	// own format.
+	for _, r := range []*ctxwire.Registry{v1, v2, newRegistry()} {
+		for _, h := range []http.Header{h1, h2} {
+			got, err := r.Extract(context.Background(), h)
+			require.NoError(t, err)
+			require.Equal(t, "foo", got.Value(keyStr))
+		}
+	}
+
+	_, err = v1.Extract(context.Background(), http.Header{"X-Ctxwire-Version": {"3"}})
+	require.EqualError(t, err, `read wire format version: unsupported version "3"`)
+}
+
+func TestVersionHeaderPassthrough(t *testing.T) {
+	r := ctxwire.NewRegistry()
+	require.NoError(t, r.Configure(ctxwire.NewPassthroughPropagator()))
+
+	// The version header is not forwarded.
+
+	ctx, err := r.Extract(context.Background(), http.Header{"X-Ctxwire-Version": {"1"}, "X-Ctxwire-Other": {"bar"}})
+	require.NoError(t, err)
+	h, err := r.Preview(ctx)
+	require.NoError(t, err)
+	require.Equal(t, http.Header{"X-Ctxwire-Other": {"bar"}}, h)
+}
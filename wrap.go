@@ -0,0 +1,88 @@
+package ctxwire
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors returned by CheckTransport.
+var (
+	// ErrNoTransport reports a transport chain without Transport.
+	ErrNoTransport = errors.New("no ctxwire transport")
+	// ErrDuplicateTransport reports a transport chain with several Transports,
+	// which would propagate the values several times.
+	ErrDuplicateTransport = errors.New("duplicate ctxwire transport")
+	// ErrTransportOrder reports a transport chain with wrappers below the
+	// Transport.
+	ErrTransportOrder = errors.New("ctxwire transport is not the innermost wrapper")
+)
+
+// unwrapper is implemented by the http.RoundTripper wrappers exposing the
+// transport they wrap.
+type unwrapper interface {
+	Unwrap() http.RoundTripper
+}
+
+// Unwrap returns the transport wrapped by the Transport.
+func (t *Transport) Unwrap() http.RoundTripper { return t.base }
+
+// WrapTransport wraps the given transport, or http.DefaultTransport if nil,
+// into a Transport configured with the given options. If the chain of the
+// given transport already holds a Transport, it is returned as is and the
+// options are ignored, so that libraries and applications can both wrap a
+// transport without propagating the values twice.
+//
+// The Transport should be the innermost wrapper of the chain, right above the
+// transport sending the requests:
+//
+//	rt := otelhttp.NewTransport(retry(ctxwire.WrapTransport(http.DefaultTransport)))
+//
+// This guarantees that the values are injected into each attempt of retry
+// layers, after they clone the request, and that the values are extracted
+// before tracing wrappers end their span. CheckTransport verifies the order
+// of the wrappers exposing the transport they wrap with an
+// Unwrap() http.RoundTripper method.
+func WrapTransport(rt http.RoundTripper, opts ...TransportOption) http.RoundTripper {
+	for next := rt; next != nil; {
+		if _, ok := next.(*Transport); ok {
+			return rt
+		}
+		u, ok := next.(unwrapper)
+		if !ok {
+			break
+		}
+		next = u.Unwrap()
+	}
+	return NewTransport(rt, opts...)
+}
+
+// CheckTransport verifies that the chain of the given transport holds a
+// single Transport, below which no wrapper of the chain is found. It is meant
+// to be called at startup, for instance in tests. Wrappers not exposing the
+// transport they wrap with an Unwrap() http.RoundTripper method end the
+// chain.
+func CheckTransport(rt http.RoundTripper) error {
+	var found *Transport
+	for next := rt; next != nil; {
+		if t, ok := next.(*Transport); ok {
+			if found != nil {
+				return ErrDuplicateTransport
+			}
+			found = t
+		} else if found != nil {
+			if _, ok := next.(unwrapper); ok {
+				return fmt.Errorf("%w: %T is below it", ErrTransportOrder, next)
+			}
+		}
+		u, ok := next.(unwrapper)
+		if !ok {
+			break
+		}
+		next = u.Unwrap()
+	}
+	if found == nil {
+		return ErrNoTransport
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package ctxwire_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/trezz/ctxwire"
+)
+
+// wrapper is a transport wrapper exposing the transport it wraps, such as a
+// retry or a tracing transport.
+type wrapper struct {
+	base http.RoundTripper
+}
+
+func (w *wrapper) RoundTrip(req *http.Request) (*http.Response, error) { return w.base.RoundTrip(req) }
+
+func (w *wrapper) Unwrap() http.RoundTripper { return w.base }
+
+// opaque is a transport wrapper not exposing the transport it wraps.
+type opaque struct {
+	base http.RoundTripper
+}
+
+func (o *opaque) RoundTrip(req *http.Request) (*http.Response, error) { return o.base.RoundTrip(req) }
+
+func TestWrapTransport(t *testing.T) {
+	rt := ctxwire.WrapTransport(nil)
+	transport, ok := rt.(*ctxwire.Transport)
+	require.True(t, ok)
+	require.Equal(t, http.DefaultTransport, transport.Unwrap())
+
+	// Chains already holding a Transport are not wrapped again.
+	chain := &wrapper{base: rt}
+	require.Same(t, chain, ctxwire.WrapTransport(chain))
+	require.NoError(t, ctxwire.CheckTransport(chain))
+	require.NoError(t, ctxwire.CheckTransport(&wrapper{base: ctxwire.WrapTransport(&opaque{base: http.DefaultTransport})}))
+
+	wrapped := ctxwire.WrapTransport(&opaque{base: rt})
+	require.IsType(t, &ctxwire.Transport{}, wrapped)
+}
+
+func TestCheckTransport(t *testing.T) {
+	require.ErrorIs(t, ctxwire.CheckTransport(http.DefaultTransport), ctxwire.ErrNoTransport)
+	require.ErrorIs(t, ctxwire.CheckTransport(nil), ctxwire.ErrNoTransport)
+	require.ErrorIs(t, ctxwire.CheckTransport(&wrapper{base: &opaque{base: ctxwire.NewTransport(nil)}}), ctxwire.ErrNoTransport)
+
+	double := ctxwire.NewTransport(ctxwire.NewTransport(nil))
+	require.ErrorIs(t, ctxwire.CheckTransport(double), ctxwire.ErrDuplicateTransport)
+
+	misordered := &wrapper{base: ctxwire.NewTransport(&wrapper{base: http.DefaultTransport})}
+	err := ctxwire.CheckTransport(misordered)
+	require.ErrorIs(t, err, ctxwire.ErrTransportOrder)
+	require.ErrorContains(t, err, "*ctxwire_test.wrapper")
+}